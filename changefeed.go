@@ -0,0 +1,305 @@
+package rethinkdb
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const defaultChangefeedRetryDelay = time.Second
+
+// ResilientChangefeedOpts contains the optional arguments for the
+// ResilientChangefeed function.
+type ResilientChangefeedOpts struct {
+	// MaxRetries is the number of consecutive attempts made to re-establish the
+	// changefeed before giving up. Zero means retry forever.
+	MaxRetries int
+	// RetryDelay is the delay between attempts to re-establish the changefeed,
+	// defaults to one second.
+	RetryDelay time.Duration
+	// OnReconnect, if set, is called after the changefeed has been
+	// re-established with the error which caused the original feed to fail.
+	// RethinkDB cannot resume a changefeed from a position so any changes made
+	// while the feed was down will not be delivered, callers that need to
+	// handle this gap should re-read the required state or use the
+	// IncludeInitial option of Changes.
+	OnReconnect func(err error)
+	// RunOpts are the options used each time the changefeed query is run.
+	RunOpts RunOpts
+}
+
+// ResilientCursor wraps the cursor of a changefeed and transparently
+// re-establishes the changefeed whenever the connection to the database is
+// lost. It is created using the ResilientChangefeed function.
+type ResilientCursor struct {
+	executor QueryExecutor
+	term     Term
+	opts     ResilientChangefeedOpts
+
+	mu      sync.Mutex
+	cursor  *Cursor
+	lastErr error
+	closed  bool
+	done    chan struct{}
+}
+
+// ResilientChangefeed runs the changefeed query t and returns a cursor which
+// automatically re-runs the query if the connection to the database is lost.
+// Errors returned by the database (for example if the table is deleted) are
+// not retried and end the changefeed.
+//
+//	cursor, err := r.ResilientChangefeed(session, r.Table("test").Changes(), r.ResilientChangefeedOpts{
+//	    OnReconnect: func(err error) {
+//	        log.Printf("changefeed reconnected, changes may have been missed: %v", err)
+//	    },
+//	})
+//	if err != nil {
+//	    // error
+//	}
+//	defer cursor.Close()
+//
+//	var change r.ChangeResponse
+//	for cursor.Next(&change) {
+//	    ...
+//	}
+//	err = cursor.Err()
+func ResilientChangefeed(s QueryExecutor, t Term, optArgs ...ResilientChangefeedOpts) (*ResilientCursor, error) {
+	rc := &ResilientCursor{
+		executor: s,
+		term:     t,
+		done:     make(chan struct{}),
+	}
+	if len(optArgs) >= 1 {
+		rc.opts = optArgs[0]
+	}
+	if rc.opts.RetryDelay <= 0 {
+		rc.opts.RetryDelay = defaultChangefeedRetryDelay
+	}
+
+	cursor, err := t.Run(s, rc.opts.RunOpts)
+	if err != nil {
+		return nil, err
+	}
+	rc.cursor = cursor
+
+	return rc, nil
+}
+
+// Next retrieves the next change from the changefeed, blocking if necessary.
+// If the connection is lost Next re-establishes the changefeed before
+// continuing.
+//
+// Next returns false if the changefeed was closed or if an error happened
+// which could not be recovered from, the Err method should be called to check
+// for an error.
+func (rc *ResilientCursor) Next(dest interface{}) bool {
+	for {
+		rc.mu.Lock()
+		if rc.closed || rc.lastErr != nil {
+			rc.mu.Unlock()
+			return false
+		}
+		cursor := rc.cursor
+		rc.mu.Unlock()
+
+		if cursor.Next(dest) {
+			return true
+		}
+
+		err := cursor.Err()
+		if err == nil {
+			// The feed ended without an error
+			return false
+		}
+
+		rc.mu.Lock()
+		closed := rc.closed
+		rc.mu.Unlock()
+		if closed {
+			return false
+		}
+
+		if !isChangefeedRetryable(err) {
+			rc.setErr(err)
+			return false
+		}
+		if !rc.reconnect(err) {
+			return false
+		}
+	}
+}
+
+func (rc *ResilientCursor) reconnect(cause error) bool {
+	lastErr := cause
+	for attempt := 1; rc.opts.MaxRetries == 0 || attempt <= rc.opts.MaxRetries; attempt++ {
+		select {
+		case <-rc.done:
+			return false
+		case <-time.After(rc.opts.RetryDelay):
+		}
+
+		cursor, err := rc.term.Run(rc.executor, rc.opts.RunOpts)
+		if err != nil {
+			if !isChangefeedRetryable(err) {
+				rc.setErr(err)
+				return false
+			}
+			lastErr = err
+			continue
+		}
+
+		rc.mu.Lock()
+		if rc.closed {
+			rc.mu.Unlock()
+			cursor.Close()
+			return false
+		}
+		rc.cursor = cursor
+		rc.mu.Unlock()
+
+		if rc.opts.OnReconnect != nil {
+			rc.opts.OnReconnect(cause)
+		}
+
+		return true
+	}
+
+	rc.setErr(lastErr)
+	return false
+}
+
+func (rc *ResilientCursor) setErr(err error) {
+	rc.mu.Lock()
+	rc.lastErr = err
+	rc.mu.Unlock()
+}
+
+// Err returns nil if no errors happened during iteration, or the actual
+// error otherwise.
+func (rc *ResilientCursor) Err() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return rc.lastErr
+}
+
+// Close closes the changefeed, stopping any further attempts to re-establish
+// it.
+func (rc *ResilientCursor) Close() error {
+	rc.mu.Lock()
+	if rc.closed {
+		rc.mu.Unlock()
+		return nil
+	}
+	rc.closed = true
+	close(rc.done)
+	cursor := rc.cursor
+	rc.mu.Unlock()
+
+	return cursor.Close()
+}
+
+// isChangefeedRetryable returns true if the changefeed should be
+// re-established after err.
+func isChangefeedRetryable(err error) bool {
+	return isConnectionError(err) || err == ErrCircuitOpen
+}
+
+// ChangesChannel runs the changefeed query t and sends each change to
+// channel, decoding the changes into the element type of the channel. The
+// channel is closed once the feed ends, ctx is done or an error happens,
+// after which the returned channel receives the error (if any) and is also
+// closed. The channel's buffer determines how many changes may be waiting to
+// be received before reading from the changefeed is paused.
+//
+//	type userChange struct {
+//	    NewVal *User `rethinkdb:"new_val"`
+//	    OldVal *User `rethinkdb:"old_val"`
+//	}
+//
+//	changes := make(chan userChange, 100)
+//	errc := r.ChangesChannel(ctx, session, r.Table("users").Changes(), changes)
+//	for change := range changes {
+//	    ...
+//	}
+//	if err := <-errc; err != nil {
+//	    // error
+//	}
+//
+// Unless RunOpts.Context is set ctx is also used to run the query, so
+// cancelling ctx interrupts the initial query as well as reading the feed. A
+// nil ctx is treated as context.Background().
+//
+// If channel is nil or is not a channel which changes can be sent to then an
+// error is sent to the returned channel without running the query.
+func ChangesChannel(ctx context.Context, s QueryExecutor, t Term, channel interface{}, optArgs ...RunOpts) <-chan error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	errc := make(chan error, 1)
+	channelv := reflect.ValueOf(channel)
+	if channelv.Kind() != reflect.Chan || channelv.Type().ChanDir()&reflect.SendDir == 0 || channelv.IsNil() {
+		errc <- RQLDriverError{rqlError(fmt.Sprintf("ChangesChannel: channel must be a non-nil channel which can be sent to, got %T", channel))}
+		close(errc)
+		return errc
+	}
+
+	go func() {
+		defer close(errc)
+		defer channelv.Close()
+
+		var opts RunOpts
+		if len(optArgs) >= 1 {
+			opts = optArgs[0]
+		}
+		if opts.Context == nil {
+			opts.Context = ctx
+		}
+		cursor, err := t.Run(s, opts)
+		if err != nil {
+			errc <- err
+			return
+		}
+
+		// Closing the cursor interrupts a pending call to Next
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				cursor.Close()
+			case <-stop:
+			}
+		}()
+
+		elemt := channelv.Type().Elem()
+		done := reflect.ValueOf(ctx.Done())
+		for {
+			elemp := reflect.New(elemt)
+			if !cursor.Next(elemp.Interface()) {
+				break
+			}
+
+			chosen, _, _ := reflect.Select([]reflect.SelectCase{
+				{Dir: reflect.SelectSend, Chan: channelv, Send: elemp.Elem()},
+				{Dir: reflect.SelectRecv, Chan: done},
+			})
+			if chosen == 1 {
+				break
+			}
+		}
+		cursor.Close()
+
+		if err := ctx.Err(); err != nil {
+			errc <- err
+		} else if err := cursor.Err(); err != nil {
+			errc <- err
+		}
+	}()
+
+	return errc
+}
@@ -0,0 +1,203 @@
+package rethinkdb
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	test "gopkg.in/check.v1"
+)
+
+type ChangefeedSuite struct{}
+
+var _ = test.Suite(&ChangefeedSuite{})
+
+// flakyExecutor wraps a Mock and fails the first n cursors it returns with a
+// connection error.
+type flakyExecutor struct {
+	*Mock
+	failures int
+	runs     int
+}
+
+func (e *flakyExecutor) Query(ctx context.Context, q Query) (*Cursor, error) {
+	cursor, err := e.Mock.Query(ctx, q)
+	e.runs++
+	if err == nil && e.runs <= e.failures {
+		cursor.lastErr = RQLConnectionError{rqlError("connection reset")}
+	}
+	return cursor, err
+}
+
+func (s *ChangefeedSuite) TestResilientChangefeed_Reconnects(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Changes()).Return([]interface{}{1, 2}, nil)
+	executor := &flakyExecutor{Mock: mock, failures: 1}
+
+	var reconnectErr error
+	cursor, err := ResilientChangefeed(executor, Table("test").Changes(), ResilientChangefeedOpts{
+		RetryDelay: time.Millisecond,
+		OnReconnect: func(err error) {
+			reconnectErr = err
+		},
+	})
+	c.Assert(err, test.IsNil)
+
+	var results []int
+	var result int
+	for cursor.Next(&result) {
+		results = append(results, result)
+	}
+	c.Assert(cursor.Err(), test.IsNil)
+	c.Assert(results, test.DeepEquals, []int{1, 2})
+	c.Assert(reconnectErr, test.FitsTypeOf, RQLConnectionError{})
+	c.Assert(executor.runs, test.Equals, 2)
+	c.Assert(cursor.Close(), test.IsNil)
+}
+
+func (s *ChangefeedSuite) TestResilientChangefeed_MaxRetries(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Changes()).Return([]interface{}{1}, nil).Once()
+	mock.On(Table("test").Changes()).Return(nil, RQLConnectionError{rqlError("connection refused")})
+	executor := &flakyExecutor{Mock: mock, failures: 1}
+
+	cursor, err := ResilientChangefeed(executor, Table("test").Changes(), ResilientChangefeedOpts{
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+	})
+	c.Assert(err, test.IsNil)
+
+	var result int
+	c.Assert(cursor.Next(&result), test.Equals, false)
+	c.Assert(cursor.Err(), test.FitsTypeOf, RQLConnectionError{})
+	c.Assert(executor.runs, test.Equals, 3)
+}
+
+func (s *ChangefeedSuite) TestResilientChangefeed_ServerErrorNotRetried(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Changes()).Return(nil, RQLOpFailedError{}).Once()
+	mock.On(Table("test").Changes()).Return([]interface{}{1}, nil)
+
+	_, err := ResilientChangefeed(mock, Table("test").Changes())
+	c.Assert(err, test.FitsTypeOf, RQLOpFailedError{})
+}
+
+type testChange struct {
+	NewVal *int `rethinkdb:"new_val"`
+	OldVal *int `rethinkdb:"old_val"`
+}
+
+func (s *ChangefeedSuite) TestChangesChannel(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Changes()).Return([]interface{}{
+		map[string]interface{}{"new_val": 1, "old_val": nil},
+		map[string]interface{}{"new_val": 2, "old_val": 1},
+	}, nil)
+
+	changes := make(chan testChange, 1)
+	errc := ChangesChannel(context.Background(), mock, Table("test").Changes(), changes)
+
+	var results []testChange
+	for change := range changes {
+		results = append(results, change)
+	}
+	c.Assert(<-errc, test.IsNil)
+	c.Assert(results, test.HasLen, 2)
+	c.Assert(results[0].OldVal, test.IsNil)
+	c.Assert(*results[0].NewVal, test.Equals, 1)
+	c.Assert(*results[1].OldVal, test.Equals, 1)
+	c.Assert(*results[1].NewVal, test.Equals, 2)
+}
+
+func (s *ChangefeedSuite) TestChangesChannel_Cancel(c *test.C) {
+	feed := make(chan []interface{})
+	mock := NewMock()
+	mock.On(Table("test").Changes()).Return(feed, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan testChange)
+	errc := ChangesChannel(ctx, mock, Table("test").Changes(), changes)
+
+	feed <- []interface{}{map[string]interface{}{"new_val": 1}}
+	change := <-changes
+	c.Assert(*change.NewVal, test.Equals, 1)
+
+	cancel()
+	// Answer the pending fetch so the mock connection accepts the STOP query
+	close(feed)
+	for range changes {
+	}
+	c.Assert(<-errc, test.Equals, context.Canceled)
+}
+
+type contextExecutor struct {
+	*Mock
+	ctx context.Context
+}
+
+func (e *contextExecutor) Query(ctx context.Context, q Query) (*Cursor, error) {
+	e.ctx = ctx
+	return e.Mock.Query(ctx, q)
+}
+
+func (s *ChangefeedSuite) TestChangesChannel_Context(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Changes()).Return([]interface{}{}, nil)
+	executor := &contextExecutor{Mock: mock}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "feed")
+	changes := make(chan testChange)
+	c.Assert(<-ChangesChannel(ctx, executor, Table("test").Changes(), changes), test.IsNil)
+	c.Assert(executor.ctx, test.Equals, ctx)
+
+	// An explicit RunOpts.Context is used to run the query instead
+	runCtx := context.WithValue(context.Background(), key{}, "run")
+	changes = make(chan testChange)
+	c.Assert(<-ChangesChannel(ctx, executor, Table("test").Changes(), changes, RunOpts{Context: runCtx}), test.IsNil)
+	c.Assert(executor.ctx, test.Equals, runCtx)
+}
+
+func (s *ChangefeedSuite) TestChangesChannel_RunError(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Changes()).Return(nil, RQLConnectionError{rqlError("connection refused")})
+
+	changes := make(chan testChange)
+	errc := ChangesChannel(context.Background(), mock, Table("test").Changes(), changes)
+
+	_, ok := <-changes
+	c.Assert(ok, test.Equals, false)
+	c.Assert(<-errc, test.FitsTypeOf, RQLConnectionError{})
+}
+
+func (s *ChangefeedSuite) TestChangesChannel_InvalidChannel(c *test.C) {
+	executor := &contextExecutor{Mock: NewMock()}
+
+	var nilChannel chan testChange
+	for _, channel := range []interface{}{
+		nil,
+		[]testChange{},
+		make(<-chan testChange),
+		nilChannel,
+	} {
+		err := <-ChangesChannel(context.Background(), executor, Table("test").Changes(), channel)
+		c.Assert(err, test.FitsTypeOf, RQLDriverError{}, test.Commentf("channel %T", channel))
+	}
+	c.Assert(executor.ctx, test.IsNil)
+}
+
+func (s *ChangefeedSuite) TestChangesChannel_NilContext(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Changes()).Return([]interface{}{
+		map[string]interface{}{"new_val": 1, "old_val": nil},
+	}, nil)
+
+	changes := make(chan testChange, 1)
+	errc := ChangesChannel(nil, mock, Table("test").Changes(), changes)
+
+	var results []testChange
+	for change := range changes {
+		results = append(results, change)
+	}
+	c.Assert(<-errc, test.IsNil)
+	c.Assert(results, test.HasLen, 1)
+}
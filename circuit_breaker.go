@@ -0,0 +1,144 @@
+package rethinkdb
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultCircuitBreakerFailures = 5
+	defaultCircuitBreakerWindow   = 10 * time.Second
+	defaultCircuitBreakerCooldown = 30 * time.Second
+)
+
+// CircuitBreakerOpts configures the circuit breaker used by a session, for
+// more information see ConnectOpts.CircuitBreaker.
+type CircuitBreakerOpts struct {
+	// Failures is the number of consecutive connection failures within Window
+	// which cause the circuit to open. Defaults to 5.
+	Failures int
+	// Window is the duration in which the consecutive failures must occur.
+	// Defaults to 10 seconds.
+	Window time.Duration
+	// Cooldown is the duration for which queries fail fast once the circuit
+	// has opened, after the cooldown a single query is allowed through to
+	// probe whether the database has recovered. Defaults to 30 seconds.
+	Cooldown time.Duration
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker tracks connection failures and fails queries fast while the
+// database is unavailable. A nil circuitBreaker allows all queries.
+type circuitBreaker struct {
+	failures int
+	window   time.Duration
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu           sync.Mutex
+	state        circuitState
+	count        int
+	firstFailure time.Time
+	openedAt     time.Time
+	probing      bool
+}
+
+func newCircuitBreaker(opts *CircuitBreakerOpts) *circuitBreaker {
+	if opts == nil {
+		return nil
+	}
+
+	cb := &circuitBreaker{
+		failures: opts.Failures,
+		window:   opts.Window,
+		cooldown: opts.Cooldown,
+		now:      time.Now,
+	}
+	if cb.failures <= 0 {
+		cb.failures = defaultCircuitBreakerFailures
+	}
+	if cb.window <= 0 {
+		cb.window = defaultCircuitBreakerWindow
+	}
+	if cb.cooldown <= 0 {
+		cb.cooldown = defaultCircuitBreakerCooldown
+	}
+
+	return cb
+}
+
+// allow returns ErrCircuitOpen if the query should not be sent.
+func (cb *circuitBreaker) allow() error {
+	if cb == nil {
+		return nil
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if cb.now().Sub(cb.openedAt) < cb.cooldown {
+			return ErrCircuitOpen
+		}
+		cb.state = circuitHalfOpen
+		cb.probing = true
+		return nil
+	case circuitHalfOpen:
+		// Only a single probe is allowed at a time
+		if cb.probing {
+			return ErrCircuitOpen
+		}
+		cb.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record updates the state of the circuit using the result of a query which
+// was allowed by allow.
+func (cb *circuitBreaker) record(err error) {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if !isConnectionError(err) {
+		cb.state = circuitClosed
+		cb.count = 0
+		cb.probing = false
+		return
+	}
+
+	now := cb.now()
+	if cb.state == circuitHalfOpen {
+		cb.open(now)
+		return
+	}
+
+	if cb.count == 0 || now.Sub(cb.firstFailure) > cb.window {
+		cb.count = 0
+		cb.firstFailure = now
+	}
+	cb.count++
+	if cb.count >= cb.failures {
+		cb.open(now)
+	}
+}
+
+func (cb *circuitBreaker) open(now time.Time) {
+	cb.state = circuitOpen
+	cb.openedAt = now
+	cb.count = 0
+	cb.probing = false
+}
@@ -0,0 +1,94 @@
+package rethinkdb
+
+import (
+	"time"
+
+	test "gopkg.in/check.v1"
+)
+
+type CircuitBreakerSuite struct{}
+
+var _ = test.Suite(&CircuitBreakerSuite{})
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestCircuitBreaker(clock *fakeClock) *circuitBreaker {
+	cb := newCircuitBreaker(&CircuitBreakerOpts{
+		Failures: 2,
+		Window:   time.Second,
+		Cooldown: time.Minute,
+	})
+	cb.now = clock.now
+	return cb
+}
+
+func (s *CircuitBreakerSuite) TestCircuitBreaker_Nil(c *test.C) {
+	var cb *circuitBreaker
+	c.Assert(newCircuitBreaker(nil), test.IsNil)
+	c.Assert(cb.allow(), test.IsNil)
+	cb.record(ErrConnectionClosed)
+	c.Assert(cb.allow(), test.IsNil)
+}
+
+func (s *CircuitBreakerSuite) TestCircuitBreaker_Opens(c *test.C) {
+	clock := &fakeClock{t: time.Now()}
+	cb := newTestCircuitBreaker(clock)
+
+	c.Assert(cb.allow(), test.IsNil)
+	cb.record(ErrConnectionClosed)
+	c.Assert(cb.allow(), test.IsNil)
+	cb.record(RQLConnectionError{rqlError("connection refused")})
+
+	c.Assert(cb.allow(), test.Equals, ErrCircuitOpen)
+	clock.t = clock.t.Add(30 * time.Second)
+	c.Assert(cb.allow(), test.Equals, ErrCircuitOpen)
+}
+
+func (s *CircuitBreakerSuite) TestCircuitBreaker_IgnoresQueryErrors(c *test.C) {
+	clock := &fakeClock{t: time.Now()}
+	cb := newTestCircuitBreaker(clock)
+
+	cb.record(ErrConnectionClosed)
+	cb.record(RQLOpFailedError{})
+	cb.record(ErrConnectionClosed)
+	c.Assert(cb.allow(), test.IsNil)
+}
+
+func (s *CircuitBreakerSuite) TestCircuitBreaker_Window(c *test.C) {
+	clock := &fakeClock{t: time.Now()}
+	cb := newTestCircuitBreaker(clock)
+
+	cb.record(ErrConnectionClosed)
+	clock.t = clock.t.Add(2 * time.Second)
+	cb.record(ErrConnectionClosed)
+	c.Assert(cb.allow(), test.IsNil)
+}
+
+func (s *CircuitBreakerSuite) TestCircuitBreaker_HalfOpen(c *test.C) {
+	clock := &fakeClock{t: time.Now()}
+	cb := newTestCircuitBreaker(clock)
+
+	cb.record(ErrConnectionClosed)
+	cb.record(ErrConnectionClosed)
+	c.Assert(cb.allow(), test.Equals, ErrCircuitOpen)
+
+	// After the cooldown a single probe is allowed, a failure re-opens the circuit
+	clock.t = clock.t.Add(time.Minute)
+	c.Assert(cb.allow(), test.IsNil)
+	c.Assert(cb.allow(), test.Equals, ErrCircuitOpen)
+	cb.record(ErrConnectionClosed)
+	c.Assert(cb.allow(), test.Equals, ErrCircuitOpen)
+
+	// A successful probe closes the circuit
+	clock.t = clock.t.Add(time.Minute)
+	c.Assert(cb.allow(), test.IsNil)
+	cb.record(nil)
+	c.Assert(cb.allow(), test.IsNil)
+	c.Assert(cb.allow(), test.IsNil)
+}
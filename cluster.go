@@ -3,6 +3,7 @@ package rethinkdb
 import (
 	"errors"
 	"fmt"
+	"math/rand"
 	"sort"
 	"strings"
 	"sync"
@@ -13,6 +14,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	"gopkg.in/cenkalti/backoff.v2"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
 var errClusterClosed = errors.New("rethinkdb: cluster is closed")
@@ -39,18 +41,28 @@ type Cluster struct {
 	closed int32            // 0 - working, 1 - closed
 
 	connFactory connFactory
+	breaker     *circuitBreaker
+	events      chan Event
 
 	discoverInterval time.Duration
 }
 
 // NewCluster creates a new cluster by connecting to the given hosts.
 func NewCluster(hosts []Host, opts *ConnectOpts) (*Cluster, error) {
+	return newCluster(hosts, opts, nil)
+}
+
+// newCluster creates a new cluster which sends its events to the given
+// channel, events are discarded if the channel is nil.
+func newCluster(hosts []Host, opts *ConnectOpts, events chan Event) (*Cluster, error) {
 	c := &Cluster{
 		hp:          newHostPool(opts),
 		seeds:       hosts,
 		opts:        opts,
 		closed:      clusterWorking,
 		connFactory: NewConnection,
+		breaker:     newCircuitBreaker(opts.CircuitBreaker),
+		events:      events,
 	}
 
 	err := c.run()
@@ -80,19 +92,26 @@ func (c *Cluster) run() error {
 
 // Query executes a ReQL query using the cluster to connect to the database
 func (c *Cluster) Query(ctx context.Context, q Query) (cursor *Cursor, err error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	defer func() { c.breaker.record(err) }()
+
 	for i := 0; i < c.numRetries(); i++ {
 		var node *Node
 		var hpr hostpool.HostPoolResponse
 
-		node, hpr, err = c.GetNextNode()
+		node, hpr, err = c.getNodeForQuery(q)
 		if err != nil {
 			return nil, err
 		}
 
 		cursor, err = node.Query(ctx, q)
-		hpr.Mark(err)
+		if hpr != nil {
+			hpr.Mark(err)
+		}
 
-		if !shouldRetryQuery(q, err) {
+		if !c.handleQueryError(node, q, err, i) {
 			break
 		}
 	}
@@ -102,6 +121,11 @@ func (c *Cluster) Query(ctx context.Context, q Query) (cursor *Cursor, err error
 
 // Exec executes a ReQL query using the cluster to connect to the database
 func (c *Cluster) Exec(ctx context.Context, q Query) (err error) {
+	if err := c.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { c.breaker.record(err) }()
+
 	for i := 0; i < c.numRetries(); i++ {
 		var node *Node
 		var hpr hostpool.HostPoolResponse
@@ -114,7 +138,7 @@ func (c *Cluster) Exec(ctx context.Context, q Query) (err error) {
 		err = node.Exec(ctx, q)
 		hpr.Mark(err)
 
-		if !shouldRetryQuery(q, err) {
+		if !c.handleQueryError(node, q, err, i) {
 			break
 		}
 	}
@@ -122,6 +146,22 @@ func (c *Cluster) Exec(ctx context.Context, q Query) (err error) {
 	return err
 }
 
+// handleQueryError sends the events caused by attempt i of the query failing
+// with err and returns true if the query should be retried.
+func (c *Cluster) handleQueryError(node *Node, q Query, err error, i int) bool {
+	if err != nil && isConnectionError(err) {
+		sendEvent(c.events, EventConnectionLost, node.Host.String(), err)
+	}
+	if !c.shouldRetry(q, err, i+1) {
+		return false
+	}
+	if i+1 < c.numRetries() {
+		sendEvent(c.events, EventRetryPerformed, node.Host.String(), err)
+	}
+
+	return true
+}
+
 // Server returns the server name and server UUID being used by a connection.
 func (c *Cluster) Server() (response ServerResponse, err error) {
 	for i := 0; i < c.numRetries(); i++ {
@@ -268,6 +308,7 @@ func (c *Cluster) listenForNodeChanges() error {
 					node, err := c.connectNodeWithStatus(result.NewVal)
 					if err == nil {
 						c.addNode(node)
+						sendEvent(c.events, EventHostDiscovered, node.Host.String(), nil)
 
 						Log.WithFields(logrus.Fields{
 							"id":   node.ID,
@@ -400,7 +441,38 @@ func (c *Cluster) connectNode(id string, aliases []Host) (*Node, error) {
 		return nil, ErrInvalidNode
 	}
 
-	return newNode(id, aliases, pool), nil
+	node := newNode(id, aliases, pool)
+	if c.opts.ReadNodeTag != "" || c.opts.WriteNodeTag != "" {
+		node.tags, err = fetchServerTags(pool, id, c.opts)
+		if err != nil {
+			Log.Warnf("Error fetching server tags: %s", err)
+		}
+	}
+
+	return node, nil
+}
+
+// fetchServerTags returns the tags of the server with the given ID, as set in
+// the server_config system table.
+func fetchServerTags(pool *Pool, id string, opts *ConnectOpts) ([]string, error) {
+	q, err := newQuery(
+		DB(SystemDatabase).Table(ServerConfigSystemTable).Get(id).Field("tags").Default([]interface{}{}),
+		map[string]interface{}{},
+		opts,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Error building query: %s", err)
+	}
+
+	cursor, err := pool.Query(nil, q) // nil = connection opts' timeout
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	err = cursor.One(&tags)
+
+	return tags, err
 }
 
 // IsConnected returns true if cluster has nodes and is not already connClosed.
@@ -427,6 +499,81 @@ func (c *Cluster) GetNextNode() (*Node, hostpool.HostPoolResponse, error) {
 	return nil, nil, ErrNoConnections
 }
 
+// getNodeForQuery returns the node requested by the query if the cluster is
+// connected to it, otherwise the next node is returned. The host pool
+// response is nil when the requested node was returned.
+func (c *Cluster) getNodeForQuery(q Query) (*Node, hostpool.HostPoolResponse, error) {
+	if q.node != "" {
+		if node := c.findNode(q.node); node != nil {
+			return node, nil, nil
+		}
+	}
+	if tag := c.nodeTagForQuery(q); tag != "" {
+		if node := c.findTaggedNode(tag); node != nil {
+			return node, nil, nil
+		}
+	}
+
+	return c.GetNextNode()
+}
+
+// nodeTagForQuery returns the server tag of the nodes which should preferably
+// run the query, see ConnectOpts.ReadNodeTag and ConnectOpts.WriteNodeTag.
+func (c *Cluster) nodeTagForQuery(q Query) string {
+	if q.Type != p.Query_START || q.Term == nil {
+		return ""
+	}
+	if isWriteTerm(q.Term) {
+		return c.opts.WriteNodeTag
+	}
+
+	return c.opts.ReadNodeTag
+}
+
+// findTaggedNode returns a random open node with the given server tag.
+func (c *Cluster) findTaggedNode(tag string) *Node {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var nodes []*Node
+	for _, n := range c.nodes {
+		if n.hasTag(tag) && !n.Closed() {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	return nodes[rand.Intn(len(nodes))]
+}
+
+// findNode returns the open node with the given ID or address.
+func (c *Cluster) findNode(idOrHost string) *Node {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, n := range c.nodes {
+		if (n.ID == idOrHost || strings.EqualFold(n.Host.String(), idOrHost)) && !n.Closed() {
+			return n
+		}
+	}
+
+	return nil
+}
+
+// healthyHosts returns the number of distinct hosts with a healthy node.
+func (c *Cluster) healthyHosts() int {
+	hosts := map[string]struct{}{}
+	for _, n := range c.GetNodes() {
+		if n.isHealthy() {
+			hosts[n.Host.String()] = struct{}{}
+		}
+	}
+
+	return len(hosts)
+}
+
 // GetNodes returns a list of all nodes in the cluster
 func (c *Cluster) GetNodes() []*Node {
 	c.mu.RLock()
@@ -511,6 +658,17 @@ func (c *Cluster) removeNode(nodeID string) *Node {
 	return rmNode
 }
 
+// shouldRetry returns true if the query should be retried after failing with
+// err, either because of a connection error or as ConnectOpts.ShouldRetry
+// returned true.
+func (c *Cluster) shouldRetry(q Query, err error, attempt int) bool {
+	if shouldRetryQuery(q, err) {
+		return true
+	}
+
+	return err != nil && c.opts.ShouldRetry != nil && c.opts.ShouldRetry(err, attempt)
+}
+
 func (c *Cluster) numRetries() int {
 	if n := c.opts.NumRetries; n > 0 {
 		return n
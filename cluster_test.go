@@ -569,3 +569,160 @@ func makeContinueQueryRaw(token int64) []byte {
 	binary.LittleEndian.PutUint32(b[8:], uint32(len(b)-respHeaderLen))
 	return b
 }
+
+func (s *ClusterSuite) TestCluster_GetNodeForQuery(c *test.C) {
+	host1 := Host{Name: "host1", Port: 28015}
+	host2 := Host{Name: "host2", Port: 28015}
+
+	opts := &ConnectOpts{}
+	cluster := &Cluster{
+		hp:     newHostPool(opts),
+		opts:   opts,
+		closed: clusterWorking,
+	}
+	cluster.replaceNodes([]*Node{
+		newNode("node1", []Host{host1}, nil),
+		newNode("node2", []Host{host2}, nil),
+	})
+
+	node, hpr, err := cluster.getNodeForQuery(Query{node: "node2"})
+	c.Assert(err, test.IsNil)
+	c.Assert(hpr, test.IsNil)
+	c.Assert(node.ID, test.Equals, "node2")
+
+	node, hpr, err = cluster.getNodeForQuery(Query{node: "HOST1:28015"})
+	c.Assert(err, test.IsNil)
+	c.Assert(hpr, test.IsNil)
+	c.Assert(node.ID, test.Equals, "node1")
+
+	// Unknown nodes fall back to the host pool
+	node, hpr, err = cluster.getNodeForQuery(Query{node: "node3"})
+	c.Assert(err, test.IsNil)
+	c.Assert(hpr, test.NotNil)
+	c.Assert(node, test.NotNil)
+}
+
+func (s *ClusterSuite) TestCluster_GetNodeForQueryByTag(c *test.C) {
+	host1 := Host{Name: "host1", Port: 28015}
+	host2 := Host{Name: "host2", Port: 28015}
+
+	opts := &ConnectOpts{ReadNodeTag: "replica", WriteNodeTag: "primary"}
+	cluster := &Cluster{
+		hp:     newHostPool(opts),
+		opts:   opts,
+		closed: clusterWorking,
+	}
+	node1 := newNode("node1", []Host{host1}, nil)
+	node1.tags = []string{"default", "primary"}
+	node2 := newNode("node2", []Host{host2}, nil)
+	node2.tags = []string{"default", "replica"}
+	cluster.replaceNodes([]*Node{node1, node2})
+
+	for i := 0; i < 10; i++ {
+		node, hpr, err := cluster.getNodeForQuery(testQuery(Table("t").Filter(map[string]interface{}{"a": 1})))
+		c.Assert(err, test.IsNil)
+		c.Assert(hpr, test.IsNil)
+		c.Assert(node.ID, test.Equals, "node2")
+
+		node, hpr, err = cluster.getNodeForQuery(testQuery(Table("t").Insert(map[string]interface{}{"a": 1})))
+		c.Assert(err, test.IsNil)
+		c.Assert(hpr, test.IsNil)
+		c.Assert(node.ID, test.Equals, "node1")
+
+		node, hpr, err = cluster.getNodeForQuery(testQuery(Table("t").ForEach(func(row Term) Term {
+			return Table("u").Get(row.Field("id")).Delete()
+		})))
+		c.Assert(err, test.IsNil)
+		c.Assert(hpr, test.IsNil)
+		c.Assert(node.ID, test.Equals, "node1")
+	}
+
+	// Queries fall back to the host pool when no node has the tag
+	node2.tags = nil
+	node, hpr, err := cluster.getNodeForQuery(testQuery(Table("t")))
+	c.Assert(err, test.IsNil)
+	c.Assert(hpr, test.NotNil)
+	c.Assert(node, test.NotNil)
+}
+
+func (s *ClusterSuite) TestCluster_QueryEvents(c *test.C) {
+	host1 := Host{Name: "host1", Port: 28015}
+	connErr := RQLConnectionError{rqlError("dial tcp: connection refused")}
+
+	opts := &ConnectOpts{NumRetries: 2}
+	pool, err := newPool(host1, opts, func(host string, opts *ConnectOpts) (*Connection, error) {
+		return nil, connErr
+	})
+	c.Assert(err, test.IsNil)
+
+	cluster := &Cluster{
+		hp:     newHostPool(opts),
+		opts:   opts,
+		closed: clusterWorking,
+		events: newEventChan(),
+	}
+	cluster.replaceNodes([]*Node{newNode("node1", []Host{host1}, pool)})
+
+	_, err = cluster.Query(nil, testQuery(Table("t")))
+	c.Assert(err, test.Equals, connErr)
+
+	var types []EventType
+	for len(cluster.events) > 0 {
+		e := <-cluster.events
+		c.Assert(e.Host, test.Equals, host1.String())
+		c.Assert(e.Err, test.Equals, connErr)
+		types = append(types, e.Type)
+	}
+	c.Assert(types, test.DeepEquals, []EventType{EventConnectionLost, EventRetryPerformed, EventConnectionLost})
+}
+
+func (s *ClusterSuite) TestCluster_EventsDropped(c *test.C) {
+	events := newEventChan()
+	for i := 0; i < eventBufferSize+10; i++ {
+		sendEvent(events, EventHostDiscovered, "host1:28015", nil)
+	}
+	c.Assert(events, test.HasLen, eventBufferSize)
+
+	// A nil channel discards events
+	sendEvent(nil, EventHostDiscovered, "host1:28015", nil)
+}
+
+func (s *ClusterSuite) TestCluster_QueryShouldRetry(c *test.C) {
+	host1 := Host{Name: "host1", Port: 28015}
+	unavailableErr := RQLOpFailedError{}
+
+	var attempts []int
+	opts := &ConnectOpts{
+		NumRetries: 5,
+		ShouldRetry: func(err error, attempt int) bool {
+			c.Assert(err, test.Equals, unavailableErr)
+			attempts = append(attempts, attempt)
+			return attempt < 3
+		},
+	}
+	dials := 0
+	pool, err := newPool(host1, opts, func(host string, opts *ConnectOpts) (*Connection, error) {
+		dials++
+		return nil, unavailableErr
+	})
+	c.Assert(err, test.IsNil)
+
+	cluster := &Cluster{
+		hp:     newHostPool(opts),
+		opts:   opts,
+		closed: clusterWorking,
+	}
+	cluster.replaceNodes([]*Node{newNode("node1", []Host{host1}, pool)})
+
+	_, err = cluster.Query(nil, testQuery(Table("t")))
+	c.Assert(err, test.Equals, unavailableErr)
+	c.Assert(dials, test.Equals, 3)
+	c.Assert(attempts, test.DeepEquals, []int{1, 2, 3})
+
+	// Without ShouldRetry runtime errors are not retried
+	opts.ShouldRetry = nil
+	dials = 0
+	_, err = cluster.Query(nil, testQuery(Table("t")))
+	c.Assert(err, test.Equals, unavailableErr)
+	c.Assert(dials, test.Equals, 1)
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"sync/atomic"
 	"time"
@@ -39,6 +41,11 @@ type Response struct {
 	Responses []json.RawMessage         `json:"r"`
 	Backtrace []interface{}             `json:"b"`
 	Profile   interface{}               `json:"p"`
+
+	// tooLarge is set instead of decoding the response when it exceeds the
+	// maximum size set for the query, see RunOpts.MaxResponseBytes. Only the
+	// Type of the response is decoded.
+	tooLarge bool
 }
 
 // Connection is a connection to a rethinkdb database. Connection is not thread
@@ -54,10 +61,16 @@ type Connection struct {
 	cursors            map[int64]*Cursor
 	bad                int32 // 0 - not bad, 1 - bad
 	closed             int32 // 0 - working, 1 - closed
+	inflight           int32 // number of queries waiting for a response
+	openCursors        int32 // number of cursors waiting for more responses
+	lastUsed           int64 // time the last query was sent, in unix nanoseconds
+	rttNanos           int64 // moving average of the round-trip time, in nanoseconds
+	createdAt          time.Time
 	stopReadChan       chan bool
 	readRequestsChan   chan tokenAndPromise
 	responseChan       chan responseAndError
 	stopProcessingChan chan struct{}
+	responseLimits     sync.Map // token -> maximum response size in bytes
 	mu                 sync.Mutex
 }
 
@@ -131,6 +144,8 @@ func newConnection(conn net.Conn, address string, opts *ConnectOpts) *Connection
 		readRequestsChan:   make(chan tokenAndPromise, 16),
 		responseChan:       make(chan responseAndError, 16),
 		stopProcessingChan: make(chan struct{}),
+		lastUsed:           time.Now().UnixNano(),
+		createdAt:          time.Now(),
 	}
 	return c
 }
@@ -155,10 +170,15 @@ func (c *Connection) Close() error {
 // Cursor which should be used to view the query's response.
 //
 // This function is used internally by Run which should be used for most queries.
-func (c *Connection) Query(ctx context.Context, q Query) (*Response, *Cursor, error) {
+func (c *Connection) Query(ctx context.Context, q Query) (r *Response, cur *Cursor, err error) {
 	if c == nil {
 		return nil, nil, ErrConnectionClosed
 	}
+	atomic.AddInt32(&c.inflight, 1)
+	defer atomic.AddInt32(&c.inflight, -1)
+	if !q.probe {
+		atomic.StoreInt64(&c.lastUsed, time.Now().UnixNano())
+	}
 	if c.Conn == nil || c.isClosed() {
 		c.setBad()
 		return nil, nil, ErrConnectionClosed
@@ -171,9 +191,20 @@ func (c *Connection) Query(ctx context.Context, q Query) (*Response, *Cursor, er
 	if q.Type == p.Query_START || q.Type == p.Query_NOREPLY_WAIT || q.Type == p.Query_SERVER_INFO {
 		q.Token = c.nextToken()
 	}
+	if q.Type == p.Query_START && q.maxResponseBytes > 0 {
+		c.responseLimits.Store(q.Token, q.maxResponseBytes)
+		defer func() {
+			// The limit is only kept for the CONTINUE queries of a cursor
+			if err != nil || cur == nil {
+				c.responseLimits.Delete(q.Token)
+			}
+		}()
+	}
+	if q.Type == p.Query_STOP {
+		defer c.responseLimits.Delete(q.Token)
+	}
 	if q.Type == p.Query_START || q.Type == p.Query_NOREPLY_WAIT {
 		if c.opts.Database != "" {
-			var err error
 			q.Opts["db"], err = DB(c.opts.Database).Build()
 			if err != nil {
 				return nil, nil, RQLDriverError{rqlError(err.Error())}
@@ -195,7 +226,7 @@ func (c *Connection) Query(ctx context.Context, q Query) (*Response, *Cursor, er
 		}
 	}
 
-	err := c.sendQuery(q)
+	err = c.sendQuery(q)
 	if err != nil {
 		if fetchingSpan != nil {
 			ext.Error.Set(fetchingSpan, true)
@@ -209,20 +240,46 @@ func (c *Connection) Query(ctx context.Context, q Query) (*Response, *Cursor, er
 	}
 
 	if noreply, ok := q.Opts["noreply"]; ok && noreply.(bool) {
+		if q.Type == p.Query_STOP {
+			// The server still responds to STOP queries, the response is
+			// read and discarded so the stopped query's cursor is removed.
+			// This is skipped rather than blocking if the connection is
+			// busy, in which case the cursor is removed when the
+			// connection is closed.
+			select {
+			case c.readRequestsChan <- tokenAndPromise{ctx: context.Background(), query: &q}:
+			default:
+			}
+		}
 		return nil, nil, nil
 	}
 
+	// The batch timeout only applies to waiting for this response, the
+	// cursor keeps ctx and applies the timeout to each batch it fetches.
+	waitCtx := ctx
+	if q.batchTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, q.batchTimeout)
+		defer cancel()
+	}
+
 	promise := make(chan responseAndCursor, 1)
 	select {
 	case c.readRequestsChan <- tokenAndPromise{ctx: ctx, query: &q, span: fetchingSpan, promise: promise}:
-	case <-ctx.Done():
+	case <-waitCtx.Done():
 		return c.stopQuery(&q)
 	}
 
 	select {
 	case future := <-promise:
+		if future.err == ErrResponseTooLarge && q.Type == p.Query_START &&
+			future.response.Type == p.Response_SUCCESS_PARTIAL {
+			// The response was the first batch of a cursor which is still
+			// running on the server
+			_, _, _ = c.Query(c.contextFromConnectionOpts(), newStopQuery(q.Token))
+		}
 		return future.response, future.cursor, future.err
-	case <-ctx.Done():
+	case <-waitCtx.Done():
 		return c.stopQuery(&q)
 	case <-c.stopProcessingChan: // connection readRequests processing stopped, promise can be never answered
 		return nil, nil, ErrConnectionClosed
@@ -248,6 +305,12 @@ func (c *Connection) startTracingSpan(parentSpan opentracing.Span, q *Query) ope
 
 	if q.Type == p.Query_START {
 		span.LogFields(log.String("query", q.Term.String()))
+		if id := q.Term.QueryID(); id != "" {
+			span.SetTag("rethinkdb.query_id", id)
+		}
+	}
+	if c.opts.ApplicationName != "" {
+		span.SetTag("rethinkdb.application_name", c.opts.ApplicationName)
 	}
 
 	return span
@@ -339,14 +402,24 @@ type ServerResponse struct {
 
 // Server returns the server name and server UUID being used by a connection.
 func (c *Connection) Server() (ServerResponse, error) {
+	return c.server(false)
+}
+
+// server sends a SERVER_INFO query, if probe is set the query only measures
+// the round-trip time and does not count as using the connection, so idle
+// connections still expire after ConnectOpts.ConnMaxIdleTime.
+func (c *Connection) server(probe bool) (ServerResponse, error) {
 	var response ServerResponse
 
+	start := time.Now()
 	_, cur, err := c.Query(c.contextFromConnectionOpts(), Query{
-		Type: p.Query_SERVER_INFO,
+		Type:  p.Query_SERVER_INFO,
+		probe: probe,
 	})
 	if err != nil {
 		return response, err
 	}
+	c.recordRTT(time.Since(start))
 
 	if err = cur.One(&response); err != nil {
 		return response, err
@@ -410,6 +483,19 @@ func (c *Connection) readResponse() (*Response, error) {
 	responseToken := int64(binary.LittleEndian.Uint64(headerBuf[:8]))
 	messageLength := binary.LittleEndian.Uint32(headerBuf[8:])
 
+	// Skip responses larger than the limit set for the query without
+	// allocating a buffer for them
+	if limit, ok := c.responseLimits.Load(responseToken); ok && int(messageLength) > limit.(int) {
+		body := io.LimitReader(c.Conn, int64(messageLength))
+		responseType := scanResponseType(body)
+		if _, err := io.Copy(ioutil.Discard, body); err != nil {
+			c.setBad()
+			return nil, RQLConnectionError{rqlError(err.Error())}
+		}
+
+		return &Response{Token: responseToken, Type: responseType, tooLarge: true}, nil
+	}
+
 	// Read the JSON encoding of the Response itself.
 	b := make([]byte, int(messageLength))
 
@@ -429,6 +515,48 @@ func (c *Connection) readResponse() (*Response, error) {
 	return response, nil
 }
 
+// scanResponseType reads the JSON encoding of a response from r until its
+// type is found, without buffering the other fields. Zero is returned if the
+// response has no type or is malformed.
+func scanResponseType(r io.Reader) p.Response_ResponseType {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return 0
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return 0
+		}
+		if key == "t" {
+			var responseType p.Response_ResponseType
+			if err := dec.Decode(&responseType); err != nil {
+				return 0
+			}
+			return responseType
+		}
+
+		// Skip the field's value one token at a time
+		depth := 0
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return 0
+			}
+			switch tok {
+			case json.Delim('{'), json.Delim('['):
+				depth++
+			case json.Delim('}'), json.Delim(']'):
+				depth--
+			}
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	return 0
+}
+
 // Called to fill response for the query
 func (c *Connection) processResponse(ctx context.Context, q Query, response *Response, span opentracing.Span) (r *Response, cur *Cursor, err error) {
 	if span != nil {
@@ -441,13 +569,20 @@ func (c *Connection) processResponse(ctx context.Context, q Query, response *Res
 		}()
 	}
 
+	if response.tooLarge || response.Type != p.Response_SUCCESS_PARTIAL {
+		c.responseLimits.Delete(response.Token)
+	}
+	if response.tooLarge {
+		return response, c.processErrorResponse(response), ErrResponseTooLarge
+	}
+
 	switch response.Type {
 	case p.Response_CLIENT_ERROR:
-		return response, c.processErrorResponse(response), createClientError(response, q.Term)
+		return response, c.processErrorResponse(response), createClientError(response, q.Term, queryDatabase(q.Opts))
 	case p.Response_COMPILE_ERROR:
-		return response, c.processErrorResponse(response), createCompileError(response, q.Term)
+		return response, c.processErrorResponse(response), createCompileError(response, q.Term, queryDatabase(q.Opts))
 	case p.Response_RUNTIME_ERROR:
-		return response, c.processErrorResponse(response), createRuntimeError(response.ErrorType, response, q.Term)
+		return response, c.processErrorResponse(response), createRuntimeError(response.ErrorType, response, q.Term, queryDatabase(q.Opts))
 	case p.Response_SUCCESS_ATOM, p.Response_SERVER_INFO:
 		return c.processAtomResponse(ctx, q, response)
 	case p.Response_SUCCESS_PARTIAL:
@@ -463,7 +598,7 @@ func (c *Connection) processResponse(ctx context.Context, q Query, response *Res
 
 func (c *Connection) processErrorResponse(response *Response) *Cursor {
 	cursor := c.cursors[response.Token]
-	delete(c.cursors, response.Token)
+	c.removeCursor(response.Token)
 	return cursor
 }
 
@@ -497,8 +632,11 @@ func (c *Connection) processPartialResponse(ctx context.Context, q Query, respon
 		// Create a new cursor if needed
 		cursor = newCursor(ctx, c, cursorType, response.Token, q.Term, q.Opts)
 		cursor.profile = response.Profile
+		cursor.prefetch = q.prefetch
+		cursor.batchTimeout = q.batchTimeout
 
 		c.cursors[response.Token] = cursor
+		atomic.AddInt32(&c.openCursors, 1)
 	}
 
 	cursor.extend(response)
@@ -513,7 +651,7 @@ func (c *Connection) processSequenceResponse(ctx context.Context, q Query, respo
 		cursor = newCursor(ctx, c, "Cursor", response.Token, q.Term, q.Opts)
 		cursor.profile = response.Profile
 	}
-	delete(c.cursors, response.Token)
+	c.removeCursor(response.Token)
 
 	cursor.extend(response)
 
@@ -521,14 +659,44 @@ func (c *Connection) processSequenceResponse(ctx context.Context, q Query, respo
 }
 
 func (c *Connection) processWaitResponse(response *Response) (*Response, *Cursor, error) {
-	delete(c.cursors, response.Token)
+	c.removeCursor(response.Token)
 	return response, nil, nil
 }
 
+// removeCursor removes the cursor with the given token, if any, once the
+// database has sent its last response.
+func (c *Connection) removeCursor(token int64) {
+	if _, ok := c.cursors[token]; ok {
+		delete(c.cursors, token)
+		atomic.AddInt32(&c.openCursors, -1)
+	}
+}
+
 func (c *Connection) setBad() {
 	atomic.StoreInt32(&c.bad, connBad)
 }
 
+func (c *Connection) inflightQueries() int {
+	return int(atomic.LoadInt32(&c.inflight))
+}
+
+// isExpired returns true if the connection has no queries or cursors in use
+// and has either been open for longer than ConnMaxLifetime or not been used
+// for longer than ConnMaxIdleTime.
+func (c *Connection) isExpired(now time.Time) bool {
+	if c.inflightQueries() > 0 || atomic.LoadInt32(&c.openCursors) > 0 {
+		return false
+	}
+	if d := c.opts.ConnMaxLifetime; d > 0 && now.Sub(c.createdAt) >= d {
+		return true
+	}
+	if d := c.opts.ConnMaxIdleTime; d > 0 && now.Sub(time.Unix(0, atomic.LoadInt64(&c.lastUsed))) >= d {
+		return true
+	}
+
+	return false
+}
+
 func (c *Connection) isBad() bool {
 	return atomic.LoadInt32(&c.bad) == connBad
 }
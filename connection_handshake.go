@@ -54,6 +54,9 @@ func (c *connectionHandshakeV0_4) Send() error {
 	// Read handshake response
 	if err := c.readHandshakeSuccess(); err != nil {
 		c.conn.Close()
+		if _, ok := err.(RQLAuthError); ok {
+			return err
+		}
 		return RQLConnectionError{rqlError(err.Error())}
 	}
 
@@ -99,7 +102,11 @@ func (c *connectionHandshakeV0_4) readHandshakeSuccess() error {
 	if response != "SUCCESS" {
 		response = strings.TrimSpace(response)
 		// we failed authorization or something else terrible happened
-		return RQLDriverError{rqlError(fmt.Sprintf("Server dropped connection with message: \"%s\"", response))}
+		err := RQLDriverError{rqlError(fmt.Sprintf("Server dropped connection with message: \"%s\"", response))}
+		if strings.Contains(response, "authorization key") {
+			return RQLAuthError{err}
+		}
+		return err
 	}
 
 	return nil
@@ -146,6 +153,7 @@ func (c *connectionHandshakeV1_0) Send() error {
 
 	// Check server nonce
 	if !strings.HasPrefix(serverNonce, clientNonce) {
+		c.conn.Close()
 		return RQLAuthError{RQLDriverError{rqlError("Invalid nonce from server")}}
 	}
 
@@ -438,7 +446,8 @@ func (c *connectionHandshakeV1_0) serverSignature(saltedPass []byte) string {
 }
 
 func (c *connectionHandshakeV1_0) handshakeError(code int, message string) error {
-	if code >= 10 || code <= 20 {
+	// Error codes 10 to 20 are used by the server for authentication failures
+	if code >= 10 && code <= 20 {
 		return RQLAuthError{RQLDriverError{rqlError(message)}}
 	}
 
@@ -0,0 +1,82 @@
+package rethinkdb
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+
+	test "gopkg.in/check.v1"
+)
+
+type HandshakeSuite struct{}
+
+var _ = test.Suite(&HandshakeSuite{})
+
+// runHandshakeServer reads the first message sent by the client and replies
+// with the given responses.
+func runHandshakeServer(conn net.Conn, responses ...string) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	if _, err := reader.ReadBytes('\x00'); err != nil {
+		return
+	}
+	for _, rsp := range responses {
+		if _, err := conn.Write([]byte(rsp + "\x00")); err != nil {
+			return
+		}
+	}
+}
+
+func (s *HandshakeSuite) TestHandshakeV1_0_AuthFailed(c *test.C) {
+	client, server := net.Pipe()
+	go runHandshakeServer(server,
+		`{"success":true,"min_protocol_version":0,"max_protocol_version":0,"server_version":"2.4.0"}`,
+		`{"success":false,"error":"Wrong password","error_code":12}`,
+	)
+
+	conn := newConnection(client, "test", &ConnectOpts{Username: "admin", Password: "wrong"})
+	handshake, err := conn.handshake(HandshakeV1_0)
+	c.Assert(err, test.IsNil)
+
+	err = handshake.Send()
+	c.Assert(err, test.FitsTypeOf, RQLAuthError{})
+	c.Assert(errors.Is(err, ErrAuthFailed), test.Equals, true)
+}
+
+func (s *HandshakeSuite) TestHandshakeV1_0_NonAuthError(c *test.C) {
+	client, server := net.Pipe()
+	go runHandshakeServer(server,
+		`{"success":false,"error":"Unsupported protocol","error_code":1}`,
+	)
+
+	conn := newConnection(client, "test", &ConnectOpts{})
+	handshake, err := conn.handshake(HandshakeV1_0)
+	c.Assert(err, test.IsNil)
+
+	err = handshake.Send()
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+	c.Assert(errors.Is(err, ErrAuthFailed), test.Equals, false)
+}
+
+func (s *HandshakeSuite) TestHandshakeV0_4_AuthFailed(c *test.C) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		// Read the protocol version, auth key length, auth key and protocol type
+		buf := make([]byte, 4+4+len("key")+4)
+		if _, err := io.ReadFull(server, buf); err != nil {
+			return
+		}
+		server.Write([]byte("ERROR: Incorrect authorization key.\n\x00"))
+	}()
+
+	conn := newConnection(client, "test", &ConnectOpts{AuthKey: "key"})
+	handshake, err := conn.handshake(HandshakeV0_4)
+	c.Assert(err, test.IsNil)
+
+	err = handshake.Send()
+	c.Assert(err, test.FitsTypeOf, RQLAuthError{})
+	c.Assert(errors.Is(err, ErrAuthFailed), test.Equals, true)
+}
@@ -3,6 +3,7 @@ package rethinkdb
 import (
 	"golang.org/x/net/context"
 	"io"
+	"time"
 )
 
 // Write 'data' to conn
@@ -17,14 +18,20 @@ func (c *Connection) read(buf []byte) (total int, err error) {
 }
 
 func (c *Connection) contextFromConnectionOpts() context.Context {
-	// back compatibility
-	min := c.opts.ReadTimeout
-	if c.opts.WriteTimeout < min {
-		min = c.opts.WriteTimeout
-	}
+	min := connectionOptsTimeout(c.opts)
 	if min == 0 {
 		return context.Background()
 	}
 	ctx, _ := context.WithTimeout(context.Background(), min)
 	return ctx
 }
+
+// connectionOptsTimeout returns the timeout of queries run without a context.
+func connectionOptsTimeout(opts *ConnectOpts) time.Duration {
+	// back compatibility
+	min := opts.ReadTimeout
+	if opts.WriteTimeout < min {
+		min = opts.WriteTimeout
+	}
+	return min
+}
@@ -10,7 +10,10 @@ import (
 	test "gopkg.in/check.v1"
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 	"io"
+	"net"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -584,3 +587,303 @@ func serializeAtomResponse() []byte {
 	})
 	return b
 }
+
+func (s *ConnectionSuite) TestConnection_Query_ResponseTooLarge(c *test.C) {
+	ctx := context.Background()
+	token := int64(1)
+	q := testQuery(DB("db").Table("table"))
+	q.maxResponseBytes = 10
+	writeData := serializeQuery(token, q)
+	stopData := serializeQuery(token, newStopQuery(token))
+	respData, _ := json.Marshal(map[string]interface{}{"t": p.Response_SUCCESS_PARTIAL, "r": []interface{}{"response"}})
+	stopRespData, _ := json.Marshal(map[string]interface{}{"t": p.Response_SUCCESS_SEQUENCE, "r": []interface{}{}})
+
+	stopSent := make(chan struct{})
+	conn := &connMock{}
+	conn.On("Write", writeData).Return(len(writeData), nil, nil)
+	conn.On("Write", stopData).Return(len(stopData), nil, nil).Once().Run(func(args mock.Arguments) {
+		close(stopSent)
+	})
+	conn.On("Read", respHeaderLen).Return(respHeader(token, respData), respHeaderLen, nil, nil).Once()
+	conn.On("Read", len(respData)).Return(respData, len(respData), nil, nil).Once()
+	conn.On("Read", respHeaderLen).Return(respHeader(token, stopRespData), respHeaderLen, nil, nil).Once().Run(func(args mock.Arguments) {
+		<-stopSent
+	})
+	conn.On("Read", len(stopRespData)).Return(stopRespData, len(stopRespData), nil, nil).Once()
+	conn.onCloseReturn(nil)
+
+	connection := newConnection(conn, "addr", &ConnectOpts{})
+	done := runConnection(connection)
+	response, cursor, err := connection.Query(ctx, q)
+	connection.Close()
+	<-done
+
+	c.Assert(err, test.Equals, ErrResponseTooLarge)
+	c.Assert(cursor, test.IsNil)
+	c.Assert(response.Responses, test.IsNil)
+	_, ok := connection.responseLimits.Load(token)
+	c.Assert(ok, test.Equals, false)
+	conn.AssertExpectations(c)
+}
+
+func (s *ConnectionSuite) TestConnection_Query_ResponseTooLargeComplete(c *test.C) {
+	ctx := context.Background()
+	token := int64(1)
+	q := testQuery(DB("db").Table("table"))
+	q.maxResponseBytes = 10
+	writeData := serializeQuery(token, q)
+	respData := serializeAtomResponse()
+
+	// No STOP query is expected as the query is complete
+	conn := &connMock{}
+	conn.On("Write", writeData).Return(len(writeData), nil, nil)
+	conn.On("Read", respHeaderLen).Return(respHeader(token, respData), respHeaderLen, nil, nil).Once()
+	conn.On("Read", len(respData)).Return(respData, len(respData), nil, nil).Once()
+	conn.On("Read", respHeaderLen).Return(nil, 0, io.EOF, nil)
+	conn.onCloseReturn(nil)
+
+	connection := newConnection(conn, "addr", &ConnectOpts{})
+	done := runConnection(connection)
+	response, cursor, err := connection.Query(ctx, q)
+	connection.Close()
+	<-done
+
+	c.Assert(err, test.Equals, ErrResponseTooLarge)
+	c.Assert(cursor, test.IsNil)
+	c.Assert(response.Type, test.Equals, p.Response_SUCCESS_ATOM)
+	c.Assert(response.Responses, test.IsNil)
+	_, ok := connection.responseLimits.Load(token)
+	c.Assert(ok, test.Equals, false)
+	conn.AssertExpectations(c)
+}
+
+func (s *ConnectionSuite) TestConnection_Query_ResponseLimitRemovedOnTimeout(c *test.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	token := int64(1)
+	q := testQuery(DB("db").Table("table"))
+	q.maxResponseBytes = 10
+	writeData := serializeQuery(token, q)
+	stopData := serializeQuery(token, newStopQuery(token))
+
+	conn := &connMock{}
+	conn.On("Write", writeData).Return(len(writeData), nil, nil)
+	conn.On("Write", stopData).Return(len(stopData), nil, nil)
+	conn.onCloseReturn(nil)
+
+	connection := newConnection(conn, "addr", &ConnectOpts{})
+	_, _, err := connection.Query(ctx, q)
+	connection.Close()
+
+	c.Assert(err, test.Equals, ErrQueryTimeout)
+	_, ok := connection.responseLimits.Load(token)
+	c.Assert(ok, test.Equals, false)
+	conn.AssertExpectations(c)
+}
+
+func (s *ConnectionSuite) TestConnection_ScanResponseType(c *test.C) {
+	c.Assert(scanResponseType(strings.NewReader(`{"t":3,"r":[1,2]}`)), test.Equals, p.Response_SUCCESS_PARTIAL)
+	c.Assert(scanResponseType(strings.NewReader(`{"r":[{"a":[1,{}]},"b"],"n":[],"t":2}`)), test.Equals, p.Response_SUCCESS_SEQUENCE)
+	c.Assert(scanResponseType(strings.NewReader(`{"r":[1,2]}`)), test.Equals, p.Response_ResponseType(0))
+	c.Assert(scanResponseType(strings.NewReader(`{"r":[1,`)), test.Equals, p.Response_ResponseType(0))
+}
+
+// serveCursors responds to the queries sent over conn, START queries receive
+// a partial response and CONTINUE queries the final response of a sequence,
+// or a runtime error for the failing tokens. The tokens of stopped queries are
+// sent to stopped.
+func serveCursors(conn net.Conn, stopped chan<- int64, failing ...int64) {
+	header := make([]byte, respHeaderLen)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		token := int64(binary.LittleEndian.Uint64(header))
+		body := make([]byte, binary.LittleEndian.Uint32(header[8:]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		var query []interface{}
+		_ = json.Unmarshal(body, &query)
+
+		var resp map[string]interface{}
+		switch p.Query_QueryType(query[0].(float64)) {
+		case p.Query_START:
+			resp = map[string]interface{}{"t": p.Response_SUCCESS_PARTIAL, "r": []interface{}{token*10 + 1}}
+		case p.Query_CONTINUE:
+			resp = map[string]interface{}{"t": p.Response_SUCCESS_SEQUENCE, "r": []interface{}{token*10 + 2}}
+			for _, t := range failing {
+				if t == token {
+					resp = map[string]interface{}{"t": p.Response_RUNTIME_ERROR, "r": []interface{}{"Cannot perform bracket on a non-object non-sequence `1`."}}
+				}
+			}
+		case p.Query_STOP:
+			stopped <- token
+			resp = map[string]interface{}{"t": p.Response_SUCCESS_SEQUENCE, "r": []interface{}{}}
+		}
+		b, _ := json.Marshal(resp)
+		if _, err := conn.Write(append(respHeader(token, b), b...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *ConnectionSuite) TestConnection_CloseCursor(c *test.C) {
+	client, server := net.Pipe()
+	stopped := make(chan int64, 2)
+	go serveCursors(server, stopped)
+
+	connection := newConnection(client, "addr", &ConnectOpts{})
+	done := runConnection(connection)
+	defer func() {
+		connection.Close()
+		<-done
+	}()
+
+	ctx := context.Background()
+	_, cursor1, err := connection.Query(ctx, testQuery(DB("db").Table("a")))
+	c.Assert(err, test.IsNil)
+	_, cursor2, err := connection.Query(ctx, testQuery(DB("db").Table("b")))
+	c.Assert(err, test.IsNil)
+	c.Assert(atomic.LoadInt32(&connection.openCursors), test.Equals, int32(2))
+
+	// Closing the first cursor only stops its own query
+	c.Assert(cursor1.Close(), test.IsNil)
+	c.Assert(<-stopped, test.Equals, cursor1.token)
+
+	var rows []int64
+	c.Assert(cursor2.All(&rows), test.IsNil)
+	c.Assert(rows, test.DeepEquals, []int64{cursor2.token*10 + 1, cursor2.token*10 + 2})
+	c.Assert(stopped, test.HasLen, 0)
+
+	// The response to the STOP query removes the first cursor
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&connection.openCursors) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	c.Assert(atomic.LoadInt32(&connection.openCursors), test.Equals, int32(0))
+}
+
+func (s *ConnectionSuite) TestConnection_ReuseAfterCursor(c *test.C) {
+	client, server := net.Pipe()
+	stopped := make(chan int64, 2)
+	// The first query fails after its first batch
+	go serveCursors(server, stopped, 1)
+
+	connection := newConnection(client, "addr", &ConnectOpts{})
+	done := runConnection(connection)
+	defer func() {
+		connection.Close()
+		<-done
+	}()
+
+	ctx := context.Background()
+	_, failing, err := connection.Query(ctx, testQuery(DB("db").Table("a")))
+	c.Assert(err, test.IsNil)
+	var rows []int64
+	c.Assert(failing.All(&rows), test.FitsTypeOf, RQLRuntimeError{})
+	// The server has already terminated the failed query so it is not stopped
+	c.Assert(failing.Close(), test.IsNil)
+
+	_, early, err := connection.Query(ctx, testQuery(DB("db").Table("b")))
+	c.Assert(err, test.IsNil)
+	var row int64
+	c.Assert(early.Next(&row), test.Equals, true)
+	c.Assert(row, test.Equals, early.token*10+1)
+	c.Assert(early.Close(), test.IsNil)
+	c.Assert(<-stopped, test.Equals, early.token)
+
+	// The connection is reused and receives the responses of the new query
+	_, cursor, err := connection.Query(ctx, testQuery(DB("db").Table("c")))
+	c.Assert(err, test.IsNil)
+	c.Assert(cursor.All(&rows), test.IsNil)
+	c.Assert(rows, test.DeepEquals, []int64{cursor.token*10 + 1, cursor.token*10 + 2})
+	c.Assert(stopped, test.HasLen, 0)
+}
+
+// serveSlowBatches answers queries after delay, the cursor of each query
+// returns batches batches. SERVER_INFO queries are answered immediately.
+func serveSlowBatches(conn net.Conn, delay time.Duration, batches int) {
+	header := make([]byte, respHeaderLen)
+	sent := map[int64]int{}
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		token := int64(binary.LittleEndian.Uint64(header))
+		body := make([]byte, binary.LittleEndian.Uint32(header[8:]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		var query []interface{}
+		_ = json.Unmarshal(body, &query)
+
+		var resp map[string]interface{}
+		switch p.Query_QueryType(query[0].(float64)) {
+		case p.Query_SERVER_INFO:
+			resp = map[string]interface{}{"t": p.Response_SERVER_INFO, "r": []interface{}{map[string]interface{}{"id": "1", "name": "server"}}}
+		case p.Query_STOP:
+			resp = map[string]interface{}{"t": p.Response_SUCCESS_SEQUENCE, "r": []interface{}{}}
+		default:
+			time.Sleep(delay)
+			sent[token]++
+			resp = map[string]interface{}{"t": p.Response_SUCCESS_PARTIAL, "r": []interface{}{sent[token]}}
+			if sent[token] == batches {
+				resp["t"] = p.Response_SUCCESS_SEQUENCE
+			}
+		}
+		b, _ := json.Marshal(resp)
+		if _, err := conn.Write(append(respHeader(token, b), b...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *ConnectionSuite) TestConnection_BatchTimeout(c *test.C) {
+	client, server := net.Pipe()
+	go serveSlowBatches(server, 30*time.Millisecond, 4)
+
+	connection := newConnection(client, "addr", &ConnectOpts{})
+	done := runConnection(connection)
+	defer func() {
+		connection.Close()
+		<-done
+	}()
+
+	// The timeout applies to each batch rather than the whole query
+	q := testQuery(DB("db").Table("a"))
+	q.batchTimeout = 80 * time.Millisecond
+	_, cursor, err := connection.Query(context.Background(), q)
+	c.Assert(err, test.IsNil)
+	var rows []int
+	c.Assert(cursor.All(&rows), test.IsNil)
+	c.Assert(rows, test.DeepEquals, []int{1, 2, 3, 4})
+
+	q = testQuery(DB("db").Table("b"))
+	q.batchTimeout = 5 * time.Millisecond
+	_, _, err = connection.Query(context.Background(), q)
+	c.Assert(err, test.Equals, ErrQueryTimeout)
+}
+
+func (s *ConnectionSuite) TestConnection_ProbeDoesNotUseConnection(c *test.C) {
+	client, server := net.Pipe()
+	go serveSlowBatches(server, 0, 1)
+
+	connection := newConnection(client, "addr", &ConnectOpts{ConnMaxIdleTime: time.Minute})
+	done := runConnection(connection)
+	defer func() {
+		connection.Close()
+		<-done
+	}()
+
+	idle := time.Now().Add(-time.Hour).UnixNano()
+	atomic.StoreInt64(&connection.lastUsed, idle)
+	_, err := connection.server(true)
+	c.Assert(err, test.IsNil)
+	c.Assert(atomic.LoadInt64(&connection.lastUsed), test.Equals, idle)
+	c.Assert(connection.rtt() > 0, test.Equals, true)
+
+	_, err = connection.Server()
+	c.Assert(err, test.IsNil)
+	c.Assert(atomic.LoadInt64(&connection.lastUsed) > idle, test.Equals, true)
+}
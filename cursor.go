@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"reflect"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"golang.org/x/net/context"
@@ -14,8 +16,7 @@ import (
 )
 
 var (
-	errNilCursor    = errors.New("cursor is nil")
-	errCursorClosed = errors.New("connection connClosed, cannot read cursor")
+	errNilCursor = errors.New("cursor is nil")
 )
 
 func newCursor(ctx context.Context, conn *Connection, cursorType string, token int64, term *Term, opts map[string]interface{}) *Cursor {
@@ -24,13 +25,16 @@ func newCursor(ctx context.Context, conn *Connection, cursorType string, token i
 	}
 
 	connOpts := &ConnectOpts{}
+	var host string
 	if conn != nil {
 		connOpts = conn.opts
+		host = conn.address
 	}
 
 	cursor := &Cursor{
 		conn:       conn,
 		connOpts:   connOpts,
+		host:       host,
 		token:      token,
 		cursorType: cursorType,
 		term:       term,
@@ -63,12 +67,17 @@ type Cursor struct {
 
 	conn       *Connection
 	connOpts   *ConnectOpts
+	host       string
 	token      int64
 	cursorType string
 	term       *Term
 	opts       map[string]interface{}
 	ctx        context.Context
+	// batchTimeout is the timeout of fetching each batch, see
+	// RunOpts.TimeoutRTTMultiplier.
+	batchTimeout time.Duration
 
+	closeOnce     sync.Once
 	mu            sync.RWMutex
 	lastErr       error
 	fetching      bool
@@ -80,6 +89,45 @@ type Cursor struct {
 	buffer        []interface{}
 	responses     []json.RawMessage
 	profile       interface{}
+	notes         ResponseNotes
+
+	prefetch       bool
+	batchSize      int
+	prefetchDone   chan struct{}
+	prefetchCancel context.CancelFunc
+}
+
+// Host returns the address ("host:port") of the server the query which
+// created the cursor was sent to. It can be passed to RunOpts.Node to send a
+// later query to the same server.
+func (c *Cursor) Host() string {
+	if c == nil {
+		return ""
+	}
+
+	return c.host
+}
+
+// Database returns the default database used by the query which created the
+// cursor, either inherited from the session (see Session.Use) or set using
+// RunOpts.DB. Tables referenced without an explicit DB term are looked up in
+// this database.
+func (c *Cursor) Database() string {
+	if c == nil {
+		return ""
+	}
+
+	return queryDatabase(c.opts)
+}
+
+// QueryID returns the ID generated by ConnectOpts.QueryIDGenerator for the
+// query which created the cursor, or an empty string if no ID was generated.
+func (c *Cursor) QueryID() string {
+	if c == nil || c.term == nil {
+		return ""
+	}
+
+	return c.term.queryID
 }
 
 // Profile returns the information returned from the query profiler.
@@ -94,6 +142,55 @@ func (c *Cursor) Profile() interface{} {
 	return c.profile
 }
 
+// ResponseNotes contains the notes the server attaches to the responses of a
+// query, which describe the kind of changefeed returned by the query. The
+// protocol does not include notes about the consistency of the results, such
+// as whether a read was outdated.
+type ResponseNotes struct {
+	// SequenceFeed is set for changefeeds on a table or a sequence.
+	SequenceFeed bool
+	// AtomFeed is set for changefeeds on a single document.
+	AtomFeed bool
+	// OrderByLimitFeed is set for changefeeds on an OrderBy and Limit query.
+	OrderByLimitFeed bool
+	// UnionedFeed is set for changefeeds made from the union of several
+	// changefeeds.
+	UnionedFeed bool
+	// IncludesStates is set if the changefeed includes state documents, see
+	// ChangesOpts.IncludeStates.
+	IncludesStates bool
+}
+
+func (n *ResponseNotes) add(notes []p.Response_ResponseNote) {
+	for _, note := range notes {
+		switch note {
+		case p.Response_SEQUENCE_FEED:
+			n.SequenceFeed = true
+		case p.Response_ATOM_FEED:
+			n.AtomFeed = true
+		case p.Response_ORDER_BY_LIMIT_FEED:
+			n.OrderByLimitFeed = true
+		case p.Response_UNIONED_FEED:
+			n.UnionedFeed = true
+		case p.Response_INCLUDES_STATES:
+			n.IncludesStates = true
+		}
+	}
+}
+
+// Notes returns the notes attached by the server to the responses received by
+// the cursor so far.
+func (c *Cursor) Notes() ResponseNotes {
+	if c == nil {
+		return ResponseNotes{}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.notes
+}
+
 // Type returns the cursor type (by default "Cursor")
 func (c *Cursor) Type() string {
 	if c == nil {
@@ -120,34 +217,46 @@ func (c *Cursor) Err() error {
 }
 
 // Close closes the cursor, preventing further enumeration. If the end is
-// encountered, the cursor is connClosed automatically. Close is idempotent.
+// encountered, the cursor is connClosed automatically. Close is idempotent and
+// is safe to call while another goroutine is iterating the cursor, in which
+// case the iterating call returns false and Err returns ErrCursorClosed.
 func (c *Cursor) Close() error {
 	if c == nil {
 		return errNilCursor
 	}
 
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.close()
+	})
+
+	return err
+}
+
+func (c *Cursor) close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	var err error
 
-	// If cursor is already connClosed return immediately
-	closed := c.closed
-	if closed {
-		return nil
-	}
-
-	// Get connection and check its valid, don't need to lock as this is only
-	// set when the cursor is created
 	conn := c.conn
-	if conn == nil {
-		return nil
+
+	c.closed = true
+	c.conn = nil
+	c.buffer = nil
+	c.responses = nil
+
+	if c.prefetchCancel != nil {
+		c.prefetchCancel()
 	}
-	if conn.isClosed() {
+
+	// Check the connection is still valid before stopping the query
+	if conn == nil || conn.isClosed() {
 		return nil
 	}
 
-	// Stop any unfinished queries
+	// Stop any unfinished queries, only the query of this cursor is stopped
+	// and other queries using the same connection are unaffected
 	if !c.finished {
 		_, _, err = conn.Query(c.ctx, newStopQuery(c.token))
 	}
@@ -162,11 +271,6 @@ func (c *Cursor) Close() error {
 		span.Finish()
 	}
 
-	c.closed = true
-	c.conn = nil
-	c.buffer = nil
-	c.responses = nil
-
 	return err
 }
 
@@ -345,6 +449,140 @@ func (c *Cursor) nextResponseLocked() ([]byte, bool, error) {
 	}
 }
 
+// NextStream behaves like Next however top-level fields of dest which
+// implement encoding.StreamUnmarshaler are passed the raw JSON returned by the
+// database instead of a fully decoded value. Only the intermediate
+// interface{} value Next decodes these fields into is saved, the raw JSON of
+// the response is still held in memory while it is read and fields of any
+// other type are decoded as usual. If dest itself implements
+// encoding.StreamUnmarshaler, such as LazyMap, it is passed the raw JSON of
+// the whole document.
+//
+// Unlike Next each call to NextStream consumes a whole response from the
+// database, so atom responses containing arrays are decoded as a single value.
+func (c *Cursor) NextStream(dest interface{}) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+
+	b, hasMore, err := c.nextResponseLocked()
+	if err == nil && hasMore {
+		err = c.decodeStream(dest, b)
+	}
+	if c.handleErrorLocked(err) != nil {
+		c.mu.Unlock()
+		c.Close()
+		return false
+	}
+	c.mu.Unlock()
+
+	if !hasMore {
+		c.Close()
+	}
+
+	return hasMore
+}
+
+// decodeOptionsSetter is implemented by values which decode raw JSON after
+// being read using NextStream, such as LazyMap, they are passed the run
+// options of the query and ConnectOpts.UseJSONNumber.
+type decodeOptionsSetter interface {
+	setDecodeOptions(opts map[string]interface{}, useNumber bool)
+}
+
+func (c *Cursor) decodeStream(dest interface{}, b []byte) error {
+	if _, ok := dest.(encoding.StreamUnmarshaler); ok {
+		if err := encoding.Decode(dest, json.RawMessage(b)); err != nil {
+			return err
+		}
+		c.setDecodeOptions(dest)
+		return nil
+	}
+
+	var streamFields []string
+	if dest != nil {
+		streamFields = encoding.StreamFieldNames(reflect.TypeOf(dest))
+	}
+
+	decode := func(b []byte) (interface{}, error) {
+		return decodeResponse(b, c.opts, c.connOpts.UseJSONNumber)
+	}
+
+	var fields map[string]json.RawMessage
+	if len(streamFields) == 0 || json.Unmarshal(b, &fields) != nil || fields == nil {
+		// Not a document (or nothing to stream) so decode as normal
+		value, err := decode(b)
+		if err != nil {
+			return err
+		}
+		return encoding.Decode(dest, value)
+	}
+
+	doc := make(map[string]interface{}, len(fields))
+	for k, raw := range fields {
+		if isStreamField(k, streamFields) {
+			doc[k] = raw
+			continue
+		}
+
+		value, err := decode(raw)
+		if err != nil {
+			return err
+		}
+		doc[k] = value
+	}
+
+	if err := encoding.Decode(dest, doc); err != nil {
+		return err
+	}
+	c.setDecodeOptions(dest)
+	return nil
+}
+
+// setDecodeOptions passes the decoding options of the cursor to dest and its
+// top-level fields if they implement decodeOptionsSetter.
+func (c *Cursor) setDecodeOptions(dest interface{}) {
+	if s, ok := dest.(decodeOptionsSetter); ok {
+		s.setDecodeOptions(c.opts, c.connOpts.UseJSONNumber)
+		return
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		if f.Kind() != reflect.Ptr {
+			f = f.Addr()
+		} else if f.IsNil() {
+			continue
+		}
+		if s, ok := f.Interface().(decodeOptionsSetter); ok {
+			s.setDecodeOptions(c.opts, c.connOpts.UseJSONNumber)
+		}
+	}
+}
+
+func isStreamField(name string, streamFields []string) bool {
+	for _, f := range streamFields {
+		if f == name || strings.EqualFold(f, name) {
+			return true
+		}
+	}
+	return false
+}
+
 // All retrieves all documents from the result set into the provided slice
 // and closes the cursor.
 //
@@ -355,14 +593,25 @@ func (c *Cursor) nextResponseLocked() ([]byte, bool, error) {
 // `All` zeroes the value before scanning in the result. It also attempts
 // to reuse the existing slice without allocating any more space by either
 // resizing or returning a selection of the slice if necessary.
+//
+// The result of a grouping query can also be read into the address of a map,
+// which is keyed by the groups and contains the reductions. Reductions which
+// are themselves grouped data are decoded into nested maps, for example when
+// grouping by two levels:
+//
+//	var counts map[string]map[string]int
+//	err := res.All(&counts) // counts["books"]["2019"]
 func (c *Cursor) All(result interface{}) error {
 	if c == nil {
 		return errNilCursor
 	}
 
 	resultv := reflect.ValueOf(result)
+	if resultv.Kind() == reflect.Ptr && resultv.Elem().Kind() == reflect.Map {
+		return c.allGrouped(resultv.Elem())
+	}
 	if resultv.Kind() != reflect.Ptr || resultv.Elem().Kind() != reflect.Slice {
-		panic("result argument must be a slice address")
+		panic("result argument must be a slice or map address")
 	}
 	slicev := resultv.Elem()
 	slicev = slicev.Slice(0, slicev.Cap())
@@ -397,6 +646,91 @@ func (c *Cursor) All(result interface{}) error {
 	return nil
 }
 
+// AllDistinct is like All but skips documents equal to a document which was
+// already read, for example:
+//
+//	res, err := r.Table("events").Field("user").Run(session)
+//	var users []string
+//	err = res.AllDistinct(&users)
+//
+// Documents are compared by their JSON encoding and only the distinct
+// documents are kept in memory, so unlike the Distinct term the sequence
+// itself can be larger than the array limit of the server.
+func (c *Cursor) AllDistinct(result interface{}) error {
+	if c == nil {
+		return errNilCursor
+	}
+
+	resultv := reflect.ValueOf(result)
+	if resultv.Kind() != reflect.Ptr || resultv.Elem().Kind() != reflect.Slice {
+		panic("result argument must be a slice address")
+	}
+	slicev := reflect.MakeSlice(resultv.Elem().Type(), 0, 0)
+	elemt := slicev.Type().Elem()
+	seen := map[string]bool{}
+	for {
+		var doc interface{}
+		if !c.Next(&doc) {
+			break
+		}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			_ = c.Close()
+			return err
+		}
+		if seen[string(b)] {
+			continue
+		}
+		seen[string(b)] = true
+
+		elemp := reflect.New(elemt)
+		if err := encoding.Decode(elemp.Interface(), doc); err != nil {
+			_ = c.Close()
+			return err
+		}
+		slicev = reflect.Append(slicev, elemp.Elem())
+	}
+	resultv.Elem().Set(slicev)
+
+	if err := c.Err(); err != nil {
+		_ = c.Close()
+		return err
+	}
+
+	return c.Close()
+}
+
+// allGrouped reads the grouped data returned by the cursor into mapv and
+// closes the cursor.
+func (c *Cursor) allGrouped(mapv reflect.Value) error {
+	var rows []interface{}
+	for {
+		var row interface{}
+		if !c.Next(&row) {
+			break
+		}
+		rows = append(rows, row)
+	}
+
+	if err := c.Err(); err != nil {
+		_ = c.Close()
+		return err
+	}
+	if err := c.Close(); err != nil {
+		return err
+	}
+
+	// With the "map" group format the grouped data is a single map
+	var data interface{} = rows
+	if len(rows) == 1 {
+		if m, ok := rows[0].(map[interface{}]interface{}); ok {
+			data = m
+		}
+	}
+
+	return decodeGroupedData(mapv, data)
+}
+
 // One retrieves a single document from the result set into the provided
 // slice and closes the cursor.
 //
@@ -535,28 +869,114 @@ func (c *Cursor) IsNil() bool {
 // If wait is true then it will wait for the database to reply otherwise it
 // will return after sending the continue query.
 func (c *Cursor) fetchMore() error {
+	// Wait for a pending prefetch instead of requesting the same batch twice
+	if done := c.prefetchDone; done != nil {
+		c.mu.Unlock()
+		<-done
+		c.mu.Lock()
+
+		if c.closed {
+			return ErrCursorClosed
+		}
+		return c.lastErr
+	}
+
+	return c.fetch(c.ctx)
+}
+
+func (c *Cursor) fetch(ctx context.Context) error {
 	var err error
 
 	if !c.fetching {
 		c.fetching = true
 
 		if c.closed {
-			return errCursorClosed
+			return ErrCursorClosed
 		}
 
 		q := Query{
-			Type:  p.Query_CONTINUE,
-			Token: c.token,
+			Type:         p.Query_CONTINUE,
+			Token:        c.token,
+			batchTimeout: c.batchTimeout,
 		}
 
+		conn := c.conn
 		c.mu.Unlock()
-		_, _, err = c.conn.Query(c.ctx, q)
+		var response *Response
+		response, _, err = conn.Query(ctx, q)
 		c.mu.Lock()
+
+		// The cursor may have been closed while the lock was released
+		if c.closed {
+			return ErrCursorClosed
+		}
+		if err != nil && queryTerminated(response, err) {
+			c.finished = true
+		}
 	}
 
 	return err
 }
 
+// queryTerminated returns true if the query of a failed CONTINUE query is no
+// longer running on the server, either because the server responded with an
+// error, because a STOP query was sent when the context was done or because
+// the response which was too large was the last batch. Closing the cursor of
+// a terminated query does not send another STOP query.
+func queryTerminated(response *Response, err error) bool {
+	if err == ErrQueryTimeout {
+		return true
+	}
+	if response == nil {
+		return false
+	}
+	if response.tooLarge {
+		return response.Type != p.Response_SUCCESS_PARTIAL
+	}
+
+	switch response.Type {
+	case p.Response_CLIENT_ERROR, p.Response_COMPILE_ERROR, p.Response_RUNTIME_ERROR:
+		return true
+	default:
+		return false
+	}
+}
+
+// maybePrefetch starts fetching the next batch in the background if
+// prefetching is enabled and no more than one batch is waiting to be read.
+func (c *Cursor) maybePrefetch() {
+	if !c.prefetch || c.cursorType != "Cursor" || c.conn == nil || c.closed || c.finished || c.fetching ||
+		c.prefetchDone != nil || len(c.responses) > c.batchSize {
+		return
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = c.conn.contextFromConnectionOpts()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	c.prefetchDone = done
+	c.prefetchCancel = cancel
+
+	go func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		defer func() {
+			cancel()
+			c.prefetchDone = nil
+			close(done)
+		}()
+
+		if c.closed || c.finished || c.fetching {
+			return
+		}
+		if err := c.fetch(ctx); err != nil && err != ErrCursorClosed {
+			c.handleErrorLocked(err)
+		}
+	}()
+}
+
 // handleError sets the value of lastErr to err if lastErr is not yet set.
 func (c *Cursor) handleError(err error) error {
 	c.mu.Lock()
@@ -582,7 +1002,13 @@ func (c *Cursor) extend(response *Response) {
 }
 
 func (c *Cursor) extendLocked(response *Response) {
+	if c.closed {
+		return
+	}
+
 	c.responses = append(c.responses, response.Responses...)
+	c.notes.add(response.Notes)
+	c.batchSize = len(response.Responses)
 	c.finished = response.Type != p.Response_SUCCESS_PARTIAL
 	c.fetching = false
 	c.isAtom = response.Type == p.Response_SUCCESS_ATOM
@@ -597,7 +1023,7 @@ func (c *Cursor) seekCursor(bufferResponse bool) error {
 	}
 
 	if len(c.buffer) == 0 && len(c.responses) == 0 && c.closed {
-		return errCursorClosed
+		return ErrCursorClosed
 	}
 
 	// Loop over loading data, applying skips as necessary and loading more data as needed
@@ -621,6 +1047,8 @@ func (c *Cursor) seekCursor(bufferResponse bool) error {
 			}
 			continue // go around the loop again to re-apply pending skips
 		}
+
+		c.maybePrefetch()
 		return nil
 	}
 }
@@ -658,12 +1086,66 @@ func (c *Cursor) applyPendingSkips(drainFromBuffer bool) (stillPending bool) {
 	return c.pendingSkips > 0
 }
 
+// changeTypes are the values of the type field of the changes emitted by a
+// changefeed when IncludeTypes is set.
+var changeTypes = map[string]bool{
+	"add":       true,
+	"remove":    true,
+	"change":    true,
+	"initial":   true,
+	"uninitial": true,
+}
+
+// normalizeChange ensures that a change emitted by a changefeed contains both
+// the old_val and new_val fields, a missing field is set to nil. The server
+// omits old_val from the initial values sent when IncludeInitial is set.
+// Only documents with an old_val or new_val field, or with the type of a
+// change, are changes; other documents such as state changes are returned
+// unmodified.
+func normalizeChange(value interface{}) interface{} {
+	change, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	_, hasNew := change["new_val"]
+	_, hasOld := change["old_val"]
+	changeType, _ := change["type"].(string)
+	if !hasNew && !hasOld && !changeTypes[changeType] {
+		return change
+	}
+
+	if !hasOld {
+		change["old_val"] = nil
+	}
+	if !hasNew {
+		change["new_val"] = nil
+	}
+
+	return change
+}
+
+// decodeResponse decodes the JSON of a response, or of a part of a response,
+// and converts the pseudotypes it contains as set by the run options opts.
+func decodeResponse(b []byte, opts map[string]interface{}, useNumber bool) (interface{}, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	if useNumber {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return recursivelyConvertPseudotype(value, opts)
+}
+
 // bufferResponse reads a single response and stores the result into the buffer
 // if the response is from an atomic response, it will check if the
 // response contains multiple records and store them all into the buffer
 func (c *Cursor) bufferNextResponse() error {
 	if c.closed {
-		return errCursorClosed
+		return ErrCursorClosed
 	}
 	// If there are no responses, nothing to do
 	if len(c.responses) == 0 {
@@ -673,19 +1155,13 @@ func (c *Cursor) bufferNextResponse() error {
 	response := c.responses[0]
 	c.responses = c.responses[1:]
 
-	var value interface{}
-	decoder := json.NewDecoder(bytes.NewBuffer(response))
-	if c.connOpts.UseJSONNumber {
-		decoder.UseNumber()
-	}
-	err := decoder.Decode(&value)
+	value, err := decodeResponse(response, c.opts, c.connOpts.UseJSONNumber)
 	if err != nil {
 		return err
 	}
 
-	value, err = recursivelyConvertPseudotype(value, c.opts)
-	if err != nil {
-		return err
+	if c.cursorType != "Cursor" {
+		value = normalizeChange(value)
 	}
 
 	// If response is an ATOM then try and convert to an array
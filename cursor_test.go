@@ -1,8 +1,21 @@
 package rethinkdb
 
 import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/context"
 	test "gopkg.in/check.v1"
 	"gopkg.in/rethinkdb/rethinkdb-go.v6/internal/integration/tests"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
 type CursorSuite struct{}
@@ -32,3 +45,644 @@ func (s *CursorSuite) TestCursor_One_Ok(c *test.C) {
 	c.Assert(response, tests.JsonEquals, data)
 	mock.AssertExpectations(c)
 }
+
+type streamedBlob struct {
+	Data []byte
+}
+
+func (b *streamedBlob) UnmarshalRQLStream(r io.Reader) error {
+	var err error
+	b.Data, err = ioutil.ReadAll(r)
+	return err
+}
+
+func (s *CursorSuite) TestCursor_NextStream(c *test.C) {
+	data := map[string]interface{}{
+		"id":   "a",
+		"blob": map[string]interface{}{"x": 1},
+	}
+
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return([]interface{}{data}, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var response struct {
+		ID   string       `rethinkdb:"id"`
+		Blob streamedBlob `rethinkdb:"blob"`
+	}
+	c.Assert(res.NextStream(&response), test.Equals, true)
+	c.Assert(res.Err(), test.IsNil)
+	c.Assert(response.ID, test.Equals, "a")
+	c.Assert(string(response.Blob.Data), test.Equals, `{"x":1}`)
+	c.Assert(res.NextStream(&response), test.Equals, false)
+	c.Assert(res.Err(), test.IsNil)
+	mock.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_NextPointer(c *test.C) {
+	type user struct {
+		Name string `rethinkdb:"name"`
+	}
+	type change struct {
+		OldVal *user `rethinkdb:"old_val"`
+		NewVal *user `rethinkdb:"new_val"`
+	}
+
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return([]interface{}{
+		map[string]interface{}{"old_val": nil, "new_val": map[string]interface{}{"name": "a"}},
+		map[string]interface{}{"old_val": map[string]interface{}{"name": "a"}, "new_val": nil},
+		nil,
+	}, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var ch change
+	c.Assert(res.Next(&ch), test.Equals, true)
+	c.Assert(ch.OldVal, test.IsNil)
+	c.Assert(ch.NewVal, test.DeepEquals, &user{Name: "a"})
+
+	c.Assert(res.Next(&ch), test.Equals, true)
+	c.Assert(ch.OldVal, test.DeepEquals, &user{Name: "a"})
+	c.Assert(ch.NewVal, test.IsNil)
+
+	// A null row sets a top-level pointer to nil
+	p := &user{Name: "b"}
+	c.Assert(res.Next(&p), test.Equals, true)
+	c.Assert(p, test.IsNil)
+	c.Assert(res.Err(), test.IsNil)
+}
+
+func (s *CursorSuite) TestCursor_DecodeTimesEqual(c *test.C) {
+	utc := map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1500000000.123, "timezone": "+00:00"}
+	local := map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1500000000.123, "timezone": "-07:00"}
+	missing := map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1500000000.123, "timezone": ""}
+
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return([]interface{}{
+		map[string]interface{}{"a": utc, "b": local, "c": missing},
+		map[string]interface{}{"a": utc, "b": local, "c": missing},
+	}, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var docs []struct {
+		A time.Time `rethinkdb:"a"`
+		B time.Time `rethinkdb:"b"`
+		C time.Time `rethinkdb:"c"`
+	}
+	c.Assert(res.All(&docs), test.IsNil)
+	c.Assert(docs, test.HasLen, 2)
+
+	want := time.Unix(1500000000, 123*int64(time.Millisecond)).UTC()
+	for _, doc := range docs {
+		// Zero and missing offsets are decoded as UTC
+		c.Assert(doc.A == want, test.Equals, true)
+		c.Assert(doc.A.Location(), test.Equals, time.UTC)
+		c.Assert(doc.C == want, test.Equals, true)
+		c.Assert(doc.A.Round(0) == doc.A, test.Equals, true)
+		c.Assert(doc.B.Equal(want), test.Equals, true)
+		c.Assert(doc.B.Format(time.RFC3339), test.Equals, "2017-07-13T19:40:00-07:00")
+	}
+	c.Assert(docs[0] == docs[1], test.Equals, true)
+}
+
+func (s *CursorSuite) TestCursor_LazyMap(c *test.C) {
+	data := map[string]interface{}{
+		"id":      "a",
+		"created": map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1500000000, "timezone": "+00:00"},
+		"blob":    []interface{}{1, 2, 3},
+	}
+
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return([]interface{}{data, data}, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	// Each document is read once using NextStream and once using Next
+	var doc LazyMap
+	for i := 0; i < 2; i++ {
+		if i == 0 {
+			c.Assert(res.NextStream(&doc), test.Equals, true)
+		} else {
+			c.Assert(res.Next(&doc), test.Equals, true)
+		}
+		c.Assert(doc.Keys(), test.DeepEquals, []string{"blob", "created", "id"})
+		c.Assert(doc.Has("id"), test.Equals, true)
+		c.Assert(doc.Has("name"), test.Equals, false)
+
+		var id string
+		c.Assert(doc.Get("id", &id), test.IsNil)
+		c.Assert(id, test.Equals, "a")
+
+		var created time.Time
+		c.Assert(doc.Get("created", &created), test.IsNil)
+		c.Assert(created.Unix(), test.Equals, int64(1500000000))
+
+		var name string
+		err = doc.Get("name", &name)
+		c.Assert(errors.Is(err, ErrNonExistence), test.Equals, true)
+	}
+	c.Assert(res.Next(&doc), test.Equals, false)
+	c.Assert(res.Err(), test.IsNil)
+}
+
+func (s *CursorSuite) TestCursor_LazyMapRunOpts(c *test.C) {
+	data := map[string]interface{}{
+		"doc": map[string]interface{}{
+			"created": map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1500000000, "timezone": "+00:00"},
+		},
+	}
+
+	mock := NewMock()
+	opts := map[string]interface{}{"time_format": "raw"}
+	mock.On(DB("test").Table("test"), opts).Return([]interface{}{data, data}, nil)
+	res, err := DB("test").Table("test").Run(mock, RunOpts{TimeFormat: "raw"})
+	c.Assert(err, test.IsNil)
+
+	// Fields of a LazyMap read using NextStream are decoded using the run
+	// options of the query, like when the document is read using Next
+	var dest struct {
+		Doc LazyMap `rethinkdb:"doc"`
+	}
+	for i := 0; i < 2; i++ {
+		if i == 0 {
+			c.Assert(res.NextStream(&dest), test.Equals, true)
+		} else {
+			c.Assert(res.Next(&dest), test.Equals, true)
+		}
+
+		var created map[string]interface{}
+		c.Assert(dest.Doc.Get("created", &created), test.IsNil)
+		c.Assert(created["$reql_type$"], test.Equals, "TIME")
+	}
+	c.Assert(res.Err(), test.IsNil)
+}
+
+func (s *CursorSuite) TestCursor_CloseIdempotent(c *test.C) {
+	mock := NewMock()
+	mock.On(DB("test").Table("test")).Return([]interface{}{1}, nil)
+	res, err := DB("test").Table("test").Run(mock)
+	c.Assert(err, test.IsNil)
+
+	c.Assert(res.Close(), test.IsNil)
+	c.Assert(res.Close(), test.IsNil)
+
+	var response interface{}
+	c.Assert(res.Next(&response), test.Equals, false)
+	c.Assert(res.Err(), test.IsNil)
+}
+
+func (s *CursorSuite) TestCursor_CloseWhileIterating(c *test.C) {
+	client, server := net.Pipe()
+	go serveCloseWhileFetching(server)
+
+	connection := newConnection(client, "addr", &ConnectOpts{})
+	done := runConnection(connection)
+	defer func() {
+		connection.Close()
+		<-done
+	}()
+
+	_, res, err := connection.Query(context.Background(), testQuery(DB("test").Table("test")))
+	c.Assert(err, test.IsNil)
+
+	var results []int
+	iterated := make(chan struct{})
+	go func() {
+		defer close(iterated)
+		var response int
+		for res.Next(&response) {
+			results = append(results, response)
+		}
+	}()
+
+	// Wait until the iterating goroutine is blocked fetching the next batch
+	for {
+		res.mu.Lock()
+		fetching := res.fetching && len(res.buffer) == 0 && len(res.responses) == 0
+		res.mu.Unlock()
+		if fetching {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The server only answers the pending fetch once the cursor has been
+	// closed, so the batch it returns is never read
+	closed := make(chan error, 2)
+	go func() {
+		closed <- res.Close()
+		closed <- res.Close()
+	}()
+
+	c.Assert(<-closed, test.IsNil)
+	c.Assert(<-closed, test.IsNil)
+	<-iterated
+	c.Assert(results, test.DeepEquals, []int{1})
+	c.Assert(res.Err(), test.Equals, ErrCursorClosed)
+}
+
+// serveCloseWhileFetching answers the START query with a partial batch and
+// holds the following CONTINUE query until the STOP query sent when the
+// cursor is closed is received.
+func serveCloseWhileFetching(conn net.Conn) {
+	header := make([]byte, respHeaderLen)
+	var pending int64
+	write := func(token int64, resp map[string]interface{}) error {
+		b, _ := json.Marshal(resp)
+		_, err := conn.Write(append(respHeader(token, b), b...))
+		return err
+	}
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		token := int64(binary.LittleEndian.Uint64(header))
+		body := make([]byte, binary.LittleEndian.Uint32(header[8:]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		var query []interface{}
+		_ = json.Unmarshal(body, &query)
+
+		var err error
+		switch p.Query_QueryType(query[0].(float64)) {
+		case p.Query_START:
+			err = write(token, map[string]interface{}{"t": p.Response_SUCCESS_PARTIAL, "r": []interface{}{1}})
+		case p.Query_CONTINUE:
+			pending = token
+		case p.Query_STOP:
+			err = write(pending, map[string]interface{}{"t": p.Response_SUCCESS_PARTIAL, "r": []interface{}{2}})
+			if err == nil {
+				err = write(token, map[string]interface{}{"t": p.Response_SUCCESS_SEQUENCE, "r": []interface{}{}})
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func newGroupedDataCursor(c *test.C, data []interface{}) *Cursor {
+	b, err := json.Marshal(map[string]interface{}{
+		"$reql_type$": "GROUPED_DATA",
+		"data":        data,
+	})
+	c.Assert(err, test.IsNil)
+
+	cursor := newCursor(context.Background(), nil, "", 1, nil, map[string]interface{}{})
+	cursor.extend(&Response{
+		Type:      p.Response_SUCCESS_ATOM,
+		Responses: []json.RawMessage{b},
+	})
+	return cursor
+}
+
+func (s *CursorSuite) TestCursor_All_GroupedReduction(c *test.C) {
+	// Response of Group("category").Map(...).Reduce(...)
+	res := newGroupedDataCursor(c, []interface{}{
+		[]interface{}{"books", 12.5},
+		[]interface{}{"games", 30},
+	})
+
+	var response []struct {
+		Group     string  `rethinkdb:"group"`
+		Reduction float64 `rethinkdb:"reduction"`
+	}
+	err := res.All(&response)
+	c.Assert(err, test.IsNil)
+	c.Assert(response, test.HasLen, 2)
+	c.Assert(response[0].Group, test.Equals, "books")
+	c.Assert(response[0].Reduction, test.Equals, 12.5)
+	c.Assert(response[1].Group, test.Equals, "games")
+	c.Assert(response[1].Reduction, test.Equals, float64(30))
+}
+
+func (s *CursorSuite) TestCursor_All_GroupedMultipleFields(c *test.C) {
+	// Response of Group("category", "year").Count()
+	res := newGroupedDataCursor(c, []interface{}{
+		[]interface{}{[]interface{}{"books", 2019}, 3},
+	})
+
+	var response []struct {
+		Group     []interface{} `rethinkdb:"group"`
+		Reduction int           `rethinkdb:"reduction"`
+	}
+	err := res.All(&response)
+	c.Assert(err, test.IsNil)
+	c.Assert(response, test.HasLen, 1)
+	c.Assert(response[0].Group, test.DeepEquals, []interface{}{"books", float64(2019)})
+	c.Assert(response[0].Reduction, test.Equals, 3)
+}
+
+func groupedData(data ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"$reql_type$": "GROUPED_DATA",
+		"data":        data,
+	}
+}
+
+func (s *CursorSuite) TestCursor_All_NestedGroupedData(c *test.C) {
+	twoLevels := []interface{}{
+		[]interface{}{"books", groupedData(
+			[]interface{}{"2019", 3},
+			[]interface{}{"2020", 5},
+		)},
+		[]interface{}{"games", groupedData(
+			[]interface{}{"2020", 1},
+		)},
+	}
+	want := map[string]map[string]int{
+		"books": {"2019": 3, "2020": 5},
+		"games": {"2020": 1},
+	}
+
+	var counts map[string]map[string]int
+	err := newGroupedDataCursor(c, twoLevels).All(&counts)
+	c.Assert(err, test.IsNil)
+	c.Assert(counts, test.DeepEquals, want)
+
+	// The "map" group format decodes the same way
+	res := newGroupedDataCursor(c, twoLevels)
+	res.opts = map[string]interface{}{"group_format": "map"}
+	counts = nil
+	err = res.All(&counts)
+	c.Assert(err, test.IsNil)
+	c.Assert(counts, test.DeepEquals, want)
+
+	var totals map[string]map[string]map[float64]float64
+	err = newGroupedDataCursor(c, []interface{}{
+		[]interface{}{"books", groupedData(
+			[]interface{}{"2019", groupedData(
+				[]interface{}{1, 12.5},
+				[]interface{}{2, 7},
+			)},
+		)},
+	}).All(&totals)
+	c.Assert(err, test.IsNil)
+	c.Assert(totals, test.DeepEquals, map[string]map[string]map[float64]float64{
+		"books": {"2019": {1: 12.5, 2: 7}},
+	})
+
+	// Single level grouped data with object reductions
+	var objects map[string]map[string]int
+	err = newGroupedDataCursor(c, []interface{}{
+		[]interface{}{"books", map[string]interface{}{"min": 1, "max": 9}},
+	}).All(&objects)
+	c.Assert(err, test.IsNil)
+	c.Assert(objects, test.DeepEquals, map[string]map[string]int{"books": {"min": 1, "max": 9}})
+}
+
+func (s *CursorSuite) TestCursor_All_NestedGroupedDataMismatch(c *test.C) {
+	var tooShallow map[string]int
+	err := newGroupedDataCursor(c, []interface{}{
+		[]interface{}{"books", groupedData([]interface{}{"2019", 3})},
+	}).All(&tooShallow)
+	c.Assert(err, test.ErrorMatches, `cannot decode the reduction of group books into int, the grouped data is nested more deeply than map\[string\]int`)
+
+	var tooDeep map[string]map[string]map[string]int
+	err = newGroupedDataCursor(c, []interface{}{
+		[]interface{}{"books", groupedData([]interface{}{"2019", 3})},
+	}).All(&tooDeep)
+	c.Assert(err, test.ErrorMatches, `cannot decode the reduction of group 2019 into map\[string\]int, the grouped data is nested less deeply than map\[string\]map\[string\]int`)
+
+	cursor := newCursor(context.Background(), nil, "", 1, nil, map[string]interface{}{})
+	cursor.extend(&Response{
+		Type:      p.Response_SUCCESS_SEQUENCE,
+		Responses: []json.RawMessage{json.RawMessage(`{"id":1}`)},
+	})
+	var notGrouped map[string]int
+	err = cursor.All(&notGrouped)
+	c.Assert(err, test.ErrorMatches, `cannot decode \[\]interface \{\} into map\[string\]int, expected grouped data`)
+}
+
+func (s *CursorSuite) TestCursor_AllDistinct(c *test.C) {
+	cursor := newCursor(context.Background(), nil, "", 1, nil, map[string]interface{}{})
+	cursor.extend(&Response{
+		Type: p.Response_SUCCESS_SEQUENCE,
+		Responses: []json.RawMessage{
+			json.RawMessage(`{"name":"a","tags":["x"]}`),
+			json.RawMessage(`{"tags":["x"],"name":"a"}`),
+			json.RawMessage(`{"name":"b","tags":["x"]}`),
+			json.RawMessage(`{"name":"a","tags":["y"]}`),
+			json.RawMessage(`{"name":"b","tags":["x"]}`),
+		},
+	})
+
+	type doc struct {
+		Name string   `rethinkdb:"name"`
+		Tags []string `rethinkdb:"tags"`
+	}
+	docs := []doc{{Name: "stale"}}
+	c.Assert(cursor.AllDistinct(&docs), test.IsNil)
+	c.Assert(docs, test.DeepEquals, []doc{
+		{Name: "a", Tags: []string{"x"}},
+		{Name: "b", Tags: []string{"x"}},
+		{Name: "a", Tags: []string{"y"}},
+	})
+	c.Assert(cursor.closed, test.Equals, true)
+}
+
+func (s *CursorSuite) TestCursor_Next_FeedNormalizesChanges(c *test.C) {
+	cursor := newCursor(context.Background(), nil, "Feed", 1, nil, map[string]interface{}{})
+	cursor.extend(&Response{
+		Type: p.Response_SUCCESS_SEQUENCE,
+		Responses: []json.RawMessage{
+			[]byte(`{"new_val": {"id": 1}}`),
+			[]byte(`{"state": "ready"}`),
+			[]byte(`{"type": "state", "state": "ready"}`),
+			[]byte(`{"type": "initial", "new_val": {"id": 2}}`),
+			[]byte(`{"id": 3, "name": "a"}`),
+			[]byte(`{"new_val": {"id": 1, "n": 2}, "old_val": {"id": 1}}`),
+		},
+	})
+
+	var changes []map[string]interface{}
+	err := cursor.All(&changes)
+	c.Assert(err, test.IsNil)
+	c.Assert(changes, tests.JsonEquals, []interface{}{
+		map[string]interface{}{"new_val": map[string]interface{}{"id": 1}, "old_val": nil},
+		map[string]interface{}{"state": "ready"},
+		map[string]interface{}{"type": "state", "state": "ready"},
+		map[string]interface{}{"type": "initial", "new_val": map[string]interface{}{"id": 2}, "old_val": nil},
+		map[string]interface{}{"id": 3, "name": "a"},
+		map[string]interface{}{"new_val": map[string]interface{}{"id": 1, "n": 2}, "old_val": map[string]interface{}{"id": 1}},
+	})
+}
+
+func (s *CursorSuite) TestCursor_Next_NonFeedNotNormalized(c *test.C) {
+	cursor := newCursor(context.Background(), nil, "Cursor", 1, nil, map[string]interface{}{})
+	cursor.extend(&Response{
+		Type:      p.Response_SUCCESS_SEQUENCE,
+		Responses: []json.RawMessage{[]byte(`{"new_val": 1}`)},
+	})
+
+	var doc map[string]interface{}
+	c.Assert(cursor.Next(&doc), test.Equals, true)
+	_, ok := doc["old_val"]
+	c.Assert(ok, test.Equals, false)
+}
+
+func (s *CursorSuite) TestCursor_Database(c *test.C) {
+	db, _ := DB("test").Build()
+	cursor := newCursor(context.Background(), nil, "Cursor", 1, nil, map[string]interface{}{"db": db})
+	c.Assert(cursor.Database(), test.Equals, "test")
+
+	cursor = newCursor(context.Background(), nil, "Cursor", 1, nil, map[string]interface{}{})
+	c.Assert(cursor.Database(), test.Equals, "")
+}
+
+func (s *CursorSuite) TestCursor_Prefetch(c *test.C) {
+	ctx := context.Background()
+	token := int64(1)
+	q := testQuery(Table("table"))
+	q.prefetch = true
+	startData := serializeQuery(token, q)
+	continueData := serializeQuery(token, Query{Type: p.Query_CONTINUE, Token: token})
+	partialData, _ := json.Marshal(map[string]interface{}{"t": p.Response_SUCCESS_PARTIAL, "r": []interface{}{1, 2}})
+	sequenceData, _ := json.Marshal(map[string]interface{}{"t": p.Response_SUCCESS_SEQUENCE, "r": []interface{}{3}})
+
+	continueSent := make(chan struct{})
+	conn := &connMock{}
+	conn.On("Write", startData).Return(len(startData), nil, nil)
+	conn.On("Write", continueData).Return(len(continueData), nil, nil).Once().Run(func(args mock.Arguments) {
+		close(continueSent)
+	})
+	conn.On("Read", respHeaderLen).Return(respHeader(token, partialData), respHeaderLen, nil, nil).Once()
+	conn.On("Read", len(partialData)).Return(partialData, len(partialData), nil, nil).Once()
+	conn.On("Read", respHeaderLen).Return(respHeader(token, sequenceData), respHeaderLen, nil, nil).Once().Run(func(args mock.Arguments) {
+		<-continueSent
+	})
+	conn.On("Read", len(sequenceData)).Return(sequenceData, len(sequenceData), nil, nil).Once()
+	conn.onCloseReturn(nil)
+
+	connection := newConnection(conn, "addr", &ConnectOpts{})
+	done := runConnection(connection)
+	_, cursor, err := connection.Query(ctx, q)
+	c.Assert(err, test.IsNil)
+
+	var row int
+	c.Assert(cursor.Next(&row), test.Equals, true)
+	c.Assert(row, test.Equals, 1)
+
+	// The next batch is requested while the first is still being read
+	select {
+	case <-continueSent:
+	case <-time.After(time.Second):
+		c.Fatal("next batch was not prefetched")
+	}
+
+	var rows []int
+	for cursor.Next(&row) {
+		rows = append(rows, row)
+	}
+	c.Assert(cursor.Err(), test.IsNil)
+	c.Assert(rows, test.DeepEquals, []int{2, 3})
+
+	connection.Close()
+	<-done
+	conn.AssertExpectations(c)
+}
+
+func (s *CursorSuite) TestCursor_PrefetchFeed(c *test.C) {
+	cursor := newCursor(context.Background(), &Connection{}, "Feed", 1, nil, nil)
+	cursor.prefetch = true
+	cursor.extend(&Response{Type: p.Response_SUCCESS_PARTIAL, Responses: []json.RawMessage{[]byte(`{"new_val":1}`)}})
+
+	cursor.mu.Lock()
+	cursor.maybePrefetch()
+	c.Assert(cursor.prefetchDone, test.IsNil)
+	cursor.mu.Unlock()
+}
+
+func (s *CursorSuite) TestCursor_Notes(c *test.C) {
+	cursor := newCursor(context.Background(), nil, "Feed", 1, nil, map[string]interface{}{})
+	c.Assert(cursor.Notes(), test.Equals, ResponseNotes{})
+
+	cursor.extend(&Response{
+		Type:  p.Response_SUCCESS_PARTIAL,
+		Notes: []p.Response_ResponseNote{p.Response_SEQUENCE_FEED, p.Response_INCLUDES_STATES},
+	})
+	cursor.extend(&Response{Type: p.Response_SUCCESS_PARTIAL})
+	c.Assert(cursor.Notes(), test.Equals, ResponseNotes{SequenceFeed: true, IncludesStates: true})
+
+	var nilCursor *Cursor
+	c.Assert(nilCursor.Notes(), test.Equals, ResponseNotes{})
+}
+
+func (s *CursorSuite) TestCursor_Host(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Get(1)).Return(map[string]interface{}{"id": 1}, nil)
+
+	cursor, err := Table("test").Get(1).Run(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(cursor.Host(), test.Equals, "mock")
+	c.Assert(cursor.Close(), test.IsNil)
+	// the host is still available once the cursor is closed
+	c.Assert(cursor.Host(), test.Equals, "mock")
+
+	var nilCursor *Cursor
+	c.Assert(nilCursor.Host(), test.Equals, "")
+}
+
+// newRowsCursor returns a finished cursor containing n documents.
+func newRowsCursor(n int) *Cursor {
+	rows := make([]json.RawMessage, n)
+	for i := range rows {
+		rows[i] = json.RawMessage(`{"id": ` + strconv.Itoa(i) + `, "name": "user"}`)
+	}
+
+	cursor := newCursor(context.Background(), nil, "", 1, nil, map[string]interface{}{})
+	cursor.extend(&Response{Type: p.Response_SUCCESS_SEQUENCE, Responses: rows})
+	return cursor
+}
+
+type allRow struct {
+	ID   int    `rethinkdb:"id"`
+	Name string `rethinkdb:"name"`
+}
+
+func (s *CursorSuite) TestCursor_All_ReusesCapacity(c *test.C) {
+	rows := make([]allRow, 0, 3)
+	backing := rows[:1]
+	c.Assert(newRowsCursor(3).All(&rows), test.IsNil)
+	c.Assert(rows, test.HasLen, 3)
+	c.Assert(&rows[0], test.Equals, &backing[0])
+	c.Assert(rows[2], test.Equals, allRow{ID: 2, Name: "user"})
+
+	// Only grows when the results do not fit
+	c.Assert(newRowsCursor(5).All(&rows), test.IsNil)
+	c.Assert(rows, test.HasLen, 5)
+	c.Assert(rows[4], test.Equals, allRow{ID: 4, Name: "user"})
+
+	c.Assert(newRowsCursor(1).All(&rows), test.IsNil)
+	c.Assert(rows, test.DeepEquals, []allRow{{ID: 0, Name: "user"}})
+}
+
+func benchmarkCursorAll(b *testing.B, prealloc bool) {
+	const n = 10000
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		cursor := newRowsCursor(n)
+		var rows []allRow
+		if prealloc {
+			rows = make([]allRow, 0, n)
+		}
+		b.StartTimer()
+
+		if err := cursor.All(&rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCursor_All(b *testing.B) {
+	benchmarkCursorAll(b, false)
+}
+
+func BenchmarkCursor_All_Preallocated(b *testing.B) {
+	benchmarkCursorAll(b, true)
+}
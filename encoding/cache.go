@@ -24,6 +24,11 @@ type field struct {
 	refName       string
 	compound      bool
 	compoundIndex int
+	extra         bool
+	unixTime      time.Duration // unit of numeric times, zero if not enabled
+	timeFormat    string        // "raw" or "native", empty to use the query setting
+	timeLayout    string        // layout of string times, empty if not enabled
+	nilAsEmpty    bool          // encode nil slices and maps as empty values
 }
 
 func fillField(f field) field {
@@ -151,6 +156,12 @@ func typeFields(t reflect.Type) []field {
 						refName:       ref,
 						compound:      isCompound,
 						compoundIndex: compoundIndex,
+						extra:         opts.Contains("extra") && isExtraFieldType(sf.Type),
+						quoted:        opts.Contains("string") && isQuotableType(ft),
+						unixTime:      unixTimeUnit(opts, ft),
+						timeFormat:    timeFormat(opts),
+						timeLayout:    timeLayout(opts, ft),
+						nilAsEmpty:    opts.Contains("nilasempty") && isNilAsEmptyType(sf.Type),
 					}))
 					if count[f.typ] > 1 {
 						// If there were multiple instances, add a second,
@@ -210,6 +221,82 @@ func typeFields(t reflect.Type) []field {
 	return fields
 }
 
+// isExtraFieldType returns true if t can be used to hold the extra fields of a
+// struct, only maps with string keys are supported.
+func isExtraFieldType(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String
+}
+
+// isQuotableType returns true if fields of type t can use the "string" tag
+// option to be encoded as a decimal string.
+func isQuotableType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNilAsEmptyType returns true if fields of type t can use the "nilasempty"
+// option. Byte slices are excluded as they are encoded as binary values, as
+// are types implementing Marshaler.
+func isNilAsEmptyType(t reflect.Type) bool {
+	if t.Implements(marshalerType) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
+}
+
+// timeFormat returns the value of the "timeformat" option, which overrides
+// the time format used by the query for a single field. Unknown formats are
+// ignored.
+func timeFormat(opts tagOptions) string {
+	switch format := opts.Value("timeformat"); format {
+	case "raw", "native":
+		return format
+	default:
+		return ""
+	}
+}
+
+// unixTimeUnit returns the unit used to decode numbers into fields tagged with
+// the "unix" or "unixmilli" options, zero is returned if the field is not a
+// time.Time or is not tagged.
+func unixTimeUnit(opts tagOptions, t reflect.Type) time.Duration {
+	if t != timeType {
+		return 0
+	}
+	if opts.Contains("unixmilli") {
+		return time.Millisecond
+	}
+	if opts.Contains("unix") {
+		return time.Second
+	}
+
+	return 0
+}
+
+// timeLayout returns the value of the "timelayout" option, the layout used to
+// parse strings decoded into time.Time fields. An empty string is returned if
+// the field is not a time.Time or is not tagged.
+func timeLayout(opts tagOptions, t reflect.Type) string {
+	if t != timeType {
+		return ""
+	}
+
+	return opts.Value("timelayout")
+}
+
 func isPseudoType(t reflect.Type) bool {
 	return t == reflect.TypeOf(time.Time{})
 }
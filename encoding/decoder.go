@@ -13,10 +13,23 @@ type decoderFunc func(dv reflect.Value, sv reflect.Value) error
 
 // Decode decodes map[string]interface{} into a struct. The first parameter
 // must be a pointer.
+//
+// The destination is reset to its zero value before decoding, so fields
+// missing from src are left zeroed. When decoding into a slice an empty
+// array results in an empty non-nil slice while null results in a nil slice.
+// Similarly when dst points to a pointer, such as a *T, the pointer is
+// allocated if src is not null and set to nil if src is null.
+//
+// Numbers and strings can be decoded into bool values, any non-zero number is
+// true and strings are parsed using strconv.ParseBool, with the empty string
+// being false. Other strings, such as "yes", return an error.
 func Decode(dst interface{}, src interface{}) (err error) {
 	return decode(dst, src, true)
 }
 
+// Merge decodes src into dst like Decode but without first resetting the
+// destination, fields missing from src keep their existing value. An empty
+// array still results in an empty non-nil slice and null in a nil slice.
 func Merge(dst interface{}, src interface{}) (err error) {
 	return decode(dst, src, false)
 }
@@ -59,7 +72,8 @@ func decode(dst interface{}, src interface{}, blank bool) (err error) {
 
 // decodeValue decodes the source value into the destination value
 func decodeValue(dv, sv reflect.Value, blank bool) error {
-	return valueDecoder(dv, sv, blank)(dv, sv)
+	dec, dv := valueDecoder(dv, sv, blank)
+	return dec(dv, sv)
 }
 
 type decoderCacheKey struct {
@@ -71,9 +85,13 @@ var decoderCache struct {
 	m map[decoderCacheKey]decoderFunc
 }
 
-func valueDecoder(dv, sv reflect.Value, blank bool) decoderFunc {
+// valueDecoder returns the decoder for the source value along with the value
+// it decodes into. Pointers in the destination are allocated as needed unless
+// the source is null, in which case the destination itself is set to its zero
+// value, for example a nil pointer.
+func valueDecoder(dv, sv reflect.Value, blank bool) (decoderFunc, reflect.Value) {
 	if !sv.IsValid() {
-		return invalidValueDecoder
+		return invalidValueDecoder, dv
 	}
 
 	if dv.IsValid() {
@@ -86,7 +104,7 @@ func valueDecoder(dv, sv reflect.Value, blank bool) decoderFunc {
 		}
 	}
 
-	return typeDecoder(dv.Type(), sv.Type(), blank)
+	return typeDecoder(dv.Type(), sv.Type(), blank), dv
 }
 
 func typeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
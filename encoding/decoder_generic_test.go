@@ -0,0 +1,83 @@
+//go:build go1.18
+// +build go1.18
+
+package encoding
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Optional is a generic type which implements Unmarshaler, it is used to
+// check that type parameters do not affect how types are decoded.
+type Optional[T any] struct {
+	Value T
+	Valid bool
+}
+
+func (o *Optional[T]) UnmarshalRQL(data interface{}) error {
+	if data == nil {
+		*o = Optional[T]{}
+		return nil
+	}
+
+	var v T
+	if err := Decode(&v, data); err != nil {
+		return err
+	}
+	*o = Optional[T]{Value: v, Valid: true}
+	return nil
+}
+
+type genericDoc struct {
+	Name  Optional[string]            `rethinkdb:"name"`
+	Age   Optional[int]               `rethinkdb:"age"`
+	Tags  Optional[[]string]          `rethinkdb:"tags"`
+	Attrs map[string]Optional[string] `rethinkdb:"attrs"`
+}
+
+func TestDecodeGenericUnmarshaler(t *testing.T) {
+	var doc genericDoc
+	err := Decode(&doc, map[string]interface{}{
+		"name":  "Alice",
+		"age":   nil,
+		"tags":  []interface{}{"a", "b"},
+		"attrs": map[string]interface{}{"x": "1", "y": nil},
+	})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	want := genericDoc{
+		Name: Optional[string]{Value: "Alice", Valid: true},
+		Tags: Optional[[]string]{Value: []string{"a", "b"}, Valid: true},
+		Attrs: map[string]Optional[string]{
+			"x": {Value: "1", Valid: true},
+			"y": {},
+		},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("got %+v, want %+v", doc, want)
+	}
+
+	var opts []Optional[float64]
+	if err := Decode(&opts, []interface{}{1.5, nil}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := []Optional[float64]{{Value: 1.5, Valid: true}, {}}; !reflect.DeepEqual(opts, want) {
+		t.Errorf("got %+v, want %+v", opts, want)
+	}
+
+	var top Optional[string]
+	if err := Decode(&top, "hello"); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := (Optional[string]{Value: "hello", Valid: true}); top != want {
+		t.Errorf("got %+v, want %+v", top, want)
+	}
+
+	var invalid Optional[int]
+	if err := Decode(&invalid, "hello"); err == nil {
+		t.Errorf("expected an error decoding a string into Optional[int]")
+	}
+}
@@ -2,11 +2,14 @@ package encoding
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"image"
+	"math"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type T struct {
@@ -187,8 +190,8 @@ var decodeTests = []decodeTest{
 
 	// array tests
 	{in: []interface{}{1, 2, 3}, ptr: new([3]int), out: [3]int{1, 2, 3}},
-	{in: []interface{}{1, 2, 3}, ptr: new([1]int), out: [1]int{1}},
-	{in: []interface{}{1, 2, 3}, ptr: new([5]int), out: [5]int{1, 2, 3, 0, 0}},
+	{in: []interface{}{1, 2, 3}, ptr: new([1]int), err: &DecodeTypeError{reflect.TypeOf([1]int{}), reflect.TypeOf([]interface{}{}), "array has 3 elements, expected 1"}},
+	{in: []interface{}{1, 2, 3}, ptr: new([5]int), err: &DecodeTypeError{reflect.TypeOf([5]int{}), reflect.TypeOf([]interface{}{}), "array has 3 elements, expected 5"}},
 
 	// empty array to interface test
 	{in: map[string]interface{}{"T": []interface{}{}}, ptr: new(map[string]interface{}), out: map[string]interface{}{"T": []interface{}{}}},
@@ -669,3 +672,687 @@ func TestDecodeCustomTypeEncodingError(t *testing.T) {
 		t.Errorf("got %v, want %v", err, cerr)
 	}
 }
+
+type ExtraFields struct {
+	ID    string                 `rethinkdb:"id"`
+	Count int                    `rethinkdb:"count"`
+	Extra map[string]interface{} `rethinkdb:",extra"`
+}
+
+func TestDecodeExtraFields(t *testing.T) {
+	in := map[string]interface{}{
+		"id":    "a",
+		"count": 2,
+		"name":  "test",
+		"tags":  []interface{}{"x", "y"},
+	}
+	want := ExtraFields{
+		ID:    "a",
+		Count: 2,
+		Extra: map[string]interface{}{
+			"name": "test",
+			"tags": []interface{}{"x", "y"},
+		},
+	}
+
+	var out ExtraFields
+	err := Decode(&out, in)
+	if err != nil {
+		t.Errorf("got error %v, expected nil", err)
+	}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestDecodeExtraFieldsTyped(t *testing.T) {
+	type typedExtra struct {
+		ID    string         `rethinkdb:"id"`
+		Extra map[string]int `rethinkdb:",extra"`
+	}
+
+	in := map[string]interface{}{
+		"id":    "a",
+		"Extra": 1,
+		"b":     float64(2),
+	}
+	want := typedExtra{ID: "a", Extra: map[string]int{"Extra": 1, "b": 2}}
+
+	var out typedExtra
+	err := Decode(&out, in)
+	if err != nil {
+		t.Errorf("got error %v, expected nil", err)
+	}
+	if !jsonEqual(out, want) {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestDecodeJSONNumberInt64(t *testing.T) {
+	var got int64
+	if err := Decode(&got, json.Number("9223372036854775807")); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != math.MaxInt64 {
+		t.Errorf("got %v, want %v", got, int64(math.MaxInt64))
+	}
+}
+
+func TestDecodeUnixTime(t *testing.T) {
+	type legacy struct {
+		Created time.Time  `rethinkdb:"created,unix"`
+		Updated *time.Time `rethinkdb:"updated,unixmilli"`
+		Deleted time.Time  `rethinkdb:"deleted,unix"`
+	}
+
+	deleted := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	input := map[string]interface{}{
+		"created": float64(1500000000),
+		"updated": json.Number("1500000000250"),
+		"deleted": deleted,
+	}
+
+	var got legacy
+	if err := Decode(&got, input); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := time.Unix(1500000000, 0); !got.Created.Equal(want) {
+		t.Errorf("got %v, want %v", got.Created, want)
+	}
+	if want := time.Unix(1500000000, 250*int64(time.Millisecond)); got.Updated == nil || !got.Updated.Equal(want) {
+		t.Errorf("got %v, want %v", got.Updated, want)
+	}
+	if !got.Deleted.Equal(deleted) {
+		t.Errorf("got %v, want %v", got.Deleted, deleted)
+	}
+}
+
+func TestDecodeUnixTimeWithoutTag(t *testing.T) {
+	var got struct {
+		Created time.Time `rethinkdb:"created"`
+	}
+	err := Decode(&got, map[string]interface{}{"created": float64(1500000000)})
+	if err == nil {
+		t.Errorf("expected error decoding number into untagged time.Time")
+	}
+}
+
+func TestDecodeFixedSizeArray(t *testing.T) {
+	type color struct {
+		RGB   [3]float64  `rethinkdb:"rgb"`
+		Point *[2]float64 `rethinkdb:"point"`
+	}
+
+	input := map[string]interface{}{
+		"rgb":   []interface{}{0.5, 0.25, 1.0},
+		"point": []interface{}{1.5, -2.0},
+	}
+	var got color
+	if err := Decode(&got, input); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	want := color{RGB: [3]float64{0.5, 0.25, 1}, Point: &[2]float64{1.5, -2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	encoded, err := Encode(want)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !reflect.DeepEqual(encoded, input) {
+		t.Errorf("got %v, want %v", encoded, input)
+	}
+
+	err = Decode(&got, map[string]interface{}{"rgb": []interface{}{0.5, 0.25}})
+	if _, ok := err.(*DecodeTypeError); !ok {
+		t.Errorf("got error %v, want *DecodeTypeError", err)
+	}
+	err = Decode(&got, map[string]interface{}{"point": []interface{}{1.0, 2.0, 3.0}})
+	if _, ok := err.(*DecodeTypeError); !ok {
+		t.Errorf("got error %v, want *DecodeTypeError", err)
+	}
+}
+
+func TestDecodeTimeLayout(t *testing.T) {
+	type legacy struct {
+		Created time.Time  `rethinkdb:"created,timelayout=2006-01-02"`
+		Updated *time.Time `rethinkdb:"updated,timelayout=02/01/2006 15:04 -0700"`
+		Deleted time.Time  `rethinkdb:"deleted,timelayout=2006-01-02"`
+	}
+
+	deleted := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	input := map[string]interface{}{
+		"created": "2019-06-30",
+		"updated": "01/07/2019 12:30 +0200",
+		"deleted": deleted,
+	}
+
+	var got legacy
+	if err := Decode(&got, input); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := time.Date(2019, 6, 30, 0, 0, 0, 0, time.UTC); !got.Created.Equal(want) || got.Created.Location() != time.UTC {
+		t.Errorf("got %v, want %v", got.Created, want)
+	}
+	if want := time.Date(2019, 7, 1, 10, 30, 0, 0, time.UTC); got.Updated == nil || !got.Updated.Equal(want) {
+		t.Errorf("got %v, want %v", got.Updated, want)
+	}
+	if !got.Deleted.Equal(deleted) {
+		t.Errorf("got %v, want %v", got.Deleted, deleted)
+	}
+
+	err := Decode(&got, map[string]interface{}{"created": "30/06/2019"})
+	if _, ok := err.(*DecodeTypeError); !ok {
+		t.Errorf("got error %v, want *DecodeTypeError", err)
+	}
+}
+
+func benchmarkDecodeSliceInput(n int) []interface{} {
+	input := make([]interface{}, n)
+	for i := range input {
+		input[i] = float64(i)
+	}
+	return input
+}
+
+func BenchmarkDecodeIntSlice(b *testing.B) {
+	input := benchmarkDecodeSliceInput(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out []int
+		if err := Decode(&out, input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeNestedFloatSlice(b *testing.B) {
+	input := make([]interface{}, 100)
+	for i := range input {
+		input[i] = benchmarkDecodeSliceInput(100)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out [][]float64
+		if err := Decode(&out, input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecodeScalarSlices(t *testing.T) {
+	var ints [][]int
+	if err := Decode(&ints, []interface{}{[]interface{}{1.0, 2.0}, []interface{}{3.0}}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := [][]int{{1, 2}, {3}}; !reflect.DeepEqual(ints, want) {
+		t.Errorf("got %v, want %v", ints, want)
+	}
+
+	var strs []string
+	if err := Decode(&strs, []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(strs, want) {
+		t.Errorf("got %v, want %v", strs, want)
+	}
+
+	// Elements which are not float64 values use the general decoder
+	var mixed []int64
+	if err := Decode(&mixed, []interface{}{1.0, json.Number("9223372036854775807"), "3"}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := []int64{1, math.MaxInt64, 3}; !reflect.DeepEqual(mixed, want) {
+		t.Errorf("got %v, want %v", mixed, want)
+	}
+}
+
+func TestDecodeScalarSliceTypeEncoding(t *testing.T) {
+	type level int
+
+	SetTypeEncoding(reflect.TypeOf(level(0)),
+		nil, func(enc interface{}, val reflect.Value) error {
+			val.SetInt(int64(enc.(float64)) * 10)
+			return nil
+		})
+
+	var levels []level
+	if err := Decode(&levels, []interface{}{1.0, 2.0}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := []level{10, 20}; !reflect.DeepEqual(levels, want) {
+		t.Errorf("got %v, want %v", levels, want)
+	}
+}
+
+type sqlNulls struct {
+	String  sql.NullString  `rethinkdb:"string"`
+	Int64   sql.NullInt64   `rethinkdb:"int64"`
+	Int32   sql.NullInt32   `rethinkdb:"int32"`
+	Float64 sql.NullFloat64 `rethinkdb:"float64"`
+	Bool    sql.NullBool    `rethinkdb:"bool"`
+	Time    sql.NullTime    `rethinkdb:"time"`
+}
+
+func TestDecodeSQLNullTypes(t *testing.T) {
+	now := time.Unix(1500000000, 0).UTC()
+
+	var got sqlNulls
+	err := Decode(&got, map[string]interface{}{
+		"string":  "bob",
+		"int64":   float64(12),
+		"int32":   float64(3),
+		"float64": 1.5,
+		"bool":    false,
+		"time":    now,
+	})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	want := sqlNulls{
+		String:  sql.NullString{String: "bob", Valid: true},
+		Int64:   sql.NullInt64{Int64: 12, Valid: true},
+		Int32:   sql.NullInt32{Int32: 3, Valid: true},
+		Float64: sql.NullFloat64{Float64: 1.5, Valid: true},
+		Bool:    sql.NullBool{Bool: false, Valid: true},
+		Time:    sql.NullTime{Time: now, Valid: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Null values reset previously decoded values, even when merging
+	err = Merge(&got, map[string]interface{}{
+		"string":  nil,
+		"int64":   nil,
+		"int32":   nil,
+		"float64": nil,
+		"bool":    nil,
+		"time":    nil,
+	})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, sqlNulls{}) {
+		t.Errorf("got %+v, want %+v", got, sqlNulls{})
+	}
+}
+
+func TestDecodeSQLNullString(t *testing.T) {
+	var got sql.NullString
+	if err := Decode(&got, "bob"); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := (sql.NullString{String: "bob", Valid: true}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if err := Decode(&got, nil); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.Valid {
+		t.Errorf("got %+v, want %+v", got, sql.NullString{})
+	}
+
+	// Documents written before sql.Null* types were supported
+	if err := Decode(&got, map[string]interface{}{"String": "bob", "Valid": true}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := (sql.NullString{String: "bob", Valid: true}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeTimeFormat(t *testing.T) {
+	type doc struct {
+		Raw     interface{}            `rethinkdb:"raw,timeformat=raw"`
+		RawMap  map[string]interface{} `rethinkdb:"raw_map,timeformat=raw"`
+		Native  time.Time              `rethinkdb:"native,timeformat=native"`
+		Default interface{}            `rethinkdb:"default"`
+	}
+
+	zone := time.FixedZone("+02:00", 2*60*60)
+	ts := time.Unix(1500000000, int64(250*time.Millisecond)).In(zone)
+	raw := map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1500000000.25, "timezone": "+02:00"}
+
+	var got doc
+	err := Decode(&got, map[string]interface{}{
+		"raw":     ts,
+		"raw_map": ts,
+		"native":  map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1500000000.25, "timezone": "+02:00"},
+		"default": ts,
+	})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if !reflect.DeepEqual(got.Raw, raw) {
+		t.Errorf("got %v, want %v", got.Raw, raw)
+	}
+	if !reflect.DeepEqual(got.RawMap, raw) {
+		t.Errorf("got %v, want %v", got.RawMap, raw)
+	}
+	if !got.Native.Equal(ts) || got.Native.Location().String() != "+02:00" {
+		t.Errorf("got %v, want %v", got.Native, ts)
+	}
+	if got.Default != ts {
+		t.Errorf("got %v, want %v", got.Default, ts)
+	}
+}
+
+func TestDecodeTimeFormatEqual(t *testing.T) {
+	type doc struct {
+		Time time.Time `rethinkdb:"time,timeformat=native"`
+	}
+
+	decode := func(tz string) time.Time {
+		var got doc
+		err := Decode(&got, map[string]interface{}{
+			"time": map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1500000000.25, "timezone": tz},
+		})
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		return got.Time
+	}
+
+	// Equal times decode to identical values without a monotonic reading
+	if a, b := decode("+02:00"), decode("+02:00"); a != b || a.Round(0) != a {
+		t.Errorf("got %v and %v, want identical times", a, b)
+	}
+	want := time.Unix(1500000000, int64(250*time.Millisecond)).UTC()
+	if got := decode("+00:00"); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := decode(""); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+type animal interface {
+	Sound() string
+}
+
+type resolvedCat struct {
+	Type  string `rethinkdb:"type"`
+	Lives int    `rethinkdb:"lives"`
+}
+
+func (resolvedCat) Sound() string { return "meow" }
+
+type resolvedDog struct {
+	Type string `rethinkdb:"type"`
+	Name string `rethinkdb:"name"`
+}
+
+func (*resolvedDog) Sound() string { return "woof" }
+
+func TestDecodeTypeResolver(t *testing.T) {
+	RegisterTypeResolver("kind_of_animal", func(kind string) reflect.Type {
+		return nil
+	})
+	RegisterTypeResolver("type", func(kind string) reflect.Type {
+		switch kind {
+		case "cat":
+			return reflect.TypeOf(resolvedCat{})
+		case "dog":
+			return reflect.TypeOf(&resolvedDog{})
+		}
+		return nil
+	})
+	defer typeResolvers.v.Store([]typeResolver(nil))
+
+	var got struct {
+		Pets  []animal    `rethinkdb:"pets"`
+		Any   interface{} `rethinkdb:"any"`
+		Other interface{} `rethinkdb:"other"`
+	}
+	err := Decode(&got, map[string]interface{}{
+		"pets": []interface{}{
+			map[string]interface{}{"type": "cat", "lives": float64(9)},
+			map[string]interface{}{"type": "dog", "name": "rex"},
+		},
+		"any":   map[string]interface{}{"type": "cat", "lives": float64(3)},
+		"other": map[string]interface{}{"type": "fish"},
+	})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	wantPets := []animal{resolvedCat{Type: "cat", Lives: 9}, &resolvedDog{Type: "dog", Name: "rex"}}
+	if !reflect.DeepEqual(got.Pets, wantPets) {
+		t.Errorf("got %v, want %v", got.Pets, wantPets)
+	}
+	if want := (resolvedCat{Type: "cat", Lives: 3}); !reflect.DeepEqual(got.Any, want) {
+		t.Errorf("got %v, want %v", got.Any, want)
+	}
+	if want := map[string]interface{}{"type": "fish"}; !reflect.DeepEqual(got.Other, want) {
+		t.Errorf("got %v, want %v", got.Other, want)
+	}
+
+	// Unresolved documents cannot be decoded into non-empty interfaces
+	var pet animal
+	if err := Decode(&pet, map[string]interface{}{"type": "fish"}); err == nil {
+		t.Errorf("expected error decoding unresolved type")
+	}
+}
+
+func TestDecodeNumericCoercion(t *testing.T) {
+	sources := []interface{}{int(3), int8(3), int64(3), uint(3), uint16(3), uint64(3), float32(3), float64(3)}
+	dests := []interface{}{
+		new(int), new(int8), new(int16), new(int32), new(int64),
+		new(uint), new(uint8), new(uint16), new(uint32), new(uint64),
+		new(float32), new(float64),
+	}
+	for _, src := range sources {
+		for _, dest := range dests {
+			dv := reflect.New(reflect.TypeOf(dest).Elem())
+			if err := Decode(dv.Interface(), src); err != nil {
+				t.Errorf("Decode(%T, %T): %v", dest, src, err)
+				continue
+			}
+			want := reflect.ValueOf(3).Convert(dv.Elem().Type()).Interface()
+			if got := dv.Elem().Interface(); got != want {
+				t.Errorf("Decode(%T, %T): got %v, want %v", dest, src, got, want)
+			}
+		}
+	}
+
+	invalid := []struct {
+		in  interface{}
+		ptr interface{}
+	}{
+		{in: 1.5, ptr: new(int)},
+		{in: -0.5, ptr: new(uint)},
+		{in: math.NaN(), ptr: new(int64)},
+		{in: math.Inf(1), ptr: new(int64)},
+		{in: 1e20, ptr: new(int64)},
+		{in: 1e20, ptr: new(uint64)},
+		{in: 300, ptr: new(int8)},
+		{in: 300.0, ptr: new(uint8)},
+		{in: -1, ptr: new(uint)},
+		{in: -1.0, ptr: new(uint32)},
+		{in: uint64(math.MaxUint64), ptr: new(int64)},
+		{in: uint16(300), ptr: new(uint8)},
+		{in: int64(1<<53 + 1), ptr: new(float64)},
+		{in: uint64(1<<53 + 1), ptr: new(float64)},
+		{in: 1<<24 + 1, ptr: new(float32)},
+		{in: 1e39, ptr: new(float32)},
+		{in: []interface{}{1.5}, ptr: new([]int)},
+		{in: []interface{}{300.0}, ptr: new([]uint8)},
+		{in: []interface{}{1e39}, ptr: new([]float32)},
+	}
+	for _, tt := range invalid {
+		err := Decode(tt.ptr, tt.in)
+		if _, ok := err.(*DecodeTypeError); !ok {
+			t.Errorf("Decode(%T, %v): got error %v, want DecodeTypeError", tt.ptr, tt.in, err)
+		}
+	}
+
+	// Values which fit are decoded, even if they are not exact
+	var f float32
+	if err := Decode(&f, 0.1); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if f != float32(0.1) {
+		t.Errorf("got %v, want %v", f, float32(0.1))
+	}
+	var i int64
+	if err := Decode(&i, float64(-1<<53)); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if i != -1<<53 {
+		t.Errorf("got %v, want %v", i, -1<<53)
+	}
+}
+
+func TestDecodeSliceNullVsEmpty(t *testing.T) {
+	type doc struct {
+		Tags  []string
+		Other int
+	}
+
+	tests := []struct {
+		name      string
+		in        map[string]interface{}
+		wantNil   bool
+		wantMerge []string
+	}{
+		{"missing", map[string]interface{}{"Other": 1}, true, []string{"x"}},
+		{"empty", map[string]interface{}{"Tags": []interface{}{}}, false, []string{}},
+		{"null", map[string]interface{}{"Tags": nil}, true, nil},
+	}
+	for _, tt := range tests {
+		out := doc{Tags: []string{"x"}}
+		if err := Decode(&out, tt.in); err != nil {
+			t.Fatalf("Decode(%s): %v", tt.name, err)
+		}
+		if (out.Tags == nil) != tt.wantNil || len(out.Tags) != 0 {
+			t.Errorf("Decode(%s): got %#v, want nil %v", tt.name, out.Tags, tt.wantNil)
+		}
+
+		out = doc{Tags: []string{"x"}}
+		if err := Merge(&out, tt.in); err != nil {
+			t.Fatalf("Merge(%s): %v", tt.name, err)
+		}
+		if (out.Tags == nil) != (tt.wantMerge == nil) || !reflect.DeepEqual(out.Tags, tt.wantMerge) {
+			t.Errorf("Merge(%s): got %#v, want %#v", tt.name, out.Tags, tt.wantMerge)
+		}
+	}
+}
+
+func TestDecodePointerRoot(t *testing.T) {
+	type doc struct {
+		Name string
+	}
+
+	var p *doc
+	if err := Decode(&p, map[string]interface{}{"Name": "a"}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if p == nil || p.Name != "a" {
+		t.Errorf("got %#v, want %#v", p, &doc{Name: "a"})
+	}
+
+	// An allocated pointer is reused
+	prev := p
+	if err := Decode(&p, map[string]interface{}{"Name": "b"}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if p != prev || p.Name != "b" {
+		t.Errorf("got %#v, want %#v at the same address", p, &doc{Name: "b"})
+	}
+
+	if err := Decode(&p, nil); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if p != nil {
+		t.Errorf("got %#v, want nil", p)
+	}
+
+	var pp **doc
+	if err := Decode(&pp, map[string]interface{}{"Name": "c"}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if pp == nil || *pp == nil || (*pp).Name != "c" {
+		t.Errorf("got %#v, want a pointer to %#v", pp, &doc{Name: "c"})
+	}
+
+	var pi *int
+	if err := Decode(&pi, float64(5)); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if pi == nil || *pi != 5 {
+		t.Errorf("got %v, want pointer to 5", pi)
+	}
+
+	var ps *[]string
+	if err := Decode(&ps, []interface{}{"a"}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if ps == nil || !reflect.DeepEqual(*ps, []string{"a"}) {
+		t.Errorf("got %v, want pointer to %v", ps, []string{"a"})
+	}
+
+	var pm *map[string]interface{}
+	if err := Decode(&pm, map[string]interface{}{"a": "b"}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if pm == nil || !reflect.DeepEqual(*pm, map[string]interface{}{"a": "b"}) {
+		t.Errorf("got %v, want pointer to %v", pm, map[string]interface{}{"a": "b"})
+	}
+}
+
+func TestDecodeBoolCoercion(t *testing.T) {
+	valid := []struct {
+		in   interface{}
+		want bool
+	}{
+		{in: true, want: true},
+		{in: false, want: false},
+		{in: float64(1), want: true},
+		{in: float64(0), want: false},
+		{in: float64(2), want: true},
+		{in: int(1), want: true},
+		{in: int64(0), want: false},
+		{in: uint8(1), want: true},
+		{in: "true", want: true},
+		{in: "false", want: false},
+		{in: "TRUE", want: true},
+		{in: "1", want: true},
+		{in: "0", want: false},
+		{in: "t", want: true},
+		{in: "F", want: false},
+		{in: "", want: false},
+	}
+	for _, tt := range valid {
+		var got bool
+		if err := Decode(&got, tt.in); err != nil {
+			t.Errorf("Decode(%#v): %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("Decode(%#v): got %v, want %v", tt.in, got, tt.want)
+		}
+
+		var doc struct {
+			Enabled bool   `rethinkdb:"enabled"`
+			Flags   []bool `rethinkdb:"flags"`
+		}
+		err := Decode(&doc, map[string]interface{}{"enabled": tt.in, "flags": []interface{}{tt.in}})
+		if err != nil {
+			t.Errorf("Decode(%#v) into struct: %v", tt.in, err)
+		} else if doc.Enabled != tt.want || !reflect.DeepEqual(doc.Flags, []bool{tt.want}) {
+			t.Errorf("Decode(%#v) into struct: got %+v, want %v", tt.in, doc, tt.want)
+		}
+	}
+
+	invalid := []interface{}{"yes", "no", "on", []interface{}{true}, map[string]interface{}{}}
+	for _, in := range invalid {
+		var got bool
+		if err := Decode(&got, in); err == nil {
+			t.Errorf("Decode(%#v): expected an error, got %v", in, got)
+		}
+	}
+}
@@ -2,18 +2,32 @@ package encoding
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
+	"time"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/internal/reqltime"
 )
 
 // newTypeDecoder constructs an decoderFunc for a type.
 func newTypeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
+	if reflect.PtrTo(dt).Implements(streamUnmarshalerType) ||
+		dt.Implements(streamUnmarshalerType) {
+		return streamUnmarshalerDecoder
+	}
+
 	if reflect.PtrTo(dt).Implements(unmarshalerType) ||
 		dt.Implements(unmarshalerType) {
 		return unmarshalerDecoder
 	}
 
+	if sqlNullTypes[dt] && st.Kind() != reflect.Map {
+		return newSQLNullDecoder(blank)
+	}
+
 	if st.Kind() == reflect.Interface {
 		return newInterfaceAsTypeDecoder(blank)
 	}
@@ -95,6 +109,9 @@ func newTypeDecoder(dt, st reflect.Type, blank bool) decoderFunc {
 			return decodeTypeError
 		}
 	case reflect.Interface:
+		if st.Kind() == reflect.Map {
+			return newResolvedInterfaceDecoder(dt, st, blank)
+		}
 		if !st.AssignableTo(dt) {
 			return decodeTypeError
 		}
@@ -161,7 +178,7 @@ func invalidValueDecoder(dv, sv reflect.Value) error {
 }
 
 func unsupportedTypeDecoder(dv, sv reflect.Value) error {
-	return &UnsupportedTypeError{dv.Type()}
+	return &UnsupportedTypeError{Type: dv.Type()}
 }
 
 func decodeTypeError(dv, sv reflect.Value) error {
@@ -195,6 +212,63 @@ func newInterfaceAsTypeDecoder(blank bool) decoderFunc {
 			}
 			return decodeValue(dv, sv.Elem(), blank)
 		}
+
+		// A null value sets slices, maps, pointers and interfaces to nil,
+		// other types are left unchanged.
+		switch dv.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+			dv.Set(reflect.Zero(dv.Type()))
+		}
+		return nil
+	}
+}
+
+// newSQLNullDecoder returns a decoder for the database/sql nullable types
+// such as sql.NullString. A null value sets Valid to false, any other value
+// is decoded into the value field and sets Valid to true.
+func newSQLNullDecoder(blank bool) decoderFunc {
+	return func(dv, sv reflect.Value) error {
+		if sv.Kind() == reflect.Interface {
+			sv = sv.Elem()
+		}
+		if !sv.IsValid() {
+			dv.Set(reflect.Zero(dv.Type()))
+			return nil
+		}
+
+		if err := decodeValue(dv.Field(0), sv, blank); err != nil {
+			return err
+		}
+		dv.Field(1).SetBool(true)
+
+		return nil
+	}
+}
+
+// newResolvedInterfaceDecoder returns a decoder for documents decoded into an
+// interface which uses the resolvers registered with RegisterTypeResolver to
+// pick the concrete type of the value.
+func newResolvedInterfaceDecoder(dt, st reflect.Type, blank bool) decoderFunc {
+	fallback := decodeTypeError
+	if st.AssignableTo(dt) {
+		fallback = interfaceDecoder
+	}
+
+	return func(dv, sv reflect.Value) error {
+		t := resolveType(sv)
+		if t == nil {
+			return fallback(dv, sv)
+		}
+
+		v := reflect.New(t).Elem()
+		if err := decodeValue(v, sv, blank); err != nil {
+			return err
+		}
+		if !t.AssignableTo(dt) {
+			return &DecodeTypeError{dt, t, "resolved type does not implement the interface"}
+		}
+		dv.Set(v)
+
 		return nil
 	}
 }
@@ -234,6 +308,66 @@ func unmarshalerDecoder(dv, sv reflect.Value) error {
 	return nil
 }
 
+func streamUnmarshalerDecoder(dv, sv reflect.Value) error {
+	if sv.Kind() == reflect.Interface && !sv.IsNil() {
+		sv = sv.Elem()
+	}
+
+	var b []byte
+	if sv.IsValid() && sv.Type() == rawMessageType {
+		b = sv.Bytes()
+	} else if dv.Type().Implements(unmarshalerType) || reflect.PtrTo(dv.Type()).Implements(unmarshalerType) {
+		// Prefer UnmarshalRQL if possible when the raw JSON is not available
+		return unmarshalerDecoder(dv, sv)
+	} else {
+		var err error
+		var v interface{}
+		if sv.IsValid() {
+			v = sv.Interface()
+		}
+		b, err = json.Marshal(v)
+		if err != nil {
+			return &DecodeTypeError{dv.Type(), sv.Type(), err.Error()}
+		}
+	}
+
+	if dv.Kind() != reflect.Ptr && dv.Type().Name() != "" && dv.CanAddr() {
+		dv = dv.Addr()
+	}
+
+	if dv.IsNil() {
+		dv.Set(reflect.New(dv.Type().Elem()))
+	}
+
+	u := dv.Interface().(StreamUnmarshaler)
+	if err := u.UnmarshalRQLStream(bytes.NewReader(b)); err != nil {
+		return &DecodeTypeError{dv.Type(), rawMessageType, err.Error()}
+	}
+	return nil
+}
+
+// StreamFieldNames returns the names of the fields in the struct type t which
+// implement StreamUnmarshaler. If t is not a struct (or a pointer to a struct)
+// then nil is returned.
+func StreamFieldNames(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for _, f := range cachedTypeFields(t) {
+		ft := typeByIndex(t, f.index)
+		if ft.Implements(streamUnmarshalerType) || reflect.PtrTo(ft).Implements(streamUnmarshalerType) {
+			names = append(names, f.name)
+		}
+	}
+
+	return names
+}
+
 // Boolean decoders
 
 func boolAsBoolDecoder(dv, sv reflect.Value) error {
@@ -280,15 +414,28 @@ func intAsBoolDecoder(dv, sv reflect.Value) error {
 	return nil
 }
 func intAsIntDecoder(dv, sv reflect.Value) error {
-	dv.SetInt(sv.Int())
+	i := sv.Int()
+	if dv.OverflowInt(i) {
+		return numberRangeError(dv, sv)
+	}
+	dv.SetInt(i)
 	return nil
 }
 func intAsUintDecoder(dv, sv reflect.Value) error {
-	dv.SetUint(uint64(sv.Int()))
+	i := sv.Int()
+	if i < 0 || dv.OverflowUint(uint64(i)) {
+		return numberRangeError(dv, sv)
+	}
+	dv.SetUint(uint64(i))
 	return nil
 }
 func intAsFloatDecoder(dv, sv reflect.Value) error {
-	dv.SetFloat(float64(sv.Int()))
+	i := sv.Int()
+	f := roundFloat(dv, float64(i))
+	if f >= math.MaxInt64 || int64(f) != i {
+		return numberPrecisionError(dv, sv)
+	}
+	dv.SetFloat(f)
 	return nil
 }
 func intAsStringDecoder(dv, sv reflect.Value) error {
@@ -303,15 +450,28 @@ func uintAsBoolDecoder(dv, sv reflect.Value) error {
 	return nil
 }
 func uintAsIntDecoder(dv, sv reflect.Value) error {
-	dv.SetInt(int64(sv.Uint()))
+	u := sv.Uint()
+	if u > math.MaxInt64 || dv.OverflowInt(int64(u)) {
+		return numberRangeError(dv, sv)
+	}
+	dv.SetInt(int64(u))
 	return nil
 }
 func uintAsUintDecoder(dv, sv reflect.Value) error {
-	dv.SetUint(sv.Uint())
+	u := sv.Uint()
+	if dv.OverflowUint(u) {
+		return numberRangeError(dv, sv)
+	}
+	dv.SetUint(u)
 	return nil
 }
 func uintAsFloatDecoder(dv, sv reflect.Value) error {
-	dv.SetFloat(float64(sv.Uint()))
+	u := sv.Uint()
+	f := roundFloat(dv, float64(u))
+	if f >= math.MaxUint64 || uint64(f) != u {
+		return numberPrecisionError(dv, sv)
+	}
+	dv.SetFloat(f)
 	return nil
 }
 func uintAsStringDecoder(dv, sv reflect.Value) error {
@@ -326,15 +486,33 @@ func floatAsBoolDecoder(dv, sv reflect.Value) error {
 	return nil
 }
 func floatAsIntDecoder(dv, sv reflect.Value) error {
-	dv.SetInt(int64(sv.Float()))
+	f := sv.Float()
+	if f != math.Trunc(f) {
+		return numberFractionError(dv, sv)
+	}
+	if f < math.MinInt64 || f >= math.MaxInt64 || dv.OverflowInt(int64(f)) {
+		return numberRangeError(dv, sv)
+	}
+	dv.SetInt(int64(f))
 	return nil
 }
 func floatAsUintDecoder(dv, sv reflect.Value) error {
-	dv.SetUint(uint64(sv.Float()))
+	f := sv.Float()
+	if f != math.Trunc(f) {
+		return numberFractionError(dv, sv)
+	}
+	if f < 0 || f >= math.MaxUint64 || dv.OverflowUint(uint64(f)) {
+		return numberRangeError(dv, sv)
+	}
+	dv.SetUint(uint64(f))
 	return nil
 }
 func floatAsFloatDecoder(dv, sv reflect.Value) error {
-	dv.SetFloat(float64(sv.Float()))
+	f := sv.Float()
+	if dv.OverflowFloat(f) {
+		return numberRangeError(dv, sv)
+	}
+	dv.SetFloat(f)
 	return nil
 }
 func floatAsStringDecoder(dv, sv reflect.Value) error {
@@ -342,6 +520,26 @@ func floatAsStringDecoder(dv, sv reflect.Value) error {
 	return nil
 }
 
+// roundFloat returns f rounded to the precision of the float type of dv.
+func roundFloat(dv reflect.Value, f float64) float64 {
+	if dv.Kind() == reflect.Float32 {
+		return float64(float32(f))
+	}
+	return f
+}
+
+func numberRangeError(dv, sv reflect.Value) error {
+	return &DecodeTypeError{dv.Type(), sv.Type(), fmt.Sprintf("value %v overflows %s", sv.Interface(), dv.Type())}
+}
+
+func numberFractionError(dv, sv reflect.Value) error {
+	return &DecodeTypeError{dv.Type(), sv.Type(), fmt.Sprintf("value %v has a fractional part", sv.Interface())}
+}
+
+func numberPrecisionError(dv, sv reflect.Value) error {
+	return &DecodeTypeError{dv.Type(), sv.Type(), fmt.Sprintf("value %v cannot be represented exactly by %s", sv.Interface(), dv.Type())}
+}
+
 // String decoders
 
 func stringAsBoolDecoder(dv, sv reflect.Value) error {
@@ -409,14 +607,110 @@ func (d *sliceDecoder) decode(dv, sv reflect.Value) error {
 
 func newSliceDecoder(dt, st reflect.Type) decoderFunc {
 	dec := &sliceDecoder{newArrayDecoder(dt, st)}
+	if st == interfaceSliceType && isScalarKind(dt.Elem()) {
+		return newScalarSliceDecoder(dt, dec.decode)
+	}
 	return dec.decode
 }
 
+// isScalarKind returns true if values of type t can be decoded by
+// scalarSliceDecoder, types with custom decoding (including types registered
+// using SetTypeEncoding) are excluded.
+func isScalarKind(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(unmarshalerType) || t.Implements(unmarshalerType) ||
+		reflect.PtrTo(t).Implements(streamUnmarshalerType) || t.Implements(streamUnmarshalerType) ||
+		hasTypeEncoding(t) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+// scalarSliceDecoder decodes a []interface{} of numbers, strings or booleans
+// into a slice of a scalar type without looking up a decoder for each element.
+// If any element cannot be converted directly the slice is decoded using the
+// fallback decoder instead.
+type scalarSliceDecoder struct {
+	typ      reflect.Type
+	fallback decoderFunc
+}
+
+func newScalarSliceDecoder(dt reflect.Type, fallback decoderFunc) decoderFunc {
+	dec := &scalarSliceDecoder{typ: dt, fallback: fallback}
+	return dec.decode
+}
+
+func (d *scalarSliceDecoder) decode(dv, sv reflect.Value) error {
+	if sv.IsNil() {
+		return d.fallback(dv, sv)
+	}
+
+	src := sv.Interface().([]interface{})
+	out := reflect.MakeSlice(d.typ, len(src), len(src))
+	kind := d.typ.Elem().Kind()
+	for i, v := range src {
+		ev := out.Index(i)
+		switch kind {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, ok := v.(float64)
+			if !ok {
+				return d.fallback(dv, sv)
+			}
+			if err := floatAsIntDecoder(ev, reflect.ValueOf(n)); err != nil {
+				return err
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n, ok := v.(float64)
+			if !ok {
+				return d.fallback(dv, sv)
+			}
+			if err := floatAsUintDecoder(ev, reflect.ValueOf(n)); err != nil {
+				return err
+			}
+		case reflect.Float32, reflect.Float64:
+			n, ok := v.(float64)
+			if !ok {
+				return d.fallback(dv, sv)
+			}
+			if err := floatAsFloatDecoder(ev, reflect.ValueOf(n)); err != nil {
+				return err
+			}
+		case reflect.String:
+			str, ok := v.(string)
+			if !ok {
+				return d.fallback(dv, sv)
+			}
+			ev.SetString(str)
+		case reflect.Bool:
+			b, ok := v.(bool)
+			if !ok {
+				return d.fallback(dv, sv)
+			}
+			ev.SetBool(b)
+		}
+	}
+
+	dv.Set(out)
+	return nil
+}
+
 type arrayDecoder struct {
 	elemDec decoderFunc
 }
 
 func (d *arrayDecoder) decode(dv, sv reflect.Value) error {
+	// Fixed-size arrays must have the same length as the source
+	if dv.Kind() == reflect.Array && sv.Len() != dv.Len() {
+		return &DecodeTypeError{dv.Type(), sv.Type(), fmt.Sprintf("array has %d elements, expected %d", sv.Len(), dv.Len())}
+	}
+
 	// Iterate through the slice/array and decode each element before adding it
 	// to the dest slice/array
 	i := 0
@@ -448,17 +742,9 @@ func (d *arrayDecoder) decode(dv, sv reflect.Value) error {
 		i++
 	}
 
-	// Ensure that the destination is the correct size
+	// Ensure that the destination slice is the correct size
 	if i < dv.Len() {
-		if dv.Kind() == reflect.Array {
-			// Array.  Zero the rest.
-			z := reflect.Zero(dv.Type().Elem())
-			for ; i < dv.Len(); i++ {
-				dv.Index(i).Set(z)
-			}
-		} else {
-			dv.SetLen(i)
-		}
+		dv.SetLen(i)
 	}
 	return nil
 }
@@ -525,9 +811,10 @@ func newMapAsMapDecoder(dt, st reflect.Type, blank bool) decoderFunc {
 }
 
 type mapAsStructDecoder struct {
-	fields    []field
-	fieldDecs []decoderFunc
-	blank     bool
+	fields     []field
+	fieldDecs  []decoderFunc
+	extraField *field
+	blank      bool
 }
 
 func (d *mapAsStructDecoder) decode(dv, sv reflect.Value) error {
@@ -539,6 +826,9 @@ func (d *mapAsStructDecoder) decode(dv, sv reflect.Value) error {
 		for i := range d.fields {
 			ff := &d.fields[i]
 			ffd := d.fieldDecs[i]
+			if ff.extra {
+				continue
+			}
 
 			if bytes.Equal(ff.nameBytes, key) {
 				f = ff
@@ -588,11 +878,42 @@ func (d *mapAsStructDecoder) decode(dv, sv reflect.Value) error {
 			if err != nil {
 				return err
 			}
+		} else if d.extraField != nil {
+			if err := d.decodeExtra(dv, kv, sv.MapIndex(kv)); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// decodeExtra stores a key which does not match any field in the field tagged
+// with the extra option.
+func (d *mapAsStructDecoder) decodeExtra(dv, kv, sv reflect.Value) error {
+	dMapVal := fieldByIndex(dv, d.extraField.index)
+	if !sv.IsValid() || !dMapVal.CanSet() {
+		return nil
+	}
+	if dMapVal.IsNil() {
+		dMapVal.Set(reflect.MakeMap(dMapVal.Type()))
+	}
+
+	if sv.Kind() == reflect.Interface && !sv.IsNil() {
+		sv = sv.Elem()
+	}
+
+	elemType := dMapVal.Type().Elem()
+	elem := reflect.New(elemType).Elem()
+	if sv.IsValid() {
+		if err := typeDecoder(elemType, sv.Type(), d.blank)(elem, sv); err != nil {
+			return err
+		}
+	}
+
+	dMapVal.SetMapIndex(reflect.ValueOf(kv.String()).Convert(dMapVal.Type().Key()), elem)
+	return nil
+}
+
 func newMapAsStructDecoder(dt, st reflect.Type, blank bool) decoderFunc {
 	fields := cachedTypeFields(dt)
 	se := &mapAsStructDecoder{
@@ -602,6 +923,145 @@ func newMapAsStructDecoder(dt, st reflect.Type, blank bool) decoderFunc {
 	}
 	for i, f := range fields {
 		se.fieldDecs[i] = typeDecoder(typeByIndex(dt, f.index), st.Elem(), blank)
+		if f.unixTime != 0 {
+			se.fieldDecs[i] = newUnixTimeDecoder(f.unixTime, se.fieldDecs[i])
+		}
+		if f.timeLayout != "" {
+			se.fieldDecs[i] = newTimeLayoutDecoder(f.timeLayout, se.fieldDecs[i])
+		}
+		if f.timeFormat != "" {
+			se.fieldDecs[i] = newTimeFormatDecoder(f.timeFormat, blank, se.fieldDecs[i])
+		}
+		if f.extra && se.extraField == nil {
+			se.extraField = &fields[i]
+		}
 	}
 	return se.decode
 }
+
+// newTimeFormatDecoder wraps the decoder of a field tagged with the
+// "timeformat" option. With the "raw" format times are decoded as TIME
+// pseudo-type objects, with the "native" format TIME pseudo-type objects are
+// decoded as times. Other values are decoded by fallback.
+func newTimeFormatDecoder(format string, blank bool, fallback decoderFunc) decoderFunc {
+	return func(dv, sv reflect.Value) error {
+		nv := sv
+		if nv.Kind() == reflect.Interface && !nv.IsNil() {
+			nv = nv.Elem()
+		}
+		if !nv.IsValid() {
+			return fallback(dv, sv)
+		}
+
+		var v interface{}
+		var err error
+		switch {
+		case format == "raw" && nv.Type() == timeType:
+			v = timeToReqlTime(nv.Interface().(time.Time))
+		case format == "native" && nv.Type() == mapInterfaceType:
+			obj := nv.Interface().(map[string]interface{})
+			if obj["$reql_type$"] != "TIME" {
+				return fallback(dv, sv)
+			}
+			v, err = reqltime.ToTime(obj)
+		default:
+			return fallback(dv, sv)
+		}
+		if err != nil {
+			return &DecodeTypeError{dv.Type(), nv.Type(), err.Error()}
+		}
+
+		return decodeValue(dv, reflect.ValueOf(v), blank)
+	}
+}
+
+// timeToReqlTime converts a time.Time to a TIME pseudo-type object. Unlike
+// timePseudoTypeEncoder the time is split at the millisecond precision used
+// by the server, so times converted by the driver give back the objects sent
+// by the server.
+func timeToReqlTime(t time.Time) map[string]interface{} {
+	ms := t.Nanosecond() / int(time.Millisecond)
+
+	return map[string]interface{}{
+		"$reql_type$": "TIME",
+		"epoch_time":  float64(t.Unix()) + float64(ms)/1000,
+		"timezone":    t.Format("-07:00"),
+	}
+}
+
+// newUnixTimeDecoder wraps the decoder of a time.Time field tagged with the
+// "unix" or "unixmilli" options so that numbers are decoded as the time since
+// the Unix epoch in the given unit, in UTC. Other values, such as TIME pseudo-types,
+// are decoded by fallback.
+func newUnixTimeDecoder(unit time.Duration, fallback decoderFunc) decoderFunc {
+	return func(dv, sv reflect.Value) error {
+		nv := sv
+		if nv.Kind() == reflect.Interface && !nv.IsNil() {
+			nv = nv.Elem()
+		}
+
+		var n float64
+		switch nv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			n = nv.Float()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(nv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n = float64(nv.Uint())
+		default:
+			if num, ok := nv.Interface().(json.Number); ok {
+				f, err := num.Float64()
+				if err != nil {
+					return &DecodeTypeError{dv.Type(), nv.Type(), err.Error()}
+				}
+				n = f
+				break
+			}
+			return fallback(dv, sv)
+		}
+
+		// Split the value to avoid losing precision when scaling large values
+		perSec := int64(time.Second / unit)
+		whole, frac := math.Modf(n)
+		w := int64(whole)
+		nsec := (w%perSec)*int64(unit) + int64(math.Round(frac*float64(unit)))
+		t := reflect.ValueOf(time.Unix(w/perSec, nsec).UTC())
+		if dv.Kind() == reflect.Ptr {
+			if dv.IsNil() {
+				dv.Set(reflect.New(timeType))
+			}
+			dv = dv.Elem()
+		}
+		dv.Set(t)
+		return nil
+	}
+}
+
+// newTimeLayoutDecoder wraps the decoder of a time.Time field tagged with the
+// "timelayout" option so that strings are parsed using layout, times without a
+// timezone are in UTC. Other values, such as TIME pseudo-types, are decoded by
+// fallback.
+func newTimeLayoutDecoder(layout string, fallback decoderFunc) decoderFunc {
+	return func(dv, sv reflect.Value) error {
+		nv := sv
+		if nv.Kind() == reflect.Interface && !nv.IsNil() {
+			nv = nv.Elem()
+		}
+		if nv.Kind() != reflect.String {
+			return fallback(dv, sv)
+		}
+
+		parsed, err := time.Parse(layout, nv.String())
+		if err != nil {
+			return &DecodeTypeError{dv.Type(), nv.Type(), err.Error()}
+		}
+		if dv.Kind() == reflect.Ptr {
+			if dv.IsNil() {
+				dv.Set(reflect.New(timeType))
+			}
+			dv = dv.Elem()
+		}
+		dv.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+}
@@ -33,6 +33,50 @@ func Encode(v interface{}) (ev interface{}, err error) {
 	return encode(reflect.ValueOf(v))
 }
 
+// EncodeNonZero returns the encoded value of v like Encode but omits any
+// struct fields which contain their zero value, as if every field was tagged
+// with the "omitempty" option. Fields of nested structs are omitted in the
+// same way, structs which implement Marshaler are encoded as normal.
+func EncodeNonZero(v interface{}) (interface{}, error) {
+	ev, err := Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return omitZeroFields(reflect.ValueOf(v), ev), nil
+}
+
+func omitZeroFields(v reflect.Value, ev interface{}) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ev
+		}
+		v = v.Elem()
+	}
+
+	m, ok := ev.(map[string]interface{})
+	if !ok || v.Kind() != reflect.Struct || v.Type().Implements(marshalerType) {
+		return ev
+	}
+
+	for _, f := range cachedTypeFields(v.Type()) {
+		if f.extra || f.compound || f.reference {
+			continue
+		}
+
+		fv := fieldByIndex(v, f.index)
+		if !fv.IsValid() || fv.IsZero() {
+			delete(m, f.name)
+			continue
+		}
+		if fev, ok := m[f.name]; ok {
+			m[f.name] = omitZeroFields(fv, fev)
+		}
+	}
+
+	return m
+}
+
 func encode(v reflect.Value) (interface{}, error) {
 	return valueEncoder(v)(v)
 }
@@ -1,8 +1,11 @@
 package encoding
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"image"
+	"math"
 	"reflect"
 	"testing"
 	"time"
@@ -547,3 +550,312 @@ func TestEncodeCustomRootTypeEncodingError(t *testing.T) {
 		t.Errorf("got %q, want %q", err, cerr)
 	}
 }
+
+type stringerEnum int
+
+func (e stringerEnum) String() string {
+	switch e {
+	case 1:
+		return "first"
+	case 2:
+		return "second"
+	default:
+		return "unknown"
+	}
+}
+
+type marshalerStringer int
+
+func (m marshalerStringer) String() string {
+	return "string"
+}
+
+func (m marshalerStringer) MarshalRQL() (interface{}, error) {
+	return "marshaler", nil
+}
+
+func TestEncodeStringers(t *testing.T) {
+	type Doc struct {
+		Enum      stringerEnum
+		Marshaler marshalerStringer
+	}
+	v := Doc{Enum: 2, Marshaler: 1}
+
+	got, err := Encode(v)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want := map[string]interface{}{"Enum": int64(2), "Marshaler": "marshaler"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	SetEncodeStringers(true)
+	defer SetEncodeStringers(false)
+
+	got, err = Encode(v)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want = map[string]interface{}{"Enum": "second", "Marshaler": "marshaler"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Times implement fmt.Stringer but should still be encoded as pseudo-types
+	got, err = Encode(time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, ok := got.(map[string]interface{}); !ok {
+		t.Errorf("got %v, want time pseudo-type", got)
+	}
+}
+
+func TestEncodeExtraFields(t *testing.T) {
+	type extraFields struct {
+		ID    string                 `rethinkdb:"id"`
+		Extra map[string]interface{} `rethinkdb:",extra"`
+	}
+
+	v := extraFields{
+		ID: "a",
+		Extra: map[string]interface{}{
+			"id":   "ignored",
+			"name": "test",
+		},
+	}
+	want := map[string]interface{}{
+		"id":   "a",
+		"name": "test",
+	}
+
+	got, err := Encode(v)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEncodeLargeIntegers(t *testing.T) {
+	got, err := Encode(int64(math.MaxInt64))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if got != int64(math.MaxInt64) {
+		t.Errorf("got %v, want %v", got, int64(math.MaxInt64))
+	}
+
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(b) != "9223372036854775807" {
+		t.Errorf("got %s, want 9223372036854775807", b)
+	}
+}
+
+func TestEncodeQuotedIntegers(t *testing.T) {
+	type quoted struct {
+		ID    int64   `rethinkdb:"id,string"`
+		Count *uint64 `rethinkdb:"count,string,omitempty"`
+		Name  string  `rethinkdb:"name,string"`
+	}
+
+	count := uint64(math.MaxUint64)
+	v := quoted{ID: math.MaxInt64, Count: &count, Name: "a"}
+	want := map[string]interface{}{
+		"id":    "9223372036854775807",
+		"count": "18446744073709551615",
+		"name":  "a",
+	}
+
+	got, err := Encode(v)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var decoded quoted
+	if err := Decode(&decoded, got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.ID != math.MaxInt64 || decoded.Count == nil || *decoded.Count != count {
+		t.Errorf("got %+v, want %+v", decoded, v)
+	}
+}
+
+func TestEncodeNonZero(t *testing.T) {
+	type address struct {
+		City    string `rethinkdb:"city"`
+		Country string `rethinkdb:"country"`
+	}
+	type user struct {
+		ID      string    `rethinkdb:"id"`
+		Name    string    `rethinkdb:"name"`
+		Age     int       `rethinkdb:"age"`
+		Active  bool      `rethinkdb:"active"`
+		Created time.Time `rethinkdb:"created"`
+		Address address   `rethinkdb:"address"`
+		Tags    []string  `rethinkdb:"tags"`
+	}
+
+	got, err := EncodeNonZero(&user{ID: "a", Age: 30, Address: address{City: "London"}, Tags: []string{}})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want := map[string]interface{}{
+		"id":      "a",
+		"age":     int64(30),
+		"address": map[string]interface{}{"city": "London"},
+		"tags":    []interface{}{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEncodeSQLNullTypes(t *testing.T) {
+	now := time.Unix(1500000000, 0).UTC()
+	input := struct {
+		String  sql.NullString  `rethinkdb:"string"`
+		Int64   sql.NullInt64   `rethinkdb:"int64"`
+		Int32   sql.NullInt32   `rethinkdb:"int32"`
+		Float64 sql.NullFloat64 `rethinkdb:"float64"`
+		Bool    sql.NullBool    `rethinkdb:"bool"`
+		Time    sql.NullTime    `rethinkdb:"time"`
+		Null    sql.NullString  `rethinkdb:"null"`
+		Omitted sql.NullInt64   `rethinkdb:"omitted,omitempty"`
+	}{
+		String:  sql.NullString{String: "bob", Valid: true},
+		Int64:   sql.NullInt64{Int64: 12, Valid: true},
+		Int32:   sql.NullInt32{Int32: 3, Valid: true},
+		Float64: sql.NullFloat64{Float64: 1.5, Valid: true},
+		Bool:    sql.NullBool{Bool: false, Valid: true},
+		Time:    sql.NullTime{Time: now, Valid: true},
+		Null:    sql.NullString{String: "ignored"},
+		Omitted: sql.NullInt64{Int64: 1},
+	}
+
+	got, err := Encode(input)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want := map[string]interface{}{
+		"string":  "bob",
+		"int64":   int64(12),
+		"int32":   int64(3),
+		"float64": 1.5,
+		"bool":    false,
+		"time":    map[string]interface{}{"$reql_type$": "TIME", "epoch_time": float64(1500000000), "timezone": "+00:00"},
+		"null":    nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEncodeNilAsEmpty(t *testing.T) {
+	type Doc struct {
+		Tags    []string
+		Attrs   map[string]int
+		Ptr     *int
+		Data    []byte
+		Always  []int          `rethinkdb:"always,nilasempty"`
+		AlwaysM map[string]int `rethinkdb:"always_m,nilasempty"`
+	}
+
+	got, err := Encode(Doc{})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want := map[string]interface{}{
+		"Tags":     []interface{}(nil),
+		"Attrs":    nil,
+		"Ptr":      nil,
+		"Data":     map[string]interface{}{"$reql_type$": "BINARY", "data": ""},
+		"always":   []interface{}{},
+		"always_m": map[string]interface{}{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	SetEncodeNilAsEmpty(true)
+	defer SetEncodeNilAsEmpty(false)
+
+	got, err = Encode(Doc{})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want["Tags"] = []interface{}{}
+	want["Attrs"] = map[string]interface{}{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	got, err = Encode(Doc{Tags: []string{"a"}, Always: []int{1}})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if tags := got.(map[string]interface{})["Tags"]; !reflect.DeepEqual(tags, []interface{}{"a"}) {
+		t.Errorf("got %#v, want %#v", tags, []interface{}{"a"})
+	}
+	if always := got.(map[string]interface{})["always"]; !reflect.DeepEqual(always, []interface{}{int64(1)}) {
+		t.Errorf("got %#v, want %#v", always, []interface{}{int64(1)})
+	}
+}
+
+func TestEncodeUnsupportedTypes(t *testing.T) {
+	type Inner struct {
+		Ch chan int `rethinkdb:"ch"`
+	}
+	type Doc struct {
+		Name     string
+		Callback func()
+		Inner    *Inner `rethinkdb:"inner,omitempty"`
+		Number   complex128
+	}
+
+	tests := []struct {
+		in    interface{}
+		typ   reflect.Type
+		field string
+	}{
+		{in: make(chan int), typ: reflect.TypeOf(make(chan int))},
+		{in: complex(1, 2), typ: reflect.TypeOf(complex(1, 2))},
+		{in: map[string]interface{}{"a": make(chan int)}, typ: reflect.TypeOf(make(chan int))},
+		{in: Doc{Callback: func() {}}, typ: reflect.TypeOf(func() {}), field: "Callback"},
+		{in: Doc{Inner: &Inner{Ch: make(chan int)}}, typ: reflect.TypeOf(make(chan int)), field: "inner.ch"},
+		{in: &Doc{Number: 1i}, typ: reflect.TypeOf(complex(1, 2)), field: "Number"},
+	}
+	for _, tt := range tests {
+		_, err := Encode(tt.in)
+		e, ok := err.(*UnsupportedTypeError)
+		if !ok {
+			t.Errorf("Encode(%T): got error %v, want UnsupportedTypeError", tt.in, err)
+			continue
+		}
+		if e.Type != tt.typ || e.Field != tt.field {
+			t.Errorf("Encode(%T): got %v %q, want %v %q", tt.in, e.Type, e.Field, tt.typ, tt.field)
+		}
+	}
+
+	_, err := Encode(Doc{Inner: &Inner{Ch: make(chan int)}})
+	if want := "rethinkdb: unsupported type: chan int (chan field inner.ch)"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+
+	// Nil functions are still encoded as null
+	got, err := Encode(struct{ Callback func() }{})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if want := map[string]interface{}{"Callback": nil}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"strconv"
 	"time"
 )
 
@@ -25,7 +26,19 @@ func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
 	case timeType:
 		return timePseudoTypeEncoder
 	}
+	if sqlNullTypes[t] {
+		return newSQLNullEncoder(t)
+	}
+
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && t.Implements(stringerType) {
+		return newStringerEncoder(newKindEncoder(t))
+	}
+
+	return newKindEncoder(t)
+}
 
+// newKindEncoder constructs an encoderFunc for a type based on its kind.
+func newKindEncoder(t reflect.Type) encoderFunc {
 	switch t.Kind() {
 	case reflect.Bool:
 		return boolEncoder
@@ -97,6 +110,19 @@ func addrMarshalerEncoder(v reflect.Value) (interface{}, error) {
 	return ev, nil
 }
 
+// newStringerEncoder returns an encoder which encodes values using their
+// String method when stringer encoding is enabled and otherwise falls back to
+// the given encoder.
+func newStringerEncoder(fallback encoderFunc) encoderFunc {
+	return func(v reflect.Value) (interface{}, error) {
+		if !encodeStringersEnabled() {
+			return fallback(v)
+		}
+
+		return v.Interface().(fmt.Stringer).String(), nil
+	}
+}
+
 func boolEncoder(v reflect.Value) (interface{}, error) {
 	if v.Bool() {
 		return true, nil
@@ -144,7 +170,7 @@ func asStringEncoder(v reflect.Value) (interface{}, error) {
 }
 
 func unsupportedTypeEncoder(v reflect.Value) (interface{}, error) {
-	return nil, &UnsupportedTypeError{v.Type()}
+	return nil, &UnsupportedTypeError{Type: v.Type()}
 }
 
 type structEncoder struct {
@@ -154,7 +180,13 @@ type structEncoder struct {
 
 func (se *structEncoder) encode(v reflect.Value) (interface{}, error) {
 	m := make(map[string]interface{})
+	var extraFields []int
 	for i, f := range se.fields {
+		if f.extra {
+			extraFields = append(extraFields, i)
+			continue
+		}
+
 		fv := fieldByIndex(v, f.index)
 		if !fv.IsValid() || f.omitEmpty && se.isEmptyValue(fv) {
 			continue
@@ -162,7 +194,7 @@ func (se *structEncoder) encode(v reflect.Value) (interface{}, error) {
 
 		encField, err := se.fieldEncs[i](fv)
 		if err != nil {
-			return nil, err
+			return nil, unsupportedFieldError(f.name, err)
 		}
 
 		// If this field is a referenced field then attempt to extract the value.
@@ -188,6 +220,26 @@ func (se *structEncoder) encode(v reflect.Value) (interface{}, error) {
 		m[f.name] = encField
 	}
 
+	// Splice any extra fields into the document, named fields take precedence
+	for _, i := range extraFields {
+		fv := fieldByIndex(v, se.fields[i].index)
+		if !fv.IsValid() {
+			continue
+		}
+
+		encField, err := se.fieldEncs[i](fv)
+		if err != nil {
+			return nil, unsupportedFieldError(se.fields[i].name, err)
+		}
+
+		extra, _ := encField.(map[string]interface{})
+		for k, ev := range extra {
+			if _, ok := m[k]; !ok {
+				m[k] = ev
+			}
+		}
+	}
+
 	return m, nil
 }
 
@@ -241,16 +293,91 @@ func newStructEncoder(t reflect.Type) encoderFunc {
 	}
 	for i, f := range fields {
 		se.fieldEncs[i] = typeEncoder(typeByIndex(t, f.index))
+		if typeByIndex(t, f.index).Kind() == reflect.Func {
+			se.fieldEncs[i] = funcFieldEncoder
+		}
+		if f.quoted {
+			se.fieldEncs[i] = newQuotedIntEncoder(se.fieldEncs[i])
+		}
+		if f.nilAsEmpty {
+			se.fieldEncs[i] = newNilAsEmptyEncoder(se.fieldEncs[i])
+		}
 	}
 	return se.encode
 }
 
+// newNilAsEmptyEncoder wraps the encoder of a slice or map field tagged with
+// the "nilasempty" option so that nil values are encoded as an empty array or
+// object rather than null.
+func newNilAsEmptyEncoder(enc encoderFunc) encoderFunc {
+	return func(v reflect.Value) (interface{}, error) {
+		if !v.IsNil() {
+			return enc(v)
+		}
+		if v.Kind() == reflect.Map {
+			return map[string]interface{}{}, nil
+		}
+		return []interface{}{}, nil
+	}
+}
+
+// funcFieldEncoder encodes struct fields with a function type, functions
+// cannot be stored in the database so an error is returned unless the field
+// is nil.
+func funcFieldEncoder(v reflect.Value) (interface{}, error) {
+	if v.IsNil() {
+		return nil, nil
+	}
+	return nil, &UnsupportedTypeError{Type: v.Type()}
+}
+
+// unsupportedFieldError adds the name of a struct field to the path of an
+// UnsupportedTypeError returned when encoding the field, other errors are
+// returned unchanged.
+func unsupportedFieldError(name string, err error) error {
+	e, ok := err.(*UnsupportedTypeError)
+	if !ok {
+		return err
+	}
+
+	field := name
+	if e.Field != "" {
+		field += "." + e.Field
+	}
+	return &UnsupportedTypeError{Type: e.Type, Field: field}
+}
+
+// newQuotedIntEncoder wraps the encoder of an integer field tagged with the
+// "string" option so that the value is encoded as a decimal string. RethinkDB
+// stores numbers as 64-bit floats so integers larger than 2^53 cannot
+// otherwise be stored without losing precision.
+func newQuotedIntEncoder(enc encoderFunc) encoderFunc {
+	return func(v reflect.Value) (interface{}, error) {
+		ev, err := enc(v)
+		if err != nil {
+			return nil, err
+		}
+
+		switch n := ev.(type) {
+		case int64:
+			return strconv.FormatInt(n, 10), nil
+		case uint64:
+			return strconv.FormatUint(n, 10), nil
+		default:
+			return ev, nil
+		}
+	}
+}
+
 type mapEncoder struct {
 	keyEnc, elemEnc encoderFunc
 }
 
 func (me *mapEncoder) encode(v reflect.Value) (interface{}, error) {
 	if v.IsNil() {
+		if encodeNilAsEmptyEnabled() {
+			return map[string]interface{}{}, nil
+		}
 		return nil, nil
 	}
 
@@ -301,6 +428,9 @@ type sliceEncoder struct {
 
 func (se *sliceEncoder) encode(v reflect.Value) (interface{}, error) {
 	if v.IsNil() {
+		if encodeNilAsEmptyEnabled() {
+			return []interface{}{}, nil
+		}
 		return []interface{}(nil), nil
 	}
 	return se.arrayEnc(v)
@@ -377,6 +507,20 @@ func newCondAddrEncoder(canAddrEnc, elseEnc encoderFunc) encoderFunc {
 	return enc.encode
 }
 
+// newSQLNullEncoder returns an encoder for the database/sql nullable types
+// which encodes the value when Valid is true and null otherwise.
+func newSQLNullEncoder(t reflect.Type) encoderFunc {
+	valueEnc := typeEncoder(t.Field(0).Type)
+
+	return func(v reflect.Value) (interface{}, error) {
+		if !v.Field(1).Bool() {
+			return nil, nil
+		}
+
+		return valueEnc(v.Field(0))
+	}
+}
+
 // Pseudo-type encoders
 
 // Encode a time.Time value to the TIME RQL type
@@ -1,22 +1,51 @@
 package encoding
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
 	// type constants
-	stringType = reflect.TypeOf("")
-	timeType   = reflect.TypeOf(new(time.Time)).Elem()
+	stringType         = reflect.TypeOf("")
+	timeType           = reflect.TypeOf(new(time.Time)).Elem()
+	interfaceSliceType = reflect.TypeOf([]interface{}(nil))
 
-	marshalerType   = reflect.TypeOf(new(Marshaler)).Elem()
-	unmarshalerType = reflect.TypeOf(new(Unmarshaler)).Elem()
+	marshalerType         = reflect.TypeOf(new(Marshaler)).Elem()
+	unmarshalerType       = reflect.TypeOf(new(Unmarshaler)).Elem()
+	streamUnmarshalerType = reflect.TypeOf(new(StreamUnmarshaler)).Elem()
+	stringerType          = reflect.TypeOf(new(fmt.Stringer)).Elem()
+	rawMessageType        = reflect.TypeOf(json.RawMessage(nil))
 
 	emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
 	mapInterfaceType   = reflect.TypeOf((map[string]interface{})(nil))
+
+	// sqlNullTypes contains the database/sql nullable types, each is a struct
+	// containing the value followed by a Valid field.
+	sqlNullTypes = map[reflect.Type]bool{
+		reflect.TypeOf(sql.NullString{}):  true,
+		reflect.TypeOf(sql.NullInt64{}):   true,
+		reflect.TypeOf(sql.NullInt32{}):   true,
+		reflect.TypeOf(sql.NullFloat64{}): true,
+		reflect.TypeOf(sql.NullBool{}):    true,
+		reflect.TypeOf(sql.NullTime{}):    true,
+	}
 )
 
+// encodeStringers is set to 1 when values implementing fmt.Stringer should
+// be encoded as strings.
+var encodeStringers int32
+
+// encodeNilAsEmpty is set to 1 when nil slices and maps should be encoded as
+// empty arrays and objects.
+var encodeNilAsEmpty int32
+
 // Marshaler is the interface implemented by objects that
 // can marshal themselves into a valid RQL pseudo-type.
 type Marshaler interface {
@@ -29,11 +58,61 @@ type Unmarshaler interface {
 	UnmarshalRQL(interface{}) error
 }
 
+// StreamUnmarshaler is the interface implemented by objects that can
+// unmarshal themselves by reading their raw JSON representation from a
+// stream. This is useful for very large values which should not be fully
+// decoded into memory before being unmarshaled.
+//
+// The raw JSON is only available when decoding a document using
+// Cursor.NextStream, otherwise the already decoded value is encoded as JSON
+// before being passed to UnmarshalRQLStream.
+type StreamUnmarshaler interface {
+	UnmarshalRQLStream(io.Reader) error
+}
+
 func init() {
 	encoderCache.m = make(map[reflect.Type]encoderFunc)
 	decoderCache.m = make(map[decoderCacheKey]decoderFunc)
 }
 
+// SetEncodeStringers controls whether values which implement fmt.Stringer are
+// encoded using their String method. Types which implement Marshaler or have
+// a custom encoding registered using SetTypeEncoding are not affected. The
+// setting applies to all encoding performed by the package and is disabled by
+// default.
+//
+// Decoding is not affected, to decode the encoded strings back into the
+// original type implement Unmarshaler or use SetTypeEncoding.
+func SetEncodeStringers(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&encodeStringers, v)
+}
+
+func encodeStringersEnabled() bool {
+	return atomic.LoadInt32(&encodeStringers) == 1
+}
+
+// SetEncodeNilAsEmpty controls whether nil slices and maps are encoded as
+// empty arrays and objects instead of null. Nil pointers and interfaces are
+// still encoded as null. Individual struct fields can be encoded as empty
+// using the "nilasempty" tag option regardless of this setting. The setting
+// applies to all encoding performed by the package and is disabled by
+// default.
+func SetEncodeNilAsEmpty(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&encodeNilAsEmpty, v)
+}
+
+func encodeNilAsEmptyEnabled() bool {
+	return atomic.LoadInt32(&encodeNilAsEmpty) == 1
+}
+
 // IgnoreType causes the encoder to ignore a type when encoding
 func IgnoreType(t reflect.Type) {
 	encoderCache.Lock()
@@ -41,11 +120,26 @@ func IgnoreType(t reflect.Type) {
 	encoderCache.Unlock()
 }
 
+// typeEncodings contains the types with a decoder registered using
+// SetTypeEncoding, slices of these types are not decoded by the
+// scalarSliceDecoder fast path.
+var typeEncodings sync.Map // reflect.Type -> struct{}
+
+func hasTypeEncoding(t reflect.Type) bool {
+	_, ok := typeEncodings.Load(t)
+	return ok
+}
+
 func SetTypeEncoding(
 	t reflect.Type,
 	encode func(value interface{}) (interface{}, error),
 	decode func(encoded interface{}, value reflect.Value) error,
 ) {
+	typeEncodings.Store(t, struct{}{})
+	if t.Kind() == reflect.Ptr {
+		typeEncodings.Store(t.Elem(), struct{}{})
+	}
+
 	encoderCache.Lock()
 	encoderCache.m[t] = func(v reflect.Value) (interface{}, error) {
 		return encode(v.Interface())
@@ -62,8 +156,107 @@ func SetTypeEncoding(
 	decoderCache.m[decoderCacheKey{dt: t, st: mapInterfaceType}] = dec
 
 	if t.Kind() == reflect.Ptr {
-		decoderCache.m[decoderCacheKey{dt: t.Elem(), st: emptyInterfaceType}] = dec
-		decoderCache.m[decoderCacheKey{dt: t.Elem(), st: mapInterfaceType}] = dec
+		// decode into the value pointed to, as when decoding into a pointer
+		// which has already been allocated
+		elemDec := func(dv reflect.Value, sv reflect.Value) error {
+			ptr := reflect.New(t).Elem()
+			if err := decode(sv.Interface(), ptr); err != nil {
+				return err
+			}
+			if !ptr.IsNil() {
+				dv.Set(ptr.Elem())
+			}
+			return nil
+		}
+		decoderCache.m[decoderCacheKey{dt: t.Elem(), st: emptyInterfaceType}] = elemDec
+		decoderCache.m[decoderCacheKey{dt: t.Elem(), st: mapInterfaceType}] = elemDec
 	}
 	decoderCache.Unlock()
 }
+
+// typeResolver picks the concrete type of a document using the value of its
+// discriminator field.
+type typeResolver struct {
+	field   string
+	resolve func(discriminator string) reflect.Type
+}
+
+var typeResolvers struct {
+	sync.Mutex
+	v atomic.Value // []typeResolver
+}
+
+// RegisterTypeResolver registers a function which picks the concrete type used
+// when decoding a document into an interface value, such as an interface{}
+// struct field. If the document contains field and its value is a string then
+// resolve is called with the value, the document is then decoded into a new
+// value of the returned type. Return a pointer type to decode into a pointer.
+// If resolve returns nil, or the field is missing, the document is decoded as
+// usual. Values which already have the type being decoded into, such as a
+// []interface{} decoded into a []interface{} field, are assigned without
+// resolving the documents they contain.
+//
+// Registering a resolver for a field which already has one replaces it. When
+// multiple resolvers are registered they are checked in the order they were
+// registered.
+//
+//	encoding.RegisterTypeResolver("type", func(t string) reflect.Type {
+//		switch t {
+//		case "cat":
+//			return reflect.TypeOf(Cat{})
+//		case "dog":
+//			return reflect.TypeOf(Dog{})
+//		}
+//		return nil
+//	})
+func RegisterTypeResolver(field string, resolve func(discriminator string) reflect.Type) {
+	typeResolvers.Lock()
+	defer typeResolvers.Unlock()
+
+	old, _ := typeResolvers.v.Load().([]typeResolver)
+	resolvers := make([]typeResolver, 0, len(old)+1)
+	replaced := false
+	for _, r := range old {
+		if r.field == field {
+			r.resolve = resolve
+			replaced = true
+		}
+		resolvers = append(resolvers, r)
+	}
+	if !replaced {
+		resolvers = append(resolvers, typeResolver{field: field, resolve: resolve})
+	}
+	typeResolvers.v.Store(resolvers)
+}
+
+// resolveType returns the type registered for the document in sv, or nil if
+// no resolver matches it.
+func resolveType(sv reflect.Value) reflect.Type {
+	resolvers, _ := typeResolvers.v.Load().([]typeResolver)
+	if len(resolvers) == 0 {
+		return nil
+	}
+
+	for _, r := range resolvers {
+		key := reflect.ValueOf(r.field)
+		if !key.Type().AssignableTo(sv.Type().Key()) {
+			if !key.Type().ConvertibleTo(sv.Type().Key()) {
+				return nil
+			}
+			key = key.Convert(sv.Type().Key())
+		}
+
+		v := sv.MapIndex(key)
+		if v.IsValid() && v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if !v.IsValid() || v.Kind() != reflect.String {
+			continue
+		}
+		if t := r.resolve(v.String()); t != nil {
+			return t
+		}
+	}
+
+	return nil
+}
@@ -46,12 +46,19 @@ func (e *DecodeTypeError) Error() string {
 }
 
 // An UnsupportedTypeError is returned by Marshal when attempting
-// to encode an unsupported value type.
+// to encode an unsupported value type, such as a channel, a complex number
+// or a function stored in a struct field. Field is the path of the struct
+// field containing the value, for example "Config.Callback", and is empty if
+// the value is not stored in a struct.
 type UnsupportedTypeError struct {
-	Type reflect.Type
+	Type  reflect.Type
+	Field string
 }
 
 func (e *UnsupportedTypeError) Error() string {
+	if e.Field != "" {
+		return "rethinkdb: unsupported type: " + e.Type.String() + " (" + e.Type.Kind().String() + " field " + e.Field + ")"
+	}
 	return "rethinkdb: unsupported type: " + e.Type.String()
 }
 
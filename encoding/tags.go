@@ -112,3 +112,22 @@ func (o tagOptions) Contains(optionName string) bool {
 	}
 	return false
 }
+
+// Value returns the value of an option of the form "name=value" in a
+// comma-separated list of options, or an empty string if the option is not
+// set.
+func (o tagOptions) Value(optionName string) string {
+	s := string(o)
+	for s != "" {
+		var next string
+		i := strings.Index(s, ",")
+		if i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if strings.HasPrefix(s, optionName+"=") {
+			return s[len(optionName)+1:]
+		}
+		s = next
+	}
+	return ""
+}
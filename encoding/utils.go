@@ -31,6 +31,10 @@ func isEmptyValue(v reflect.Value) bool {
 		return v.Float() == 0
 	case reflect.Interface, reflect.Ptr:
 		return v.IsNil()
+	case reflect.Struct:
+		if sqlNullTypes[v.Type()] {
+			return !v.Field(1).Bool()
+		}
 	}
 	return false
 }
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"regexp"
 	"strings"
 
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
@@ -27,6 +30,27 @@ var (
 	ErrConnectionClosed = errors.New("rethinkdb: the connection is closed")
 	// ErrQueryTimeout is returned when query context deadline exceeded.
 	ErrQueryTimeout = errors.New("rethinkdb: query timeout")
+	// ErrCursorClosed is returned when reading from a cursor which was closed
+	// while it was being read.
+	ErrCursorClosed = errors.New("rethinkdb: the cursor is closed")
+	// ErrCircuitOpen is returned when a query is not sent as the circuit
+	// breaker is open after repeated connection failures.
+	ErrCircuitOpen = errors.New("rethinkdb: circuit breaker is open")
+	// ErrAuthFailed is matched (using errors.Is) by the errors returned when
+	// the server rejects the credentials used to connect, unlike connection
+	// errors retrying these will not succeed.
+	ErrAuthFailed = errors.New("rethinkdb: authentication failed")
+	// ErrNonExistence is matched (using errors.Is) by the errors returned when
+	// a query accesses a value which does not exist, for example when calling
+	// Nth with an out of bounds index or getting a missing field.
+	ErrNonExistence = errors.New("rethinkdb: non-existence error")
+	// ErrResponseTooLarge is returned when a response to a query is larger
+	// than the limit set by RunOpts.MaxResponseBytes.
+	ErrResponseTooLarge = errors.New("rethinkdb: response exceeds the maximum size")
+	// ErrTooManyInFlightQueries is returned when a query is not sent as
+	// ConnectOpts.MaxInFlightQueries queries were still waiting for a response
+	// after ConnectOpts.MaxInFlightWait.
+	ErrTooManyInFlightQueries = errors.New("rethinkdb: too many queries in flight")
 )
 
 func printCarrots(t Term, frames []*p.Frame) string {
@@ -77,14 +101,17 @@ var ErrEmptyResult = errors.New("The result does not contain any more rows")
 type rqlServerError struct {
 	response *Response
 	term     *Term
+	db       string
 }
 
 func (e rqlServerError) Error() string {
-	var err = "An error occurred"
-	if e.response != nil {
-		json.Unmarshal(e.response.Responses[0], &err)
+	err := e.message()
+	if e.db != "" {
+		err = fmt.Sprintf("%s (default database: %s)", err, e.db)
+	}
+	if id := e.QueryID(); id != "" {
+		err = fmt.Sprintf("%s (query id: %s)", err, id)
 	}
-
 	if e.term == nil {
 		return fmt.Sprintf("rethinkdb: %s", err)
 	}
@@ -93,10 +120,97 @@ func (e rqlServerError) Error() string {
 
 }
 
+// Database returns the default database the query which caused the error was
+// run against, either inherited from the session or set using RunOpts.DB. An
+// empty string is returned if the query had no default database.
+func (e rqlServerError) Database() string {
+	return e.db
+}
+
+// QueryID returns the ID generated by ConnectOpts.QueryIDGenerator for the
+// query which caused the error, or an empty string if no ID was generated.
+func (e rqlServerError) QueryID() string {
+	if e.term == nil {
+		return ""
+	}
+	return e.term.queryID
+}
+
+// BacktraceFrame is one step of the path from a query to the sub-term which
+// caused an error, see Backtrace.
+type BacktraceFrame struct {
+	// Pos is the position of the sub-term in the arguments of its parent,
+	// it is only used if Opt is empty.
+	Pos int
+	// Opt is the name of the optional argument of the parent which contains
+	// the sub-term.
+	Opt string
+}
+
+// Backtrace returns the path from the query which caused the error to the
+// failing sub-term, as reported by the server. It returns nil if the server
+// did not send a backtrace, FailedTerm returns the sub-term itself.
+func (e rqlServerError) Backtrace() []BacktraceFrame {
+	if e.response == nil || len(e.response.Backtrace) == 0 {
+		return nil
+	}
+
+	frames := make([]BacktraceFrame, 0, len(e.response.Backtrace))
+	for _, frame := range e.response.Backtrace {
+		switch frame := frame.(type) {
+		case float64:
+			frames = append(frames, BacktraceFrame{Pos: int(frame)})
+		case string:
+			frames = append(frames, BacktraceFrame{Opt: frame})
+		default:
+			return nil
+		}
+	}
+	return frames
+}
+
+// FailedTerm returns the sub-term of the query which caused the error, found
+// by following Backtrace from the query. It returns nil if the error has no
+// backtrace or the backtrace does not match the query, in which case the
+// whole query may have failed.
+func (e rqlServerError) FailedTerm() *Term {
+	frames := e.Backtrace()
+	if e.term == nil || len(frames) == 0 {
+		return nil
+	}
+
+	t := *e.term
+	for _, frame := range frames {
+		if frame.Opt != "" {
+			arg, ok := t.optArgs[frame.Opt]
+			if !ok {
+				return nil
+			}
+			t = arg
+		} else {
+			if frame.Pos < 0 || frame.Pos >= len(t.args) {
+				return nil
+			}
+			t = t.args[frame.Pos]
+		}
+	}
+	return &t
+}
+
 func (e rqlServerError) String() string {
 	return e.Error()
 }
 
+// message returns the error message sent by the server.
+func (e rqlServerError) message() string {
+	var msg = "An error occurred"
+	if e.response != nil {
+		json.Unmarshal(e.response.Responses[0], &msg)
+	}
+
+	return msg
+}
+
 type rqlError string
 
 func (e rqlError) Error() string {
@@ -138,16 +252,116 @@ type RQLConnectionError struct {
 	rqlError
 }
 
-func createClientError(response *Response, term *Term) error {
-	return RQLClientError{rqlServerError{response, term}}
+// Is allows RQLAuthError to be matched with ErrAuthFailed using errors.Is.
+func (e RQLAuthError) Is(target error) bool {
+	return target == ErrAuthFailed
 }
 
-func createCompileError(response *Response, term *Term) error {
-	return RQLCompileError{rqlServerError{response, term}}
+// Message returns the message passed to the Error term which raised the error,
+// without the query which caused it.
+func (e RQLUserError) Message() string {
+	return e.message()
 }
 
-func createRuntimeError(errorType p.Response_ErrorType, response *Response, term *Term) error {
-	serverErr := rqlServerError{response, term}
+// RQLWriteError is returned by RunWrite when the write query succeeded but
+// one or more documents could not be written, for example because an Error
+// term was called by the function passed to Update or by a write run by
+// ForEach. FirstError contains the message of the first error, which is the
+// message passed to Error when the error was raised using the Error term.
+//
+// When the first error is caused by a duplicate primary key DuplicateKey
+// contains the name of the key. When RunOpts.CollectErrors is set every
+// failed write reported in the changes of the response is returned by the
+// WriteErrors method.
+type RQLWriteError struct {
+	FirstError   string
+	Errors       int
+	DuplicateKey string
+
+	// writeErrors is a pointer so RQLWriteError remains comparable.
+	writeErrors *[]WriteError
+}
+
+func (e RQLWriteError) Error() string {
+	return e.FirstError
+}
+
+// WriteErrors returns the failed writes reported in the changes of the write
+// response, it is only populated when RunOpts.CollectErrors is set.
+func (e RQLWriteError) WriteErrors() []WriteError {
+	if e.writeErrors == nil {
+		return nil
+	}
+	return *e.writeErrors
+}
+
+// WriteError describes a single document which could not be written, see
+// RQLWriteError.WriteErrors. OldValue and NewValue contain the document
+// before the write and the document which could not be written, when they
+// are reported by the server.
+type WriteError struct {
+	Message  string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+func (e WriteError) Error() string {
+	return e.Message
+}
+
+var duplicateKeyRegexp = regexp.MustCompile("^Duplicate primary key `([^`]*)`")
+
+// newWriteError creates an RQLWriteError from a write response which
+// contains errors, if collect is set the failed writes are read from the
+// changes of the response.
+func newWriteError(response WriteResponse, collect bool) RQLWriteError {
+	err := RQLWriteError{FirstError: response.FirstError, Errors: response.Errors}
+	if m := duplicateKeyRegexp.FindStringSubmatch(response.FirstError); m != nil {
+		err.DuplicateKey = m[1]
+	}
+	if collect {
+		var writeErrors []WriteError
+		for _, change := range response.Changes {
+			if change.Error == "" {
+				continue
+			}
+			writeErrors = append(writeErrors, WriteError{
+				Message:  change.Error,
+				OldValue: change.OldValue,
+				NewValue: change.NewValue,
+			})
+		}
+		err.writeErrors = &writeErrors
+	}
+
+	return err
+}
+
+// Is allows RQLNonExistenceError to be matched with ErrNonExistence using
+// errors.Is.
+func (e RQLNonExistenceError) Is(target error) bool {
+	return target == ErrNonExistence
+}
+
+// newNonExistenceError creates an RQLNonExistenceError for a non-existence
+// error detected by the driver, which matches the errors returned by the
+// server when accessing missing values.
+func newNonExistenceError(msg string) error {
+	b, _ := json.Marshal(msg)
+	response := &Response{Responses: []json.RawMessage{b}}
+	return RQLNonExistenceError{RQLQueryLogicError{RQLRuntimeError{rqlServerError{response: response}}}}
+}
+
+func createClientError(response *Response, term *Term, db string) error {
+	return RQLClientError{rqlServerError{response, term, db}}
+}
+
+func createCompileError(response *Response, term *Term, db string) error {
+	return RQLCompileError{rqlServerError{response, term, db}}
+}
+
+func createRuntimeError(errorType p.Response_ErrorType, response *Response, term *Term, db string) error {
+	serverErr := rqlServerError{response, term, db}
 
 	switch errorType {
 	case p.Response_QUERY_LOGIC:
@@ -190,3 +404,17 @@ func IsTypeErr(err error) bool {
 
 	return strings.HasPrefix(err.Error(), "Expected type")
 }
+
+// isConnectionError returns true if err was caused by the connection to the
+// database being lost or not being available.
+func isConnectionError(err error) bool {
+	switch err.(type) {
+	case RQLConnectionError:
+		return true
+	case net.Error:
+		return true
+	}
+
+	return err == ErrConnectionClosed || err == io.EOF || err == io.ErrUnexpectedEOF ||
+		err == ErrNoConnections || err == ErrNoConnectionsStarted
+}
@@ -0,0 +1,52 @@
+package rethinkdb
+
+import "time"
+
+// eventBufferSize is the number of events buffered by the channel returned by
+// Session.Events, once full further events are dropped.
+const eventBufferSize = 64
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	// EventConnectionLost is sent when a query fails because the connection
+	// to a host was lost or could not be established.
+	EventConnectionLost EventType = "connection_lost"
+	// EventHostDiscovered is sent when host discovery connects to a new node
+	// in the cluster, see ConnectOpts.DiscoverHosts.
+	EventHostDiscovered EventType = "host_discovered"
+	// EventRetryPerformed is sent when a query is retried after failing due
+	// to a connection error, see ConnectOpts.NumRetries.
+	EventRetryPerformed EventType = "retry_performed"
+)
+
+// Event describes something which happened to the connections of a session,
+// events can be received using Session.Events.
+type Event struct {
+	Type EventType
+	// Host is the address of the host the event relates to.
+	Host string
+	// Err is the error which caused the event, if any.
+	Err error
+	// Time is when the event occurred.
+	Time time.Time
+}
+
+// newEventChan returns the channel used to send the events of a session.
+func newEventChan() chan Event {
+	return make(chan Event, eventBufferSize)
+}
+
+// sendEvent sends the event without blocking, the event is dropped if ch is
+// nil or its buffer is full.
+func sendEvent(ch chan Event, typ EventType, host string, err error) {
+	if ch == nil {
+		return
+	}
+
+	select {
+	case ch <- Event{Type: typ, Host: host, Err: err, Time: time.Now()}:
+	default:
+	}
+}
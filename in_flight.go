@@ -0,0 +1,114 @@
+package rethinkdb
+
+import (
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// inFlightQueries counts the queries of a session which are waiting for a
+// response and limits them to ConnectOpts.MaxInFlightQueries. A nil
+// inFlightQueries allows all queries.
+type inFlightQueries struct {
+	count int64
+	slots chan struct{}
+	wait  time.Duration
+
+	// timeout is the timeout of the connection options which applies to
+	// queries run without a context, if there is none they wait for at most
+	// unboundedWait even if wait is zero.
+	timeout       time.Duration
+	unboundedWait time.Duration
+}
+
+// defaultInFlightWait is the longest a query run without a context or
+// connection timeouts waits for another query to finish.
+const defaultInFlightWait = time.Minute
+
+func newInFlightQueries(opts *ConnectOpts) *inFlightQueries {
+	q := &inFlightQueries{
+		wait:          opts.MaxInFlightWait,
+		timeout:       connectionOptsTimeout(opts),
+		unboundedWait: defaultInFlightWait,
+	}
+	if opts.MaxInFlightQueries > 0 {
+		q.slots = make(chan struct{}, opts.MaxInFlightQueries)
+	}
+	return q
+}
+
+// acquire waits until another query can be sent. It returns ErrQueryTimeout if
+// ctx is done first, or ErrTooManyInFlightQueries once the configured wait has
+// passed. Each successful call must be followed by a call to release.
+func (q *inFlightQueries) acquire(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+	if q.slots != nil {
+		select {
+		case q.slots <- struct{}{}:
+		default:
+			if err := q.waitForSlot(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	atomic.AddInt64(&q.count, 1)
+	return nil
+}
+
+func (q *inFlightQueries) waitForSlot(ctx context.Context) error {
+	wait := q.wait
+	if ctx == nil {
+		// Like the connection use the timeout of the connection options, so
+		// that queries run without a context cannot block forever
+		if q.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(context.Background(), q.timeout)
+			defer cancel()
+		} else if wait <= 0 {
+			wait = q.unboundedWait
+		}
+	}
+
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	case <-done:
+		return ErrQueryTimeout
+	case <-timeout:
+		return ErrTooManyInFlightQueries
+	}
+}
+
+// release marks a query acquired using acquire as no longer in-flight.
+func (q *inFlightQueries) release() {
+	if q == nil {
+		return
+	}
+	atomic.AddInt64(&q.count, -1)
+	if q.slots != nil {
+		<-q.slots
+	}
+}
+
+// inFlight returns the number of queries currently waiting for a response.
+func (q *inFlightQueries) inFlight() int {
+	if q == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&q.count))
+}
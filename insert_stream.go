@@ -0,0 +1,82 @@
+package rethinkdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// defaultInsertStreamBatchSize is the number of documents inserted by each
+// query of InsertStream if InsertStreamOpts.BatchSize is not set.
+const defaultInsertStreamBatchSize = 200
+
+// InsertStreamOpts contains the optional arguments for InsertStream.
+type InsertStreamOpts struct {
+	// BatchSize is the number of documents inserted by each query, defaults
+	// to 200.
+	BatchSize int
+	// InsertOpts are the options of each insert query.
+	InsertOpts InsertOpts
+	// RunOpts are the options used to run each insert query.
+	RunOpts RunOpts
+}
+
+// InsertStream inserts the newline-delimited JSON objects read from r into
+// table, in batches of InsertStreamOpts.BatchSize documents. The documents
+// are not decoded by the driver, each line is only checked to be a valid JSON
+// object and is parsed by the server using JSON. Blank lines are skipped.
+//
+// The responses of each batch are combined into the returned WriteResponse.
+// Inserting stops at the first batch which fails, the returned response then
+// contains the documents written so far. For example:
+//
+//	f, err := os.Open("users.ndjson")
+//	// ...
+//	res, err := r.InsertStream(session, r.Table("users"), f, r.InsertStreamOpts{
+//		InsertOpts: r.InsertOpts{Conflict: "replace"},
+//	})
+func InsertStream(s QueryExecutor, table Term, r io.Reader, optArgs ...InsertStreamOpts) (WriteResponse, error) {
+	var opts InsertStreamOpts
+	if len(optArgs) >= 1 {
+		opts = optArgs[0]
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultInsertStreamBatchSize
+	}
+
+	var response WriteResponse
+	insert := func(docs []interface{}) error {
+		res, err := table.Insert(docs, opts.InsertOpts).RunWrite(s, opts.RunOpts)
+		addInsertResponse(&response, res)
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	docs := make([]interface{}, 0, batchSize)
+	for lineNum := 1; ; lineNum++ {
+		line, readErr := br.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return response, readErr
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if line[0] != '{' || !json.Valid(line) {
+				return response, RQLDriverError{rqlError(fmt.Sprintf("InsertStream: line %d is not a valid JSON object", lineNum))}
+			}
+			docs = append(docs, JSON(string(line)))
+		}
+
+		if len(docs) == batchSize || (readErr == io.EOF && len(docs) > 0) {
+			if err := insert(docs); err != nil {
+				return response, err
+			}
+			docs = docs[:0]
+		}
+		if readErr == io.EOF {
+			return response, nil
+		}
+	}
+}
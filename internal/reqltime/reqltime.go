@@ -0,0 +1,58 @@
+// Package reqltime converts RethinkDB TIME pseudo-types to native times, it
+// is shared by the driver and the encoding package so both decode times the
+// same way.
+package reqltime
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ToTime converts a TIME pseudo-type object to a time.Time, rounded to
+// milliseconds. The time has no monotonic clock reading and is in UTC if the
+// object has no timezone or a zero offset. The same location is used for
+// every time with a timezone, so equal times are converted to identical
+// time.Time values which can be compared using ==.
+func ToTime(obj map[string]interface{}) (time.Time, error) {
+	epoch, ok := obj["epoch_time"].(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("pseudo-type TIME object %v does not have a numeric epoch_time", obj)
+	}
+
+	sec, ms := math.Modf(epoch)
+	t := time.Unix(int64(sec), int64(math.Floor(ms*1000+0.5))*int64(time.Millisecond))
+
+	tz, _ := obj["timezone"].(string)
+	loc, err := location(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.In(loc), nil
+}
+
+// timezones caches the locations returned by location.
+var timezones sync.Map // timezone -> *time.Location
+
+// location returns the location of a timezone offset such as "-07:00".
+func location(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := timezones.Load(tz); ok {
+		return loc.(*time.Location), nil
+	}
+
+	zone, err := time.Parse("-07:00", tz)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	if _, offset := zone.Zone(); offset != 0 {
+		loc = time.FixedZone(tz, offset)
+	}
+	actual, _ := timezones.LoadOrStore(tz, loc)
+	return actual.(*time.Location), nil
+}
@@ -0,0 +1,133 @@
+package rethinkdb
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// Job describes a job running on the cluster as listed in the jobs system
+// table, for example a query, an index being built or a backfill.
+type Job struct {
+	// ID identifies the job, it is passed to KillJob to stop the job.
+	ID []string
+	// Type is the type of the job, for example "query", "disk_compaction",
+	// "index_construction" or "backfill".
+	Type string
+	// Duration is how long the job has been running for.
+	Duration time.Duration
+	// StartedAt is the approximate time the job started, calculated by the
+	// database using its current time and the duration of the job.
+	StartedAt time.Time
+	// Servers contains the names of the servers running the job.
+	Servers []string
+	// Info contains information which depends on the type of the job, such
+	// as the query being run and the address of the client for query jobs.
+	Info map[string]interface{}
+	// Application is the ConnectOpts.ApplicationName of the session for
+	// query jobs sent using its connections, otherwise it is empty.
+	Application string
+}
+
+type jobResponse struct {
+	ID          []string               `rethinkdb:"id"`
+	Type        string                 `rethinkdb:"type"`
+	DurationSec float64                `rethinkdb:"duration_sec"`
+	StartedAt   time.Time              `rethinkdb:"started_at"`
+	Servers     []string               `rethinkdb:"servers"`
+	Info        map[string]interface{} `rethinkdb:"info"`
+}
+
+// RunningJobs returns the jobs currently running on the cluster, the user the
+// session is connected as must have read permissions on the jobs system table.
+func (s *Session) RunningJobs() ([]Job, error) {
+	jobs, err := runningJobs(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.opts.ApplicationName != "" {
+		labelJobs(jobs, s.opts.ApplicationName, s.localAddresses())
+	}
+	return jobs, nil
+}
+
+// KillJob stops the job with the given ID, jobs can be listed using
+// RunningJobs. Only query and disk compaction jobs can be stopped.
+func (s *Session) KillJob(id []string) error {
+	return killJob(s, id)
+}
+
+func jobsTable() Term {
+	return DB(SystemDatabase).Table(JobsSystemTable)
+}
+
+func runningJobs(s QueryExecutor) ([]Job, error) {
+	var responses []jobResponse
+	err := jobsTable().Merge(func(job Term) Term {
+		return Expr(map[string]interface{}{
+			"started_at": Now().Sub(job.Field("duration_sec")),
+		})
+	}).ReadAll(&responses, s)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, len(responses))
+	for i, r := range responses {
+		jobs[i] = Job{
+			ID:        r.ID,
+			Type:      r.Type,
+			Duration:  time.Duration(r.DurationSec * float64(time.Second)),
+			StartedAt: r.StartedAt,
+			Servers:   r.Servers,
+			Info:      r.Info,
+		}
+	}
+
+	return jobs, nil
+}
+
+// labelJobs sets the application of the query jobs sent by a client whose
+// address is in addrs.
+func labelJobs(jobs []Job, application string, addrs map[string]bool) {
+	for i, job := range jobs {
+		if job.Type != "query" {
+			continue
+		}
+		host, ok := job.Info["client_address"].(string)
+		if !ok {
+			continue
+		}
+		port, ok := job.Info["client_port"].(float64)
+		if !ok {
+			continue
+		}
+		if addrs[net.JoinHostPort(host, strconv.Itoa(int(port)))] {
+			jobs[i].Application = application
+		}
+	}
+}
+
+// localAddresses returns the local addresses of the open connections of the
+// session.
+func (s *Session) localAddresses() map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	addrs := map[string]bool{}
+	if s.closed || s.cluster == nil {
+		return addrs
+	}
+	for _, node := range s.cluster.GetNodes() {
+		for _, addr := range node.pool.localAddrs() {
+			addrs[addr] = true
+		}
+	}
+	return addrs
+}
+
+func killJob(s QueryExecutor, id []string) error {
+	_, err := jobsTable().Get(id).Delete().RunWrite(s)
+	return err
+}
@@ -0,0 +1,108 @@
+package rethinkdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+)
+
+// LazyMap is a document whose fields are decoded when they are read using Get
+// rather than when the document is decoded. This is useful when only a few
+// fields of large documents are used, for example:
+//
+//	var doc r.LazyMap
+//	for cursor.NextStream(&doc) {
+//		var name string
+//		err := doc.Get("name", &name)
+//	}
+//
+// The raw JSON of each field is only retained when the document is read using
+// Cursor.NextStream, otherwise the document is fully decoded as usual and Get
+// only converts the already decoded value.
+//
+// Fields are decoded using the run options of the query and
+// ConnectOpts.UseJSONNumber, so Get returns the same values as when the
+// document is read using Next.
+type LazyMap struct {
+	raw    map[string]json.RawMessage
+	values map[string]interface{}
+
+	// opts and useNumber are set by the cursor which read the document.
+	opts      map[string]interface{}
+	useNumber bool
+}
+
+// setDecodeOptions implements decodeOptionsSetter.
+func (m *LazyMap) setDecodeOptions(opts map[string]interface{}, useNumber bool) {
+	m.opts, m.useNumber = opts, useNumber
+}
+
+// UnmarshalRQLStream implements encoding.StreamUnmarshaler by retaining the
+// raw JSON of each field of the document.
+func (m *LazyMap) UnmarshalRQLStream(r io.Reader) error {
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+
+	m.raw, m.values = raw, nil
+	return nil
+}
+
+// UnmarshalRQL implements encoding.Unmarshaler, it is used when the raw JSON of
+// the document is not available.
+func (m *LazyMap) UnmarshalRQL(v interface{}) error {
+	values, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		return fmt.Errorf("cannot decode %T into LazyMap", v)
+	}
+
+	m.raw, m.values = nil, values
+	return nil
+}
+
+// Has returns true if the document contains the field.
+func (m LazyMap) Has(field string) bool {
+	if _, ok := m.raw[field]; ok {
+		return true
+	}
+	_, ok := m.values[field]
+	return ok
+}
+
+// Keys returns the sorted names of the fields of the document.
+func (m LazyMap) Keys() []string {
+	keys := make([]string, 0, len(m.raw)+len(m.values))
+	for k := range m.raw {
+		keys = append(keys, k)
+	}
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Get decodes the value of the field into dest. If the document does not
+// contain the field then an error matching ErrNonExistence is returned.
+func (m LazyMap) Get(field string, dest interface{}) error {
+	if v, ok := m.values[field]; ok {
+		return encoding.Decode(dest, v)
+	}
+
+	raw, ok := m.raw[field]
+	if !ok {
+		return newNonExistenceError(fmt.Sprintf("No attribute `%s`", field))
+	}
+
+	v, err := decodeResponse(raw, m.opts, m.useNumber)
+	if err != nil {
+		return err
+	}
+
+	return encoding.Decode(dest, v)
+}
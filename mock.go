@@ -7,6 +7,7 @@ import (
 	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
 	"net"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -198,6 +199,8 @@ type Mock struct {
 
 	ExpectedQueries []*MockQuery
 	Queries         []MockQuery
+
+	uuids []string
 }
 
 // NewMock creates an instance of Mock, you can optionally pass ConnectOpts to
@@ -233,6 +236,65 @@ func (m *Mock) On(t Term, opts ...map[string]interface{}) *MockQuery {
 	return mq
 }
 
+// StubUUIDs sets values which are used in place of the UUID terms (without
+// arguments) of executed queries, this allows queries which generate UUIDs
+// to be mocked with known values. Each value is used once and in the order
+// given, UUID terms are replaced in the order they appear in a query. Once all
+// values are used UUID terms are no longer replaced.
+//
+//     mock.StubUUIDs("uuid-1")
+//     mock.On(r.Table("test").Insert(map[string]interface{}{"id": "uuid-1"})).Return(nil, nil)
+//
+//     r.Table("test").Insert(map[string]interface{}{"id": r.UUID()}).Exec(mock)
+//
+// Queries consisting of only a UUID term return the stubbed value if no
+// expectation matches the query.
+func (m *Mock) StubUUIDs(uuids ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.uuids = append(m.uuids, uuids...)
+}
+
+// replaceUUIDs returns a copy of t with any UUID terms replaced by the stubbed
+// UUIDs. m.mu must be held.
+func (m *Mock) replaceUUIDs(t Term) Term {
+	if len(m.uuids) == 0 {
+		return t
+	}
+
+	if t.termType == p.Term_UUID && len(t.args) == 0 {
+		var uuid string
+		uuid, m.uuids = m.uuids[0], m.uuids[1:]
+		return Expr(uuid)
+	}
+
+	if len(t.args) > 0 {
+		args := make(termsList, len(t.args))
+		for i, arg := range t.args {
+			args[i] = m.replaceUUIDs(arg)
+		}
+		t.args = args
+	}
+
+	if len(t.optArgs) > 0 {
+		// Replace in key order so the stubbed values are used deterministically
+		keys := make([]string, 0, len(t.optArgs))
+		for k := range t.optArgs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		optArgs := make(termsObj, len(t.optArgs))
+		for _, k := range keys {
+			optArgs[k] = m.replaceUUIDs(t.optArgs[k])
+		}
+		t.optArgs = optArgs
+	}
+
+	return t
+}
+
 // AssertExpectations asserts that everything specified with On and Return was
 // in fact executed as expected. Queries may have been executed in any order.
 func (m *Mock) AssertExpectations(t testingT) bool {
@@ -269,7 +331,7 @@ func (m *Mock) AssertExpectations(t testingT) bool {
 func (m *Mock) AssertNumberOfExecutions(t testingT, expectedQuery *MockQuery, expectedExecutions int) bool {
 	var actualExecutions int
 	for _, query := range m.queries() {
-		if query.Query.Term.compare(*expectedQuery.Query.Term, map[int64]int64{}) && query.Repeatability > -1 {
+		if query.Query.Term.compare(*expectedQuery.Query.Term, map[int64]int64{}, true) && query.Repeatability > -1 {
 			// if bytes.Equal(query.BuiltQuery, expectedQuery.BuiltQuery) {
 			actualExecutions++
 		}
@@ -308,9 +370,25 @@ func (m *Mock) IsConnected() bool {
 }
 
 func (m *Mock) Query(ctx context.Context, q Query) (*Cursor, error) {
+	var stubbedUUID interface{}
+	if q.Term != nil {
+		m.mu.Lock()
+		isUUID := q.Term.termType == p.Term_UUID && len(q.Term.args) == 0 && len(m.uuids) > 0
+		t := m.replaceUUIDs(*q.Term)
+		m.mu.Unlock()
+
+		q.Term = &t
+		if isUUID {
+			stubbedUUID = t.data
+		}
+	}
+
 	found, query := m.findExpectedQuery(q)
 
-	if found < 0 {
+	if found < 0 && stubbedUUID != nil {
+		query = newMockQuery(m, q)
+		query.Response = stubbedUUID
+	} else if found < 0 {
 		panic(fmt.Sprintf("rethinkdb: mock: This query was unexpected:\n\t\t%s", q.Term.String()))
 	} else {
 		m.mu.Lock()
@@ -391,7 +469,7 @@ func (m *Mock) findExpectedQuery(q Query) (int, *MockQuery) {
 
 	for i, query := range m.ExpectedQueries {
 		// if bytes.Equal(query.BuiltQuery, builtQuery) && query.Repeatability > -1 {
-		if query.Query.Term.compare(*q.Term, map[int64]int64{}) && query.Repeatability > -1 {
+		if query.Query.Term.compare(*q.Term, map[int64]int64{}, true) && query.Repeatability > -1 {
 			return i, query
 		}
 	}
@@ -401,7 +479,7 @@ func (m *Mock) findExpectedQuery(q Query) (int, *MockQuery) {
 
 func (m *Mock) queryWasExecuted(expectedQuery *MockQuery) bool {
 	for _, query := range m.queries() {
-		if query.Query.Term.compare(*expectedQuery.Query.Term, map[int64]int64{}) {
+		if query.Query.Term.compare(*expectedQuery.Query.Term, map[int64]int64{}, true) {
 			// if bytes.Equal(query.BuiltQuery, expectedQuery.BuiltQuery) {
 			return true
 		}
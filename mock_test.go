@@ -433,3 +433,56 @@ func (t *simpleTestingT) FailNow() {
 func (t *simpleTestingT) Failed() bool {
 	return t.failed
 }
+
+func (s *MockSuite) TestMockStubUUIDs(c *test.C) {
+	mock := NewMock()
+	mock.StubUUIDs("uuid-1", "uuid-2")
+	mock.On(DB("test").Table("test").Insert(map[string]interface{}{
+		"id":    "uuid-1",
+		"other": "uuid-2",
+	})).Return(nil, nil)
+
+	err := DB("test").Table("test").Insert(map[string]interface{}{
+		"id":    UUID(),
+		"other": UUID(),
+	}).Exec(mock)
+	c.Assert(err, test.IsNil)
+	mock.AssertExpectations(c)
+}
+
+func (s *MockSuite) TestMockStubUUIDsRootTerm(c *test.C) {
+	mock := NewMock()
+	mock.StubUUIDs("uuid-1")
+
+	var uuid string
+	err := UUID().ReadOne(&uuid, mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(uuid, test.Equals, "uuid-1")
+
+	// Once the stubbed values are used UUID terms are no longer replaced
+	mock.On(UUID()).Return("uuid-2", nil)
+	err = UUID().ReadOne(&uuid, mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(uuid, test.Equals, "uuid-2")
+	mock.AssertExpectations(c)
+}
+
+func (s *MockSuite) TestMockRunSample(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Sample(2)).Return([]interface{}{
+		map[string]interface{}{"id": "b"},
+		map[string]interface{}{"id": "a"},
+	}, nil)
+
+	res, err := Table("test").Sample(2).Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var response []interface{}
+	err = res.All(&response)
+	c.Assert(err, test.IsNil)
+	c.Assert(response, tests.JsonEquals, []interface{}{
+		map[string]interface{}{"id": "b"},
+		map[string]interface{}{"id": "a"},
+	})
+	mock.AssertExpectations(c)
+}
@@ -12,6 +12,7 @@ type Node struct {
 	ID      string
 	Host    Host
 	aliases []Host
+	tags    []string
 
 	pool *Pool
 
@@ -38,6 +39,25 @@ func (n *Node) Closed() bool {
 	return n.closed
 }
 
+// hasTag returns true if the server has the given tag.
+func (n *Node) hasTag(tag string) bool {
+	for _, t := range n.tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isHealthy returns true if the node is open and has a working connection.
+func (n *Node) isHealthy() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return !n.closed && n.pool != nil && n.pool.hasLiveConn()
+}
+
 // Close closes the session
 func (n *Node) Close(optArgs ...CloseOpts) error {
 	n.mu.Lock()
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -29,8 +30,19 @@ type Pool struct {
 	closed  int32
 
 	connFactory connFactory
+	stop        chan struct{} // closed when the pool is closed, nil if the pool has no background tasks
 
-	mu sync.Mutex // protects lazy creating connections
+	mu sync.Mutex // protects conns and lazy creating connections
+}
+
+// HostPoolOpts contains the limits of the connection pool of a single host,
+// for more information see ConnectOpts.HostPools. Zero values use the limits
+// set in ConnectOpts.
+type HostPoolOpts struct {
+	// InitialCap is the number of connections opened when the pool is created.
+	InitialCap int `json:"initial_cap,omitempty"`
+	// MaxOpen is the maximum number of connections held in the pool.
+	MaxOpen int `json:"max_open,omitempty"`
 }
 
 // NewPool creates a new connection pool for the given host
@@ -39,35 +51,139 @@ func NewPool(host Host, opts *ConnectOpts) (*Pool, error) {
 }
 
 func newPool(host Host, opts *ConnectOpts, connFactory connFactory) (*Pool, error) {
-	initialCap := opts.InitialCap
+	limits := hostPoolLimits(host, opts)
+	initialCap := limits.InitialCap
 	if initialCap <= 0 {
 		// Fallback to MaxIdle if InitialCap is zero, this should be removed
 		// when MaxIdle is removed
 		initialCap = opts.MaxIdle
 	}
 
-	maxOpen := opts.MaxOpen
+	maxOpen := limits.MaxOpen
 	if maxOpen <= 0 {
 		maxOpen = 1
 	}
-
-	conns := make([]*Connection, maxOpen)
-	var err error
-	for i := 0; i < opts.InitialCap; i++ {
-		conns[i], err = connFactory(host.String(), opts)
-		if err != nil {
-			return nil, err
-		}
+	if limits.InitialCap > maxOpen {
+		limits.InitialCap = maxOpen
 	}
 
-	return &Pool{
+	conns := make([]*Connection, maxOpen)
+	pool := &Pool{
 		conns:       conns,
 		pointer:     -1,
 		host:        host,
 		opts:        opts,
 		connFactory: connFactory,
 		closed:      poolIsNotClosed,
-	}, nil
+	}
+
+	var err error
+	for i := 0; i < limits.InitialCap; i++ {
+		conns[i], err = pool.openConn()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if expiryCheckInterval(opts) > 0 || opts.RTTProbeInterval > 0 {
+		pool.stop = make(chan struct{})
+	}
+	if interval := expiryCheckInterval(opts); interval > 0 {
+		go pool.closeExpiredConns(interval)
+	}
+	if interval := opts.RTTProbeInterval; interval > 0 {
+		go pool.probeRTT(interval)
+	}
+
+	return pool, nil
+}
+
+// hostPoolLimits returns the pool limits for the given host, the limits of
+// ConnectOpts.HostPools override those of opts.
+func hostPoolLimits(host Host, opts *ConnectOpts) HostPoolOpts {
+	limits := HostPoolOpts{
+		InitialCap: opts.InitialCap,
+		MaxOpen:    opts.MaxOpen,
+	}
+	if override, ok := opts.HostPools[host.String()]; ok {
+		if override.InitialCap > 0 {
+			limits.InitialCap = override.InitialCap
+		}
+		if override.MaxOpen > 0 {
+			limits.MaxOpen = override.MaxOpen
+		}
+	}
+
+	return limits
+}
+
+// expiryCheckInterval returns how often the pool should check for expired
+// connections, zero is returned if connections do not expire.
+func expiryCheckInterval(opts *ConnectOpts) time.Duration {
+	interval := opts.ConnMaxIdleTime
+	if d := opts.ConnMaxLifetime; d > 0 && (interval <= 0 || d < interval) {
+		interval = d
+	}
+
+	return interval
+}
+
+// closeExpiredConns periodically closes the connections which have exceeded
+// ConnMaxIdleTime or ConnMaxLifetime until the pool is closed.
+func (p *Pool) closeExpiredConns(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.closeExpired(now)
+		}
+	}
+}
+
+// closeExpired closes the connections which have expired at the given time,
+// their place in the pool is freed so a new connection is opened when next
+// needed.
+func (p *Pool) closeExpired(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed == poolIsClosed {
+		return
+	}
+
+	for i, c := range p.conns {
+		if c != nil && c.isExpired(now) {
+			_ = p.closeConn(c)
+			p.conns[i] = nil
+		}
+	}
+}
+
+// openConn opens a new connection to the pool's host.
+func (p *Pool) openConn() (*Connection, error) {
+	c, err := p.connFactory(p.host.String(), p.opts)
+	if err != nil {
+		return nil, err
+	}
+	if p.opts.OnConnOpen != nil {
+		p.opts.OnConnOpen(p.host.String())
+	}
+
+	return c, nil
+}
+
+// closeConn closes a connection which is being removed from the pool.
+func (p *Pool) closeConn(c *Connection) error {
+	err := c.Close()
+	if p.opts.OnConnClose != nil {
+		p.opts.OnConnClose(p.host.String())
+	}
+
+	return err
 }
 
 // Ping verifies a connection to the database is still alive,
@@ -93,10 +209,13 @@ func (p *Pool) Close() error {
 		return nil
 	}
 	p.closed = poolIsClosed
+	if p.stop != nil {
+		close(p.stop)
+	}
 
 	for _, c := range p.conns {
 		if c != nil {
-			err := c.Close()
+			err := p.closeConn(c)
 			if err != nil {
 				return err
 			}
@@ -117,30 +236,93 @@ func (p *Pool) conn() (*Connection, error) {
 	}
 	pos = pos % int32(len(p.conns))
 
-	var err error
+	// The slots are read and replaced while holding the lock as closeExpired
+	// may clear them at any time
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	if p.conns[pos] == nil {
-		p.mu.Lock()
-		defer p.mu.Unlock()
+	if p.closed == poolIsClosed {
+		return nil, errPoolClosed
+	}
+	if limit := p.opts.MaxConcurrentPerConn; limit > 0 {
+		pos = p.nextAvailable(pos, limit)
+	}
 
-		if p.conns[pos] == nil {
-			p.conns[pos], err = p.connFactory(p.host.String(), p.opts)
-			if err != nil {
-				return nil, err
-			}
+	return p.connAt(pos)
+}
+
+// nextAvailable returns the position of the first connection starting from
+// pos which has fewer than limit queries in-flight, a position without an open
+// connection is also available as a new connection will be opened. If all
+// connections are busy pos is returned. p.mu must be held.
+func (p *Pool) nextAvailable(pos int32, limit int) int32 {
+	n := int32(len(p.conns))
+	for i := int32(0); i < n; i++ {
+		next := (pos + i) % n
+		c := p.conns[next]
+		if c == nil || c.isBad() || c.inflightQueries() < limit {
+			return next
 		}
-	} else if p.conns[pos].isBad() {
+	}
+
+	return pos
+}
+
+// connAt returns the connection at pos, opening a new connection if there is
+// none or it is broken. p.mu must be held.
+func (p *Pool) connAt(pos int32) (*Connection, error) {
+	c := p.conns[pos]
+	if c != nil && !c.isBad() {
+		return c, nil
+	}
+
+	if c != nil {
 		// connBad connection needs to be reconnected
-		p.mu.Lock()
-		defer p.mu.Unlock()
+		p.closeConn(c)
+		p.conns[pos] = nil
+	}
 
-		p.conns[pos], err = p.connFactory(p.host.String(), p.opts)
-		if err != nil {
-			return nil, err
+	c, err := p.openConn()
+	if err != nil {
+		return nil, err
+	}
+	p.conns[pos] = c
+
+	return c, nil
+}
+
+// hasLiveConn returns true if the pool has at least one open connection which
+// is not broken.
+func (p *Pool) hasLiveConn() bool {
+	if atomic.LoadInt32(&p.closed) == poolIsClosed {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, c := range p.conns {
+		if c != nil && !c.isBad() && !c.isClosed() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// localAddrs returns the local addresses of the pool's open connections.
+func (p *Pool) localAddrs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var addrs []string
+	for _, c := range p.conns {
+		if c != nil && c.Conn != nil && !c.isClosed() {
+			addrs = append(addrs, c.LocalAddr().String())
 		}
 	}
 
-	return p.conns[pos], nil
+	return addrs
 }
 
 // SetInitialPoolCap sets the initial capacity of the connection pool.
@@ -0,0 +1,248 @@
+package rethinkdb
+
+import (
+	"net"
+	"runtime"
+	"time"
+
+	test "gopkg.in/check.v1"
+)
+
+type PoolSuite struct{}
+
+var _ = test.Suite(&PoolSuite{})
+
+func (s *PoolSuite) TestPool_MaxConcurrentPerConn(c *test.C) {
+	opts := &ConnectOpts{MaxOpen: 3, MaxConcurrentPerConn: 2}
+	busy := newConnection(nil, "host1:28015", opts)
+	busy.inflight = 2
+	idle := newConnection(nil, "host1:28015", opts)
+	idle.inflight = 1
+
+	pool := &Pool{
+		conns:   []*Connection{busy, idle, nil},
+		pointer: -1,
+		opts:    opts,
+	}
+
+	conn, err := pool.conn()
+	c.Assert(err, test.IsNil)
+	c.Assert(conn, test.Equals, idle)
+
+	idle.inflight = 2
+	c.Assert(pool.nextAvailable(0, 2), test.Equals, int32(2))
+
+	// All connections are busy so the original position is used
+	full := newConnection(nil, "host1:28015", opts)
+	full.inflight = 2
+	pool.conns[2] = full
+	c.Assert(pool.nextAvailable(1, 2), test.Equals, int32(1))
+}
+
+func (s *PoolSuite) TestPool_NoConcurrencyLimit(c *test.C) {
+	opts := &ConnectOpts{MaxOpen: 2}
+	busy := newConnection(nil, "host1:28015", opts)
+	busy.inflight = 100
+	other := newConnection(nil, "host1:28015", opts)
+
+	pool := &Pool{
+		conns:   []*Connection{busy, other},
+		pointer: -1,
+		opts:    opts,
+	}
+
+	conn, err := pool.conn()
+	c.Assert(err, test.IsNil)
+	c.Assert(conn, test.Equals, busy)
+}
+
+func (s *PoolSuite) TestPool_ConnLifecycleCallbacks(c *test.C) {
+	var opened, closed []string
+	opts := &ConnectOpts{
+		MaxOpen:     1,
+		InitialCap:  1,
+		OnConnOpen:  func(address string) { opened = append(opened, address) },
+		OnConnClose: func(address string) { closed = append(closed, address) },
+	}
+	factory := func(host string, opts *ConnectOpts) (*Connection, error) {
+		conn, _ := net.Pipe()
+		return newConnection(conn, host, opts), nil
+	}
+
+	pool, err := newPool(Host{Name: "host1", Port: 28015}, opts, factory)
+	c.Assert(err, test.IsNil)
+	c.Assert(opened, test.DeepEquals, []string{"host1:28015"})
+
+	// A broken connection is replaced
+	pool.conns[0].setBad()
+	_, err = pool.conn()
+	c.Assert(err, test.IsNil)
+	c.Assert(opened, test.HasLen, 2)
+	c.Assert(closed, test.DeepEquals, []string{"host1:28015"})
+
+	c.Assert(pool.Close(), test.IsNil)
+	c.Assert(closed, test.HasLen, 2)
+}
+
+func (s *PoolSuite) TestPool_CloseExpired(c *test.C) {
+	opts := &ConnectOpts{MaxOpen: 4, ConnMaxIdleTime: time.Minute, ConnMaxLifetime: time.Hour}
+	newConn := func() *Connection {
+		conn, _ := net.Pipe()
+		return newConnection(conn, "host1:28015", opts)
+	}
+	now := time.Now()
+
+	idle := newConn()
+	idle.lastUsed = now.Add(-2 * time.Minute).UnixNano()
+	old := newConn()
+	old.createdAt = now.Add(-2 * time.Hour)
+	busy := newConn()
+	busy.createdAt = now.Add(-2 * time.Hour)
+	busy.inflight = 1
+	feed := newConn()
+	feed.lastUsed = now.Add(-2 * time.Minute).UnixNano()
+	feed.openCursors = 1
+
+	pool := &Pool{
+		conns:   []*Connection{idle, old, busy, feed},
+		pointer: -1,
+		opts:    opts,
+	}
+	pool.closeExpired(now)
+	c.Assert(pool.conns, test.DeepEquals, []*Connection{nil, nil, busy, feed})
+	c.Assert(idle.isClosed(), test.Equals, true)
+	c.Assert(old.isClosed(), test.Equals, true)
+
+	c.Assert(expiryCheckInterval(opts), test.Equals, time.Minute)
+	c.Assert(expiryCheckInterval(&ConnectOpts{ConnMaxLifetime: time.Hour}), test.Equals, time.Hour)
+	c.Assert(expiryCheckInterval(&ConnectOpts{}), test.Equals, time.Duration(0))
+}
+
+func (s *PoolSuite) TestPool_CloseExpiredConcurrently(c *test.C) {
+	opts := &ConnectOpts{MaxOpen: 2, ConnMaxLifetime: time.Minute, MaxConcurrentPerConn: 1}
+	factory := func(host string, opts *ConnectOpts) (*Connection, error) {
+		conn, _ := net.Pipe()
+		return newConnection(conn, host, opts), nil
+	}
+	pool, err := newPool(Host{Name: "host1", Port: 28015}, opts, factory)
+	c.Assert(err, test.IsNil)
+	defer pool.Close()
+
+	// Every connection has expired, so closeExpired keeps clearing the slots
+	// which conn reads and fills
+	done := make(chan struct{})
+	closing := make(chan struct{})
+	go func() {
+		defer close(closing)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				pool.closeExpired(time.Now().Add(time.Hour))
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	for end := time.Now().Add(50 * time.Millisecond); time.Now().Before(end); {
+		conn, err := pool.conn()
+		c.Assert(err, test.IsNil)
+		c.Assert(conn, test.NotNil)
+		runtime.Gosched()
+	}
+	close(done)
+	<-closing
+}
+
+func (s *PoolSuite) TestPool_LocalAddrs(c *test.C) {
+	opts := &ConnectOpts{MaxOpen: 4}
+	open := &connMock{}
+	open.On("LocalAddr").Return(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40000})
+	closed := newConnection(&connMock{}, "host1:28015", opts)
+	closed.closed = connClosed
+
+	pool := &Pool{
+		conns:   []*Connection{newConnection(open, "host1:28015", opts), closed, newConnection(nil, "host1:28015", opts), nil},
+		pointer: -1,
+		opts:    opts,
+	}
+	c.Assert(pool.localAddrs(), test.DeepEquals, []string{"127.0.0.1:40000"})
+	open.AssertExpectations(c)
+}
+
+func (s *PoolSuite) TestPool_Stats(c *test.C) {
+	opts := &ConnectOpts{}
+	newConn := func() *Connection {
+		conn, _ := net.Pipe()
+		return newConnection(conn, "host1:28015", opts)
+	}
+
+	fast := newConn()
+	fast.recordRTT(10 * time.Millisecond)
+	c.Assert(fast.rtt(), test.Equals, 10*time.Millisecond)
+	fast.recordRTT(20 * time.Millisecond)
+	c.Assert(fast.rtt(), test.Equals, 12*time.Millisecond)
+
+	slow := newConn()
+	slow.recordRTT(30 * time.Millisecond)
+	unmeasured := newConn()
+	bad := newConn()
+	bad.recordRTT(time.Second)
+	bad.setBad()
+
+	pool := &Pool{
+		host:    Host{Name: "host1", Port: 28015},
+		conns:   []*Connection{fast, nil, slow, unmeasured, bad},
+		pointer: -1,
+		opts:    opts,
+	}
+	c.Assert(pool.stats(), test.Equals, HostStats{Host: "host1:28015", Connections: 3, RTT: 21 * time.Millisecond})
+
+	cluster := &Cluster{hp: newHostPool(opts), opts: opts}
+	cluster.replaceNodes([]*Node{newNode("node1", []Host{pool.host}, pool)})
+	session := &Session{opts: opts, cluster: cluster}
+	c.Assert(session.Stats(), test.DeepEquals, Stats{RTT: 21 * time.Millisecond, Hosts: []HostStats{pool.stats()}})
+
+	c.Assert(rttTimeout(session, 2), test.Equals, 42*time.Millisecond)
+
+	// Without any measurements there is no timeout
+	c.Assert(rttTimeout(&Session{opts: opts, cluster: &Cluster{opts: opts}}, 2), test.Equals, time.Duration(0))
+}
+
+func (s *PoolSuite) TestPool_HostPools(c *test.C) {
+	opts := &ConnectOpts{
+		MaxOpen:    2,
+		InitialCap: 1,
+		HostPools: map[string]HostPoolOpts{
+			"host2:28015": {MaxOpen: 4, InitialCap: 3},
+			"host3:28015": {MaxOpen: 1, InitialCap: 0},
+		},
+	}
+	var opened []string
+	factory := func(host string, opts *ConnectOpts) (*Connection, error) {
+		opened = append(opened, host)
+		conn, _ := net.Pipe()
+		return newConnection(conn, host, opts), nil
+	}
+
+	pool1, err := newPool(Host{Name: "host1", Port: 28015}, opts, factory)
+	c.Assert(err, test.IsNil)
+	c.Assert(pool1.conns, test.HasLen, 2)
+	pool2, err := newPool(Host{Name: "host2", Port: 28015}, opts, factory)
+	c.Assert(err, test.IsNil)
+	c.Assert(pool2.conns, test.HasLen, 4)
+	pool3, err := newPool(Host{Name: "host3", Port: 28015}, opts, factory)
+	c.Assert(err, test.IsNil)
+	c.Assert(pool3.conns, test.HasLen, 1)
+
+	c.Assert(opened, test.DeepEquals, []string{
+		"host1:28015",
+		"host2:28015", "host2:28015", "host2:28015",
+		"host3:28015",
+	})
+
+	for _, pool := range []*Pool{pool1, pool2, pool3} {
+		c.Assert(pool.Close(), test.IsNil)
+	}
+}
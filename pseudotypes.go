@@ -2,10 +2,10 @@ package rethinkdb
 
 import (
 	"encoding/base64"
-	"math"
-	"strconv"
-	"time"
+	"reflect"
 
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/internal/reqltime"
 	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
 
 	"fmt"
@@ -25,7 +25,7 @@ func convertPseudotype(obj map[string]interface{}, opts map[string]interface{})
 			}
 
 			if timeFormat == "native" {
-				return reqlTimeToNativeTime(obj["epoch_time"].(float64), obj["timezone"].(string))
+				return reqltime.ToTime(obj)
 			} else if timeFormat == "raw" {
 				return obj, nil
 			} else {
@@ -125,33 +125,6 @@ func recursivelyConvertPseudotype(obj interface{}, opts map[string]interface{})
 
 // Pseudo-type helper functions
 
-func reqlTimeToNativeTime(timestamp float64, timezone string) (time.Time, error) {
-	sec, ms := math.Modf(timestamp)
-
-	// Convert to native time rounding to milliseconds
-	t := time.Unix(int64(sec), int64(math.Floor(ms*1000+0.5))*1000*1000)
-
-	// Caclulate the timezone
-	if timezone != "" {
-		hours, err := strconv.Atoi(timezone[1:3])
-		if err != nil {
-			return time.Time{}, err
-		}
-		minutes, err := strconv.Atoi(timezone[4:6])
-		if err != nil {
-			return time.Time{}, err
-		}
-		tzOffset := ((hours * 60) + minutes) * 60
-		if timezone[:1] == "-" {
-			tzOffset = 0 - tzOffset
-		}
-
-		t = t.In(time.FixedZone(timezone, tzOffset))
-	}
-
-	return t, nil
-}
-
 func reqlGroupedDataToSlice(obj map[string]interface{}) (interface{}, error) {
 	if data, ok := obj["data"]; ok {
 		ret := []interface{}{}
@@ -179,6 +152,93 @@ func reqlGroupedDataToMap(obj map[string]interface{}) (interface{}, error) {
 	return nil, fmt.Errorf("pseudo-type GROUPED_DATA object %v does not have the expected field \"data\"", obj)
 }
 
+// groupedDataEntries returns the groups and reductions of converted grouped
+// data, either a slice of objects with the fields "group" and "reduction" or
+// a map when using the "map" group format. The second result is false if data
+// is not grouped data.
+func groupedDataEntries(data interface{}) ([][2]interface{}, bool) {
+	switch data := data.(type) {
+	case map[interface{}]interface{}:
+		entries := make([][2]interface{}, 0, len(data))
+		for group, reduction := range data {
+			entries = append(entries, [2]interface{}{group, reduction})
+		}
+		return entries, true
+	case []interface{}:
+		entries := make([][2]interface{}, 0, len(data))
+		for _, v := range data {
+			obj, ok := v.(map[string]interface{})
+			if !ok || len(obj) != 2 {
+				return nil, false
+			}
+			group, ok := obj["group"]
+			if !ok {
+				return nil, false
+			}
+			reduction, ok := obj["reduction"]
+			if !ok {
+				return nil, false
+			}
+			entries = append(entries, [2]interface{}{group, reduction})
+		}
+		return entries, true
+	}
+	return nil, false
+}
+
+// decodeGroupedData decodes grouped data into the map dest, keyed by the
+// groups. Reductions which are grouped data are decoded recursively when the
+// element type of dest is a map.
+func decodeGroupedData(dest reflect.Value, data interface{}) error {
+	entries, ok := groupedDataEntries(data)
+	if !ok {
+		return fmt.Errorf("cannot decode %T into %s, expected grouped data", data, dest.Type())
+	}
+
+	keyt, elemt := dest.Type().Key(), dest.Type().Elem()
+	m := reflect.MakeMapWithSize(dest.Type(), len(entries))
+	for _, entry := range entries {
+		group, reduction := entry[0], entry[1]
+
+		key := reflect.New(keyt)
+		if err := encoding.Decode(key.Interface(), group); err != nil {
+			return err
+		}
+
+		elem := reflect.New(elemt)
+		_, nested := groupedDataEntries(reduction)
+		_, object := reduction.(map[string]interface{})
+		switch {
+		case elemt.Kind() == reflect.Map && nested:
+			if err := decodeGroupedData(elem.Elem(), reduction); err != nil {
+				return err
+			}
+		case elemt.Kind() == reflect.Map && !object:
+			return fmt.Errorf("cannot decode the reduction of group %v into %s, the grouped data is nested less deeply than %s", group, elemt, dest.Type())
+		case nested && !isGroupedDataContainer(elemt):
+			return fmt.Errorf("cannot decode the reduction of group %v into %s, the grouped data is nested more deeply than %s", group, elemt, dest.Type())
+		default:
+			if err := encoding.Decode(elem.Interface(), reduction); err != nil {
+				return err
+			}
+		}
+		m.SetMapIndex(key.Elem(), elem.Elem())
+	}
+	dest.Set(m)
+
+	return nil
+}
+
+// isGroupedDataContainer returns true if grouped data can be decoded into a
+// value of type t without converting it to a map.
+func isGroupedDataContainer(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Interface:
+		return true
+	}
+	return false
+}
+
 func reqlBinaryToNativeBytes(obj map[string]interface{}) (interface{}, error) {
 	if data, ok := obj["data"]; ok {
 		if data, ok := data.(string); ok {
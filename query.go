@@ -1,10 +1,13 @@
 package rethinkdb
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
@@ -21,6 +24,22 @@ type Query struct {
 	Term      *Term
 	Opts      map[string]interface{}
 	builtTerm interface{}
+
+	// node is the ID or address of the node the query should preferably be
+	// sent to, see RunOpts.Node.
+	node string
+	// prefetch enables fetching the next batch of results in the background,
+	// see RunOpts.Prefetch.
+	prefetch bool
+	// maxResponseBytes is the maximum size of a response to the query, see
+	// RunOpts.MaxResponseBytes.
+	maxResponseBytes int
+	// batchTimeout is the timeout of the query and of fetching each further
+	// batch of its cursor, see RunOpts.TimeoutRTTMultiplier.
+	batchTimeout time.Duration
+	// probe is set for the queries which measure round-trip times, they do
+	// not update the time the connection was last used.
+	probe bool
 }
 
 func (q *Query) Build() []interface{} {
@@ -65,14 +84,19 @@ type Term struct {
 	optArgs        map[string]Term
 	lastErr        error
 	isMockAnything bool
+	orderedInsert  bool
+	queryID        string
 }
 
-func (t Term) compare(t2 Term, varMap map[int64]int64) bool {
-	if t.isMockAnything || t2.isMockAnything {
+// compare returns true if t and t2 represent the same query, if
+// matchAnything is true terms created by MockAnything match any term.
+func (t Term) compare(t2 Term, varMap map[int64]int64, matchAnything bool) bool {
+	if matchAnything && (t.isMockAnything || t2.isMockAnything) {
 		return true
 	}
 
 	if t.name != t2.name ||
+		t.isMockAnything != t2.isMockAnything ||
 		t.rawQuery != t2.rawQuery ||
 		t.rootTerm != t2.rootTerm ||
 		t.termType != t2.termType ||
@@ -105,7 +129,7 @@ func (t Term) compare(t2 Term, varMap map[int64]int64) bool {
 			if varMap[v1] != v2 {
 				return false
 			}
-		} else if !v.compare(t2.args[i], varMap) {
+		} else if !v.compare(t2.args[i], varMap, matchAnything) {
 			return false
 		}
 	}
@@ -115,7 +139,7 @@ func (t Term) compare(t2 Term, varMap map[int64]int64) bool {
 			return false
 		}
 
-		if !v.compare(t2.optArgs[k], varMap) {
+		if !v.compare(t2.optArgs[k], varMap, matchAnything) {
 			return false
 		}
 	}
@@ -123,6 +147,228 @@ func (t Term) compare(t2 Term, varMap map[int64]int64) bool {
 	return true
 }
 
+// Equal returns true if t and other represent the same query. Functions are
+// equal if their bodies are equal, regardless of the IDs of their variables.
+// Unlike when matching the queries of a Mock, terms created by MockAnything
+// are only equal to other MockAnything terms.
+func (t Term) Equal(other Term) bool {
+	return t.compare(other, map[int64]int64{}, false)
+}
+
+// QueryID returns the ID generated by ConnectOpts.QueryIDGenerator for the
+// query the term is run as. It is only set on the root term of a query, such
+// as the term passed to ConnectOpts.QueryRewriter, and is empty otherwise.
+func (t Term) QueryID() string {
+	return t.queryID
+}
+
+// Type returns the type of the term, for example p.Term_TABLE for terms
+// created by Table.
+func (t Term) Type() p.Term_TermType {
+	return t.termType
+}
+
+// Transform returns a copy of the term with every sub-term replaced by the
+// result of calling fn with it, starting from the innermost terms and ending
+// with the term itself. The original term is not modified. Along with
+// ConnectOpts.QueryRewriter this can be used to modify queries before they
+// are sent, for example to use a separate database for each tenant:
+//
+//	users := r.Table("users")
+//	t = t.Transform(func(t r.Term) r.Term {
+//		if t.Equal(users) {
+//			return r.DB(tenant).Table("users")
+//		}
+//		return t
+//	})
+//
+// Note that fn is called with each sub-term without its parent, so the
+// replacement must be valid wherever the sub-term is used. For example
+// wrapping tables in Filter breaks queries such as Table("users").Get(id) as
+// Get, GetAll, Between and the write terms require a table.
+func (t Term) Transform(fn func(Term) Term) Term {
+	if len(t.args) > 0 {
+		args := make([]Term, len(t.args))
+		for i, arg := range t.args {
+			args[i] = arg.Transform(fn)
+		}
+		t.args = args
+	}
+	if len(t.optArgs) > 0 {
+		optArgs := make(map[string]Term, len(t.optArgs))
+		for k, arg := range t.optArgs {
+			optArgs[k] = arg.Transform(fn)
+		}
+		t.optArgs = optArgs
+	}
+
+	res := fn(t)
+	res.orderedInsert = res.orderedInsert || t.orderedInsert
+	return res
+}
+
+// Hash returns a hash of the term which is stable across calls and builds of
+// the same query, terms which are Equal have the same hash as the hash covers
+// the same fields as Equal, including the names of the terms. It can be used as
+// the key of a cache of prepared queries.
+func (t Term) Hash() uint64 {
+	h := termHasher{sum: fnvOffset64}
+	t.hash(&h, map[int64]int64{})
+	return h.sum
+}
+
+// Depth returns the nesting depth of the term tree, a term without arguments
+// has a depth of 1. Arrays and objects passed to Expr count as terms, as they
+// do on the server, which limits how deeply queries can be nested. The
+// contents of terms created by RawQuery are not inspected, they have a depth
+// of 1.
+func (t Term) Depth() int {
+	if t.rawQuery {
+		return 1
+	}
+
+	depth := 0
+	for _, arg := range t.args {
+		if d := arg.Depth(); d > depth {
+			depth = d
+		}
+	}
+	for _, arg := range t.optArgs {
+		if d := arg.Depth(); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// termHasher computes the 64-bit FNV-1a hash of a term, it avoids the
+// allocations of hash/fnv and fmt so that hashing a term is cheaper than
+// building it.
+type termHasher struct {
+	sum uint64
+	buf [64]byte
+}
+
+func (h *termHasher) write(b []byte) {
+	for _, c := range b {
+		h.sum ^= uint64(c)
+		h.sum *= fnvPrime64
+	}
+}
+
+func (h *termHasher) writeString(s string) {
+	for i := 0; i < len(s); i++ {
+		h.sum ^= uint64(s[i])
+		h.sum *= fnvPrime64
+	}
+}
+
+func (h *termHasher) writeByte(c byte) {
+	h.sum ^= uint64(c)
+	h.sum *= fnvPrime64
+}
+
+func (h *termHasher) writeInt(n int64) {
+	h.write(strconv.AppendInt(h.buf[:0], n, 10))
+}
+
+// hash writes the term to h, function variables are replaced by the order in
+// which they are declared so that the hash does not depend on their IDs.
+func (t Term) hash(h *termHasher, varMap map[int64]int64) {
+	h.writeByte('(')
+	h.writeInt(int64(t.termType))
+	flags := byte('0')
+	if t.rootTerm {
+		flags |= 1
+	}
+	if t.rawQuery {
+		flags |= 2
+	}
+	if t.isMockAnything {
+		flags |= 4
+	}
+	h.writeByte(flags)
+	h.writeInt(int64(len(t.name)))
+	h.writeByte(':')
+	h.writeString(t.name)
+	switch data := t.data.(type) {
+	case nil:
+	case *json.RawMessage:
+		h.write(*data)
+	case string:
+		// The length separates the string from the following arguments
+		h.writeByte('s')
+		h.writeInt(int64(len(data)))
+		h.writeByte(':')
+		h.writeString(data)
+	case float64:
+		h.writeByte('f')
+		h.write(strconv.AppendFloat(h.buf[:0], data, 'g', -1, 64))
+	case int:
+		h.writeByte('i')
+		h.writeInt(int64(data))
+	case int64:
+		h.writeByte('l')
+		h.writeInt(data)
+	case bool:
+		h.writeByte('b')
+		h.write(strconv.AppendBool(h.buf[:0], data))
+	default:
+		h.writeString(fmt.Sprintf("%T:%v", data, data))
+	}
+
+	for i, arg := range t.args {
+		h.writeByte(',')
+		if t.termType == p.Term_FUNC && i == 0 {
+			for _, v := range arg.args {
+				if id, ok := v.data.(int64); ok {
+					varMap[id] = int64(len(varMap))
+				}
+			}
+			h.writeString("params:")
+			h.writeInt(int64(len(arg.args)))
+			continue
+		} else if t.termType == p.Term_VAR && i == 0 {
+			if id, ok := arg.data.(int64); ok {
+				if mapped, ok := varMap[id]; ok {
+					h.writeString("var:")
+					h.writeInt(mapped)
+					continue
+				}
+			}
+		}
+		arg.hash(h, varMap)
+	}
+
+	if len(t.optArgs) == 1 {
+		for k, arg := range t.optArgs {
+			h.writeOptArg(k, arg, varMap)
+		}
+	} else if len(t.optArgs) > 1 {
+		keys := make([]string, 0, len(t.optArgs))
+		for k := range t.optArgs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			h.writeOptArg(k, t.optArgs[k], varMap)
+		}
+	}
+	h.writeByte(')')
+}
+
+func (h *termHasher) writeOptArg(key string, arg Term, varMap map[int64]int64) {
+	h.writeByte(',')
+	h.write(strconv.AppendQuote(h.buf[:0], key))
+	h.writeByte(':')
+	arg.hash(h, varMap)
+}
+
 // build takes the query tree and prepares it to be sent as a JSON
 // expression
 func (t Term) Build() (interface{}, error) {
@@ -244,6 +490,8 @@ type OptArgs interface {
 	toMap() map[string]interface{}
 }
 
+// OptArgs replaces the optional arguments of the term, args should be either
+// one of the optional argument types (such as InsertOpts) or a map.
 func (t Term) OptArgs(args interface{}) Term {
 	switch args := args.(type) {
 	case OptArgs:
@@ -255,6 +503,41 @@ func (t Term) OptArgs(args interface{}) Term {
 	return t
 }
 
+// WithOpts merges args into the existing optional arguments of the term,
+// overriding any existing values with the same key. Like OptArgs, args should
+// be either one of the optional argument types or a map. This allows optional
+// arguments to be added conditionally after the term has been created, for
+// example:
+//
+//	query := r.Table("test").Insert(doc)
+//	if upsert {
+//		query = query.WithOpts(r.InsertOpts{Conflict: "replace"})
+//	}
+func (t Term) WithOpts(args interface{}) Term {
+	var opts map[string]interface{}
+	switch args := args.(type) {
+	case OptArgs:
+		opts = args.toMap()
+	case map[string]interface{}:
+		opts = args
+	}
+	if len(opts) == 0 {
+		return t
+	}
+
+	// Copy the existing optional arguments so the original term is unchanged
+	optArgs := make(termsObj, len(t.optArgs)+len(opts))
+	for k, v := range t.optArgs {
+		optArgs[k] = v
+	}
+	for k, v := range convertTermObj(opts) {
+		optArgs[k] = v
+	}
+	t.optArgs = optArgs
+
+	return t
+}
+
 type QueryExecutor interface {
 	IsConnected() bool
 	Query(context.Context, Query) (*Cursor, error)
@@ -286,6 +569,21 @@ type WriteResponse struct {
 	Changes       []ChangeResponse
 }
 
+// WasNoOp returns true if the write query completed without errors but did not
+// modify any documents, for example when an update sets fields to their
+// existing values.
+func (r WriteResponse) WasNoOp() bool {
+	return r.Unchanged > 0 && r.Errors == 0 &&
+		r.Replaced == 0 && r.Inserted == 0 && r.Deleted == 0
+}
+
+// Applied returns true if the write query completed without errors and
+// replaced at least one document, such as when the version passed to
+// UpdateIfVersion matched.
+func (r WriteResponse) Applied() bool {
+	return r.Replaced > 0 && r.Errors == 0
+}
+
 // ChangeResponse is a helper type used when dealing with changefeeds. The type
 // contains both the value before the query and the new value.
 type ChangeResponse struct {
@@ -299,18 +597,78 @@ type ChangeResponse struct {
 }
 
 // RunOpts contains the optional arguments for the Run function.
+//
+// ArrayLimit overrides the maximum size of arrays for this query, if nil the
+// server default of 100,000 elements is used. When set it must be greater
+// than zero.
+//
+// Node routes the query to a specific server, identified by either its server
+// ID or its address ("host:port"). Combined with ReadMode set to "outdated"
+// (or "single" when the node is the primary replica) this allows reads to be
+// served by a chosen replica instead of a random node. The driver can only
+// route to nodes it is connected to: without DiscoverHosts these are the hosts
+// passed to Connect, with DiscoverHosts every server in the cluster is
+// available. If the node is unknown or unavailable the query is sent to any
+// other node as usual. The address of the server an earlier query was sent to
+// is returned by Cursor.Host, for example to send a read to the server which
+// handled a write:
+//
+//	write, err := r.Table("posts").Insert(post).Run(session)
+//	// ...
+//	cursor, err := r.Table("posts").Get(id).Run(session, r.RunOpts{Node: r.String(write.Host())})
+//
+// Note that the server a query is sent to is not necessarily a replica of the
+// table, with the default ReadMode reads are always served by the primary
+// replica.
+//
+// RawPseudotypes disables the conversion of all pseudotypes (times, binary
+// data, geometry and grouped data), instead they are returned as their raw
+// {"$reql_type$": ...} objects. It overrides TimeFormat, GroupFormat,
+// BinaryFormat and GeometryFormat, and is useful when forwarding results
+// without modifying them.
+//
+// Prefetch requests the next batch of results from the server while the
+// current batch is still being read, overlapping network round trips with
+// processing of the results. At most one batch is fetched ahead of the batch
+// being read. Prefetching only applies to sequences, changefeeds are never
+// prefetched, and any pending prefetch is cancelled when the cursor is closed.
+//
+// MaxResponseBytes limits the size of each response received for the query,
+// for cursors this applies to every batch. Responses larger than the limit
+// are discarded without being read into memory and ErrResponseTooLarge is
+// returned instead. If nil or zero then there is no limit.
+//
+// TimeoutRTTMultiplier, if set and greater than zero, sets the timeout of the query to
+// the average round-trip time of the session's connections multiplied by the
+// value (see Session.Stats), making timeouts relative to the latency of the
+// network. The timeout applies separately to the query and to fetching each
+// further batch of its cursor, unlike a deadline set using Context it does not
+// limit the lifetime of the cursor. It is ignored if no round-trip time has
+// been measured yet, see ConnectOpts.RTTProbeInterval.
+//
+// CollectErrors is used by RunWrite, when set the RQLWriteError returned for
+// failed writes contains every error reported in the changes of the response
+// rather than only the first. The server only includes failed writes in the
+// changes if the write term was created with ReturnChanges set to "always".
 type RunOpts struct {
 	DB             interface{} `rethinkdb:"db,omitempty"`
 	Db             interface{} `rethinkdb:"db,omitempty"` // Deprecated
 	Profile        interface{} `rethinkdb:"profile,omitempty"`
 	Durability     interface{} `rethinkdb:"durability,omitempty"`
 	UseOutdated    interface{} `rethinkdb:"use_outdated,omitempty"` // Deprecated
-	ArrayLimit     interface{} `rethinkdb:"array_limit,omitempty"`
+	ArrayLimit     *int        `rethinkdb:"array_limit,omitempty"`
 	TimeFormat     interface{} `rethinkdb:"time_format,omitempty"`
 	GroupFormat    interface{} `rethinkdb:"group_format,omitempty"`
 	BinaryFormat   interface{} `rethinkdb:"binary_format,omitempty"`
 	GeometryFormat interface{} `rethinkdb:"geometry_format,omitempty"`
 	ReadMode       interface{} `rethinkdb:"read_mode,omitempty"`
+	Node           *string     `rethinkdb:"-"`
+	RawPseudotypes *bool       `rethinkdb:"-"`
+	Prefetch       *bool       `rethinkdb:"-"`
+
+	MaxResponseBytes     *int     `rethinkdb:"-"`
+	TimeoutRTTMultiplier *float64 `rethinkdb:"-"`
+	CollectErrors        *bool    `rethinkdb:"-"`
 
 	MinBatchRows              interface{} `rethinkdb:"min_batch_rows,omitempty"`
 	MaxBatchRows              interface{} `rethinkdb:"max_batch_rows,omitempty"`
@@ -322,7 +680,37 @@ type RunOpts struct {
 }
 
 func (o RunOpts) toMap() map[string]interface{} {
-	return optArgsToMap(o)
+	opts := optArgsToMap(o)
+	if o.RawPseudotypes != nil && *o.RawPseudotypes {
+		opts["time_format"] = "raw"
+		opts["group_format"] = "raw"
+		opts["binary_format"] = "raw"
+		opts["geometry_format"] = "raw"
+	}
+
+	return opts
+}
+
+// Bool returns a pointer to v, for setting the boolean fields of RunOpts such
+// as Prefetch.
+func Bool(v bool) *bool {
+	return &v
+}
+
+// Int returns a pointer to v, for setting the integer fields of RunOpts such
+// as ArrayLimit and MaxResponseBytes.
+func Int(v int) *int {
+	return &v
+}
+
+// Float64 returns a pointer to v, for setting RunOpts.TimeoutRTTMultiplier.
+func Float64(v float64) *float64 {
+	return &v
+}
+
+// String returns a pointer to v, for setting RunOpts.Node.
+func String(v string) *string {
+	return &v
 }
 
 // Run runs a query using the given connection.
@@ -337,11 +725,29 @@ func (o RunOpts) toMap() map[string]interface{} {
 //      // Do something with document
 //	}
 func (t Term) Run(s QueryExecutor, optArgs ...RunOpts) (*Cursor, error) {
+	if t.orderedInsert {
+		return nil, errOrderedInsertRun
+	}
+	optArgs = withDefaultRunOpts(s, optArgs)
+
 	opts := map[string]interface{}{}
 	var ctx context.Context = nil // if it's nil connection will form context from connection opts
+	var node string
+	var prefetch bool
+	var maxResponseBytes int
 	if len(optArgs) >= 1 {
 		opts = optArgs[0].toMap()
 		ctx = optArgs[0].Context
+		if optArgs[0].Node != nil {
+			node = *optArgs[0].Node
+		}
+		prefetch = optArgs[0].Prefetch != nil && *optArgs[0].Prefetch
+		if optArgs[0].MaxResponseBytes != nil {
+			maxResponseBytes = *optArgs[0].MaxResponseBytes
+		}
+		if err := validateArrayLimit(optArgs[0].ArrayLimit); err != nil {
+			return nil, err
+		}
 	}
 
 	if s == nil || !s.IsConnected() {
@@ -352,18 +758,64 @@ func (t Term) Run(s QueryExecutor, optArgs ...RunOpts) (*Cursor, error) {
 	if err != nil {
 		return nil, err
 	}
+	q.node = node
+	q.prefetch = prefetch
+	q.maxResponseBytes = maxResponseBytes
+	if len(optArgs) >= 1 && optArgs[0].TimeoutRTTMultiplier != nil && *optArgs[0].TimeoutRTTMultiplier > 0 {
+		q.batchTimeout = rttTimeout(s, *optArgs[0].TimeoutRTTMultiplier)
+	}
 
 	return s.Query(ctx, q)
 }
 
+// runOptsDefaulter is implemented by query executors which have default run
+// options, see Session.SetDefaultRunOpts.
+type runOptsDefaulter interface {
+	runOptsDefaults() (RunOpts, bool)
+}
+
+// withDefaultRunOpts merges the run options passed to a query over the
+// default run options of s, if it has any. Fields of the options which are not
+// nil or the zero value override the defaults, so options set using a pointer
+// such as Prefetch or Node can be overridden with any value.
+func withDefaultRunOpts(s QueryExecutor, optArgs []RunOpts) []RunOpts {
+	d, ok := s.(runOptsDefaulter)
+	if !ok {
+		return optArgs
+	}
+	defaults, ok := d.runOptsDefaults()
+	if !ok {
+		return optArgs
+	}
+	if len(optArgs) == 0 {
+		return []RunOpts{defaults}
+	}
+
+	merged := reflect.ValueOf(&defaults).Elem()
+	opts := reflect.ValueOf(optArgs[0])
+	for i := 0; i < opts.NumField(); i++ {
+		if f := opts.Field(i); !f.IsZero() {
+			merged.Field(i).Set(f)
+		}
+	}
+
+	return []RunOpts{defaults}
+}
+
 // RunWrite runs a query using the given connection but unlike Run automatically
 // scans the result into a variable of type WriteResponse. This function should be used
 // if you are running a write query (such as Insert,  Update, TableCreate, etc...).
 //
-// If an error occurs when running the write query the first error is returned.
+// If an error occurs when running the write query the first error is returned,
+// if any documents failed to be written an RQLWriteError is returned.
 //
 //	res, err := r.DB("database").Table("table").Insert(doc).RunWrite(sess)
 func (t Term) RunWrite(s QueryExecutor, optArgs ...RunOpts) (WriteResponse, error) {
+	optArgs = withDefaultRunOpts(s, optArgs)
+	if t.orderedInsert {
+		return t.runOrderedInsert(s, optArgs...)
+	}
+
 	var response WriteResponse
 
 	res, err := t.Run(s, optArgs...)
@@ -377,7 +829,8 @@ func (t Term) RunWrite(s QueryExecutor, optArgs ...RunOpts) (WriteResponse, erro
 	}
 
 	if response.Errors > 0 {
-		return response, fmt.Errorf("%s", response.FirstError)
+		collect := len(optArgs) >= 1 && optArgs[0].CollectErrors != nil && *optArgs[0].CollectErrors
+		return response, newWriteError(response, collect)
 	}
 
 	return response, nil
@@ -395,6 +848,36 @@ func (t Term) ReadOne(dest interface{}, s QueryExecutor, optArgs ...RunOpts) err
 	return res.One(dest)
 }
 
+// RunFirst runs the query and decodes the first row of the result into dest.
+// The cursor is returned positioned after the first row so the remaining rows
+// can be read by calling Next. If the result is empty false is returned
+// along with the closed cursor.
+//
+//	var first Doc
+//	cursor, ok, err := r.Table("table").RunFirst(sess, &first)
+//	if err != nil {
+//		// error
+//	}
+//	if !ok {
+//		// no rows
+//	}
+//	defer cursor.Close()
+func (t Term) RunFirst(s QueryExecutor, dest interface{}, optArgs ...RunOpts) (*Cursor, bool, error) {
+	res, err := t.Run(s, optArgs...)
+	if err != nil {
+		return nil, false, err
+	}
+	if !res.Next(dest) {
+		if err := res.Err(); err != nil {
+			res.Close()
+			return nil, false, err
+		}
+		return res, false, nil
+	}
+
+	return res, true, nil
+}
+
 // ReadAll is a shortcut method that runs the query on the given connection
 // and reads all of the responses from the cursor before closing it.
 //
@@ -407,19 +890,29 @@ func (t Term) ReadAll(dest interface{}, s QueryExecutor, optArgs ...RunOpts) err
 	return res.All(dest)
 }
 
+func validateArrayLimit(limit *int) error {
+	if limit != nil && *limit <= 0 {
+		return RQLDriverError{rqlError(fmt.Sprintf("array limit must be greater than zero, got %d", *limit))}
+	}
+
+	return nil
+}
+
 // ExecOpts contains the optional arguments for the Exec function and  inherits
 // its options from RunOpts, the only difference is the addition of the NoReply
 // field.
 //
 // When NoReply is true it causes the driver not to wait to receive the result
 // and return immediately.
+//
+// ArrayLimit behaves as it does in RunOpts.
 type ExecOpts struct {
 	DB             interface{} `rethinkdb:"db,omitempty"`
 	Db             interface{} `rethinkdb:"db,omitempty"` // Deprecated
 	Profile        interface{} `rethinkdb:"profile,omitempty"`
 	Durability     interface{} `rethinkdb:"durability,omitempty"`
 	UseOutdated    interface{} `rethinkdb:"use_outdated,omitempty"` // Deprecated
-	ArrayLimit     interface{} `rethinkdb:"array_limit,omitempty"`
+	ArrayLimit     *int        `rethinkdb:"array_limit,omitempty"`
 	TimeFormat     interface{} `rethinkdb:"time_format,omitempty"`
 	GroupFormat    interface{} `rethinkdb:"group_format,omitempty"`
 	BinaryFormat   interface{} `rethinkdb:"binary_format,omitempty"`
@@ -447,11 +940,18 @@ func (o ExecOpts) toMap() map[string]interface{} {
 //		NoReply: true,
 //	})
 func (t Term) Exec(s QueryExecutor, optArgs ...ExecOpts) error {
+	if t.orderedInsert {
+		return errOrderedInsertRun
+	}
+
 	opts := map[string]interface{}{}
 	var ctx context.Context = nil // if it's nil connection will form context from connection opts
 	if len(optArgs) >= 1 {
 		opts = optArgs[0].toMap()
 		ctx = optArgs[0].Context
+		if err := validateArrayLimit(optArgs[0].ArrayLimit); err != nil {
+			return err
+		}
 	}
 
 	if s == nil || !s.IsConnected() {
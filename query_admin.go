@@ -1,6 +1,8 @@
 package rethinkdb
 
 import (
+	"fmt"
+
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -17,6 +19,11 @@ func (t Term) Rebalance() Term {
 }
 
 // ReconfigureOpts contains the optional arguments for the Reconfigure term.
+//
+// The number of replicas can either be set using Replicas, which accepts any
+// value, or using one of the typed fields ReplicasInt and ReplicasByTag. Only
+// one of these fields may be set, ReplicasByTag also requires PrimaryReplicaTag
+// to be set.
 type ReconfigureOpts struct {
 	Shards               interface{} `rethinkdb:"shards,omitempty"`
 	Replicas             interface{} `rethinkdb:"replicas,omitempty"`
@@ -24,19 +31,86 @@ type ReconfigureOpts struct {
 	EmergencyRepair      interface{} `rethinkdb:"emergency_repair,omitempty"`
 	NonVotingReplicaTags interface{} `rethinkdb:"nonvoting_replica_tags,omitempty"`
 	PrimaryReplicaTag    interface{} `rethinkdb:"primary_replica_tag,omitempty"`
+
+	// ReplicasInt sets the same number of replicas for every shard.
+	ReplicasInt int `rethinkdb:"-"`
+	// ReplicasByTag sets the number of replicas for each server tag.
+	ReplicasByTag map[string]int `rethinkdb:"-"`
 }
 
 func (o ReconfigureOpts) toMap() map[string]interface{} {
-	return optArgsToMap(o)
+	opts := optArgsToMap(o)
+	if o.ReplicasInt > 0 {
+		opts["replicas"] = o.ReplicasInt
+	}
+	if len(o.ReplicasByTag) > 0 {
+		replicas := make(map[string]interface{}, len(o.ReplicasByTag))
+		for tag, n := range o.ReplicasByTag {
+			replicas[tag] = n
+		}
+		opts["replicas"] = replicas
+	}
+
+	return opts
+}
+
+func (o ReconfigureOpts) validate() error {
+	set := 0
+	if o.Replicas != nil {
+		set++
+	}
+	if o.ReplicasInt != 0 {
+		set++
+	}
+	if o.ReplicasByTag != nil {
+		set++
+	}
+	if set > 1 {
+		return RQLDriverError{rqlError("Reconfigure: only one of Replicas, ReplicasInt and ReplicasByTag can be set")}
+	}
+	if set == 0 && o.EmergencyRepair == nil {
+		return RQLDriverError{rqlError("Reconfigure: one of Replicas, ReplicasInt and ReplicasByTag must be set")}
+	}
+
+	if o.ReplicasInt < 0 {
+		return RQLDriverError{rqlError(fmt.Sprintf("Reconfigure: ReplicasInt must be positive, got %d", o.ReplicasInt))}
+	}
+	if o.ReplicasByTag != nil {
+		if len(o.ReplicasByTag) == 0 {
+			return RQLDriverError{rqlError("Reconfigure: ReplicasByTag must not be empty")}
+		}
+		for tag, n := range o.ReplicasByTag {
+			if n < 0 {
+				return RQLDriverError{rqlError(fmt.Sprintf("Reconfigure: replicas for tag %q must not be negative, got %d", tag, n))}
+			}
+		}
+		if o.PrimaryReplicaTag == nil {
+			return RQLDriverError{rqlError("Reconfigure: PrimaryReplicaTag must be set when using ReplicasByTag")}
+		}
+	}
+
+	return nil
 }
 
 // Reconfigure a table's sharding and replication.
+//
+// If the options are invalid, for example both ReplicasInt and ReplicasByTag
+// are set or none of the replicas options are set without EmergencyRepair,
+// then an error is returned when the query is run.
 func (t Term) Reconfigure(optArgs ...ReconfigureOpts) Term {
 	opts := map[string]interface{}{}
+	var err error
 	if len(optArgs) >= 1 {
+		err = optArgs[0].validate()
 		opts = optArgs[0].toMap()
 	}
-	return constructMethodTerm(t, "Reconfigure", p.Term_RECONFIGURE, []interface{}{}, opts)
+
+	t = constructMethodTerm(t, "Reconfigure", p.Term_RECONFIGURE, []interface{}{}, opts)
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
 }
 
 // Status return the status of a table
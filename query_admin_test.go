@@ -0,0 +1,222 @@
+package rethinkdb
+
+import (
+	"time"
+
+	test "gopkg.in/check.v1"
+)
+
+type QueryAdminSuite struct{}
+
+var _ = test.Suite(&QueryAdminSuite{})
+
+func (s *QueryAdminSuite) TestReconfigure_ReplicasInt(c *test.C) {
+	built, err := Table("test").Reconfigure(ReconfigureOpts{
+		Shards:      2,
+		ReplicasInt: 3,
+	}).Build()
+	c.Assert(err, test.IsNil)
+
+	optArgs := built.([]interface{})[2].(map[string]interface{})
+	c.Assert(optArgs["shards"], test.Equals, int64(2))
+	c.Assert(optArgs["replicas"], test.Equals, 3)
+}
+
+func (s *QueryAdminSuite) TestReconfigure_ReplicasByTag(c *test.C) {
+	built, err := Table("test").Reconfigure(ReconfigureOpts{
+		Shards:            1,
+		ReplicasByTag:     map[string]int{"us_east": 2, "us_west": 1},
+		PrimaryReplicaTag: "us_east",
+	}).Build()
+	c.Assert(err, test.IsNil)
+
+	optArgs := built.([]interface{})[2].(map[string]interface{})
+	c.Assert(optArgs["replicas"], test.DeepEquals, map[string]interface{}{"us_east": 2, "us_west": 1})
+	c.Assert(optArgs["primary_replica_tag"], test.Equals, "us_east")
+}
+
+func (s *QueryAdminSuite) TestReconfigure_MultipleReplicasFields(c *test.C) {
+	_, err := Table("test").Reconfigure(ReconfigureOpts{
+		Shards:        1,
+		ReplicasInt:   1,
+		ReplicasByTag: map[string]int{"us_east": 1},
+	}).Build()
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+
+	_, err = Table("test").Reconfigure(ReconfigureOpts{
+		Shards:      1,
+		Replicas:    1,
+		ReplicasInt: 1,
+	}).Build()
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+}
+
+func (s *QueryAdminSuite) TestReconfigure_NoReplicasFields(c *test.C) {
+	_, err := Table("test").Reconfigure(ReconfigureOpts{
+		Shards: 1,
+	}).Build()
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+
+	// Emergency repairs don't change the number of replicas
+	_, err = Table("test").Reconfigure(ReconfigureOpts{
+		EmergencyRepair: "unsafe_rollback",
+	}).Build()
+	c.Assert(err, test.IsNil)
+}
+
+func (s *QueryAdminSuite) TestReconfigure_ReplicasByTagWithoutPrimary(c *test.C) {
+	_, err := Table("test").Reconfigure(ReconfigureOpts{
+		Shards:        1,
+		ReplicasByTag: map[string]int{"us_east": 1},
+	}).Build()
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+}
+
+func (s *QueryAdminSuite) TestRunningJobs(c *test.C) {
+	startedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock := NewMock()
+	mock.On(DB("rethinkdb").Table("jobs").Merge(func(job Term) Term {
+		return Expr(map[string]interface{}{
+			"started_at": Now().Sub(job.Field("duration_sec")),
+		})
+	})).Return([]interface{}{
+		map[string]interface{}{
+			"id":           []interface{}{"query", "a"},
+			"type":         "query",
+			"duration_sec": 1.5,
+			"started_at":   startedAt,
+			"servers":      []interface{}{"server1"},
+			"info":         map[string]interface{}{"user": "admin"},
+		},
+	}, nil)
+
+	jobs, err := runningJobs(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(jobs, test.HasLen, 1)
+	c.Assert(jobs[0].ID, test.DeepEquals, []string{"query", "a"})
+	c.Assert(jobs[0].Type, test.Equals, "query")
+	c.Assert(jobs[0].Duration, test.Equals, 1500*time.Millisecond)
+	c.Assert(jobs[0].StartedAt.Equal(startedAt), test.Equals, true)
+	c.Assert(jobs[0].Servers, test.DeepEquals, []string{"server1"})
+	c.Assert(jobs[0].Info, test.DeepEquals, map[string]interface{}{"user": "admin"})
+	mock.AssertExpectations(c)
+}
+
+func (s *QueryAdminSuite) TestLabelJobs(c *test.C) {
+	jobs := []Job{
+		{Type: "query", Info: map[string]interface{}{"client_address": "127.0.0.1", "client_port": float64(40000)}},
+		{Type: "query", Info: map[string]interface{}{"client_address": "127.0.0.1", "client_port": float64(40001)}},
+		{Type: "query", Info: map[string]interface{}{"client_address": "::1", "client_port": float64(40000)}},
+		{Type: "query", Info: map[string]interface{}{}},
+		{Type: "disk_compaction", Info: map[string]interface{}{}},
+	}
+	labelJobs(jobs, "billing-1", map[string]bool{"127.0.0.1:40000": true, "[::1]:40000": true})
+	c.Assert(jobs[0].Application, test.Equals, "billing-1")
+	c.Assert(jobs[1].Application, test.Equals, "")
+	c.Assert(jobs[2].Application, test.Equals, "billing-1")
+	c.Assert(jobs[3].Application, test.Equals, "")
+	c.Assert(jobs[4].Application, test.Equals, "")
+}
+
+func (s *QueryAdminSuite) TestKillJob(c *test.C) {
+	mock := NewMock()
+	mock.On(DB("rethinkdb").Table("jobs").Get([]string{"query", "a"}).Delete()).Return(map[string]interface{}{"deleted": 1}, nil)
+
+	c.Assert(killJob(mock, []string{"query", "a"}), test.IsNil)
+	mock.AssertExpectations(c)
+}
+
+func (s *QueryAdminSuite) TestSystemTables(c *test.C) {
+	started := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock := NewMock()
+	mock.On(DB("app").Table("users").Status()).Return(map[string]interface{}{
+		"id":          "31c92680-f70c-4a4b-a49e-b238eb12c023",
+		"name":        "users",
+		"db":          "app",
+		"raft_leader": "server1",
+		"status": map[string]interface{}{
+			"all_replicas_ready":       false,
+			"ready_for_outdated_reads": true,
+			"ready_for_reads":          true,
+			"ready_for_writes":         true,
+		},
+		"shards": []interface{}{map[string]interface{}{
+			"primary_replicas": []interface{}{"server1"},
+			"replicas": []interface{}{
+				map[string]interface{}{"server": "server1", "state": "ready"},
+				map[string]interface{}{"server": "server2", "state": "backfilling"},
+			},
+		}},
+	}, nil)
+	mock.On(DB("app").Table("users").Config()).Return(map[string]interface{}{
+		"name":        "users",
+		"db":          "app",
+		"primary_key": "id",
+		"shards": []interface{}{map[string]interface{}{
+			"primary_replica":    "server1",
+			"replicas":           []interface{}{"server1", "server2"},
+			"nonvoting_replicas": []interface{}{},
+		}},
+		"indexes":    []interface{}{"email"},
+		"write_acks": "majority",
+		"durability": "hard",
+	}, nil)
+	mock.On(DB("rethinkdb").Table("server_status")).Return([]interface{}{map[string]interface{}{
+		"name": "server1",
+		"network": map[string]interface{}{
+			"hostname":            "db1",
+			"reql_port":           28015,
+			"http_admin_port":     "<no http admin>",
+			"canonical_addresses": []interface{}{map[string]interface{}{"host": "10.0.0.1", "port": 29015}},
+			"connected_to":        map[string]interface{}{"server2": true},
+			"time_connected":      started,
+		},
+		"process": map[string]interface{}{"pid": 42, "version": "rethinkdb 2.4.0", "time_started": started},
+	}}, nil)
+	mock.On(DB("rethinkdb").Table("stats")).Return([]interface{}{
+		map[string]interface{}{
+			"id":           []interface{}{"cluster"},
+			"query_engine": map[string]interface{}{"queries_per_sec": 1.5, "client_connections": 3},
+		},
+		map[string]interface{}{
+			"id":           []interface{}{"table_server", "t1", "s1"},
+			"db":           "app",
+			"table":        "users",
+			"server":       "server1",
+			"query_engine": map[string]interface{}{"read_docs_total": 100},
+		},
+	}, nil)
+
+	status, err := System(mock).TableStatus("app", "users")
+	c.Assert(err, test.IsNil)
+	c.Assert(status.Status, test.Equals, TableReadiness{ReadyForOutdatedReads: true, ReadyForReads: true, ReadyForWrites: true})
+	c.Assert(status.Shards, test.DeepEquals, []TableShardStatus{{
+		PrimaryReplicas: []string{"server1"},
+		Replicas:        []ReplicaStatus{{Server: "server1", State: "ready"}, {Server: "server2", State: "backfilling"}},
+	}})
+
+	config, err := System(mock).TableConfig("app", "users")
+	c.Assert(err, test.IsNil)
+	c.Assert(config.PrimaryKey, test.Equals, "id")
+	c.Assert(config.Shards[0].Replicas, test.DeepEquals, []string{"server1", "server2"})
+	c.Assert(config.Indexes, test.DeepEquals, []string{"email"})
+	c.Assert(config.WriteAcks, test.Equals, "majority")
+
+	servers, err := System(mock).ServerStatus()
+	c.Assert(err, test.IsNil)
+	c.Assert(servers, test.HasLen, 1)
+	c.Assert(servers[0].Network.ReqlPort, test.Equals, 28015)
+	c.Assert(servers[0].Network.HTTPAdminPort, test.Equals, "<no http admin>")
+	c.Assert(servers[0].Network.CanonicalAddresses, test.DeepEquals, []ServerAddress{{Host: "10.0.0.1", Port: 29015}})
+	c.Assert(servers[0].Network.ConnectedTo, test.DeepEquals, map[string]bool{"server2": true})
+	c.Assert(servers[0].Process.TimeStarted.Equal(started), test.Equals, true)
+	c.Assert(servers[0].Process.PID, test.Equals, 42)
+
+	stats, err := System(mock).Stats()
+	c.Assert(err, test.IsNil)
+	c.Assert(stats, test.HasLen, 2)
+	c.Assert(stats[0].QueryEngine, test.Equals, QueryEngineStats{QueriesPerSec: 1.5, ClientConnections: 3})
+	c.Assert(stats[1].ID, test.DeepEquals, []string{"table_server", "t1", "s1"})
+	c.Assert(stats[1].QueryEngine.ReadDocsTotal, test.Equals, int64(100))
+	mock.AssertExpectations(c)
+}
@@ -1,6 +1,11 @@
 package rethinkdb
 
-import p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+import (
+	"fmt"
+	"reflect"
+
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
 
 // Aggregation
 // These commands are used to compute smaller values from large sequences.
@@ -18,7 +23,26 @@ func (t Term) Reduce(args ...interface{}) Term {
 	return constructMethodTerm(t, "Reduce", p.Term_REDUCE, funcWrapArgs(args), map[string]interface{}{})
 }
 
+// ReduceWithBase is like Reduce but returns base instead of an error when the
+// sequence is empty, for example this query returns 0 for an empty table:
+//
+//	r.Table("orders").Field("total").ReduceWithBase(0, func(left, right r.Term) interface{} {
+//		return left.Add(right)
+//	})
+//
+// Unlike Fold the base value is not passed to the reduction function, so the
+// reduction can still be distributed. The result of Reduce is wrapped with
+// Default, so other non-existence errors raised by the reduction function,
+// such as a missing field, also return base.
+func (t Term) ReduceWithBase(base, fn interface{}) Term {
+	return t.Reduce(fn).Default(base)
+}
+
 // DistinctOpts contains the optional arguments for the Distinct term
+//
+// Index is the name of a secondary index (or a term returning it), the
+// distinct values of the index are returned instead of distinct documents.
+// Other types cause an error when the query is run.
 type DistinctOpts struct {
 	Index interface{} `rethinkdb:"index,omitempty"`
 }
@@ -27,22 +51,69 @@ func (o DistinctOpts) toMap() map[string]interface{} {
 	return optArgsToMap(o)
 }
 
+func (o DistinctOpts) validate() error {
+	switch index := o.Index.(type) {
+	case nil, Term:
+		return nil
+	case string:
+		if index == "" {
+			return RQLDriverError{rqlError("Distinct: the Index must not be empty")}
+		}
+		return nil
+	}
+	return RQLDriverError{rqlError(fmt.Sprintf("Distinct: invalid Index %T, expected the name of an index", o.Index))}
+}
+
+// distinctTerm sets the error of a Distinct term if the options are invalid,
+// or if an index is used with a receiver which is known not to be a table.
+func distinctTerm(term, arg Term, opts DistinctOpts) Term {
+	if err := opts.validate(); err != nil {
+		term.lastErr = err
+	} else if opts.Index != nil && !indexOrderable(arg) {
+		name := arg.name
+		if arg.termType == p.Term_DATUM || arg.termType == p.Term_MAKE_ARRAY || arg.termType == p.Term_MAKE_OBJ {
+			name = "Expr"
+		}
+		term.lastErr = RQLDriverError{rqlError(fmt.Sprintf("Distinct: an index can only be used on a table, got %s", name))}
+	}
+	return term
+}
+
 // Distinct removes duplicate elements from the sequence.
+//
+// Without an index the server computes the distinct elements of a stream in
+// memory, so a query fails once the result is larger than the array limit
+// (100,000 elements by default, see RunOpts.ArrayLimit). Tables can use an
+// index to return distinct index values without this limit, for other large
+// sequences Cursor.AllDistinct removes duplicates on the client instead.
 func Distinct(arg interface{}, optArgs ...DistinctOpts) Term {
 	opts := map[string]interface{}{}
 	if len(optArgs) >= 1 {
 		opts = optArgs[0].toMap()
 	}
-	return constructRootTerm("Distinct", p.Term_DISTINCT, []interface{}{arg}, opts)
+	term := constructRootTerm("Distinct", p.Term_DISTINCT, []interface{}{arg}, opts)
+	if len(optArgs) == 0 {
+		return term
+	}
+	argt, ok := arg.(Term)
+	if !ok {
+		argt = Expr(arg)
+	}
+	return distinctTerm(term, argt, optArgs[0])
 }
 
-// Distinct removes duplicate elements from the sequence.
+// Distinct removes duplicate elements from the sequence, see the root
+// Distinct function for the limits of distinct.
 func (t Term) Distinct(optArgs ...DistinctOpts) Term {
 	opts := map[string]interface{}{}
 	if len(optArgs) >= 1 {
 		opts = optArgs[0].toMap()
 	}
-	return constructMethodTerm(t, "Distinct", p.Term_DISTINCT, []interface{}{}, opts)
+	term := constructMethodTerm(t, "Distinct", p.Term_DISTINCT, []interface{}{}, opts)
+	if len(optArgs) == 0 {
+		return term
+	}
+	return distinctTerm(term, t, optArgs[0])
 }
 
 // GroupOpts contains the optional arguments for the Group term
@@ -58,6 +129,26 @@ func (o GroupOpts) toMap() map[string]interface{} {
 // Group takes a stream and partitions it into multiple groups based on the
 // fields or functions provided. Commands chained after group will be
 // called on each of these grouped sub-streams, producing grouped data.
+//
+// Unless the group_format run option is set to "raw" or "map" grouped data is
+// returned as a sequence of objects with the fields "group" (the value of the
+// group, an array if grouping by multiple fields) and "reduction" (the result
+// of the commands chained after group). When decoding into a struct the fields
+// must be tagged with these names:
+//
+//     var results []struct {
+//         Group     string  `rethinkdb:"group"`
+//         Reduction float64 `rethinkdb:"reduction"`
+//     }
+//     res, err := r.Table("orders").Group("customer").Map(func(row r.Term) interface{} {
+//         return row.Field("total")
+//     }).Reduce(func(left, right r.Term) interface{} {
+//         return left.Add(right)
+//     }).Run(session)
+//     err = res.All(&results)
+//
+// Grouped data can also be read into a map keyed by the groups, see
+// Cursor.All.
 func Group(fieldOrFunctions ...interface{}) Term {
 	return constructRootTerm("Group", p.Term_GROUP, funcWrapArgs(fieldOrFunctions), map[string]interface{}{})
 }
@@ -103,6 +194,8 @@ func MultiGroupByIndex(index interface{}, fieldOrFunctions ...interface{}) Term
 // Group takes a stream and partitions it into multiple groups based on the
 // fields or functions provided. Commands chained after group will be
 // called on each of these grouped sub-streams, producing grouped data.
+//
+// See the root Group function for details of how grouped data is decoded.
 func (t Term) Group(fieldOrFunctions ...interface{}) Term {
 	return constructMethodTerm(t, "Group", p.Term_GROUP, funcWrapArgs(fieldOrFunctions), map[string]interface{}{})
 }
@@ -163,11 +256,76 @@ func Contains(args ...interface{}) Term {
 
 // Contains returns whether or not a sequence contains all the specified values,
 // or if functions are provided instead, returns whether or not a sequence
-// contains values matching all the specified functions.
+// contains values matching all the specified functions. ContainsValue and
+// ContainsFunc can be used to make the intent explicit.
 func (t Term) Contains(args ...interface{}) Term {
 	return constructMethodTerm(t, "Contains", p.Term_CONTAINS, funcWrapArgs(args), map[string]interface{}{})
 }
 
+// ContainsValue returns whether or not a sequence contains all the specified
+// values. Unlike Contains the values are never treated as predicates, passing
+// a function returns an error when the query is run, use ContainsFunc instead.
+func (t Term) ContainsValue(values ...interface{}) Term {
+	var err error
+	for i, v := range values {
+		if isFuncArg(v) {
+			err = RQLDriverError{rqlError(fmt.Sprintf("ContainsValue: argument %d is a function, use ContainsFunc instead", i))}
+			break
+		}
+	}
+
+	t = constructMethodTerm(t, "Contains", p.Term_CONTAINS, values, map[string]interface{}{})
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
+}
+
+// ContainsFunc returns whether or not a sequence contains values matching all
+// the specified predicates. Each predicate must be a function which accepts a
+// single argument or, like Contains, an expression using Row, otherwise an
+// error is returned when the query is run.
+func (t Term) ContainsFunc(predicates ...interface{}) Term {
+	var err error
+	for i, f := range predicates {
+		if err = validatePredicate("ContainsFunc", i, f); err != nil {
+			break
+		}
+	}
+
+	t = constructMethodTerm(t, "Contains", p.Term_CONTAINS, funcWrapArgs(predicates), map[string]interface{}{})
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
+}
+
+// isFuncArg returns true if v is a Go function or a function term.
+func isFuncArg(v interface{}) bool {
+	if term, ok := v.(Term); ok {
+		return term.termType == p.Term_FUNC
+	}
+	return v != nil && reflect.TypeOf(v).Kind() == reflect.Func
+}
+
+// validatePredicate returns an error if argument i of the term is not a
+// function accepting a single argument or an expression using Row.
+func validatePredicate(term string, i int, f interface{}) error {
+	if ft, ok := f.(Term); ok && (ft.termType == p.Term_FUNC || implVarScan(ft)) {
+		return nil
+	}
+	if f != nil && reflect.TypeOf(f).Kind() == reflect.Func {
+		if n := reflect.TypeOf(f).NumIn(); n != 1 {
+			return RQLDriverError{rqlError(fmt.Sprintf("%s: argument %d must accept a single argument, got %d", term, i, n))}
+		}
+		return nil
+	}
+
+	return RQLDriverError{rqlError(fmt.Sprintf("%s: argument %d must be a function, got %T", term, i, f))}
+}
+
 // Aggregators
 // These standard aggregator objects are to be used in conjunction with Group.
 
@@ -272,6 +430,26 @@ func (t Term) MinIndex(index interface{}, args ...interface{}) Term {
 	})
 }
 
+// MinByField returns the element of the sequence with the smallest value in
+// the given field, elements without the field are ignored.
+func (t Term) MinByField(field string) Term {
+	return t.Min(field)
+}
+
+// MinByIndex returns the document of the table with the smallest value in the
+// given secondary index, using the index is much faster than reading every
+// document.
+func (t Term) MinByIndex(index string) Term {
+	return t.MinIndex(index)
+}
+
+// MinByFunc calls f on every element of the sequence and returns the element
+// which produced the smallest value, ignoring any elements where f returns
+// null or produces a non-existence error.
+func (t Term) MinByFunc(f func(Term) Term) Term {
+	return t.Min(f)
+}
+
 // MaxOpts contains the optional arguments for the Max term
 type MaxOpts struct {
 	Index interface{} `rethinkdb:"index,omitempty"`
@@ -321,6 +499,26 @@ func (t Term) MaxIndex(index interface{}, args ...interface{}) Term {
 	})
 }
 
+// MaxByField returns the element of the sequence with the largest value in
+// the given field, elements without the field are ignored.
+func (t Term) MaxByField(field string) Term {
+	return t.Max(field)
+}
+
+// MaxByIndex returns the document of the table with the largest value in the
+// given secondary index, using the index is much faster than reading every
+// document.
+func (t Term) MaxByIndex(index string) Term {
+	return t.MaxIndex(index)
+}
+
+// MaxByFunc calls f on every element of the sequence and returns the element
+// which produced the largest value, ignoring any elements where f returns
+// null or produces a non-existence error.
+func (t Term) MaxByFunc(f func(Term) Term) Term {
+	return t.Max(f)
+}
+
 // FoldOpts contains the optional arguments for the Fold term
 type FoldOpts struct {
 	Emit      interface{} `rethinkdb:"emit,omitempty"`
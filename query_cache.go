@@ -0,0 +1,81 @@
+package rethinkdb
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+)
+
+const (
+	// maxCachedQueries is the maximum number of terms cached by a queryCache.
+	maxCachedQueries = 1024
+	// maxCachedQueryBytes is the maximum size of the JSON encoding of a
+	// cached term, larger terms are built every time.
+	maxCachedQueryBytes = 4096
+)
+
+// queryCache caches the JSON encoding of built terms, keyed by their hash,
+// see ConnectOpts.CacheSerializedQueries. The least recently used term is
+// evicted once maxCachedQueries terms are cached. The zero value is an empty
+// cache.
+type queryCache struct {
+	mu      sync.Mutex
+	entries map[uint64]*list.Element
+	lru     list.List // of *cachedQuery, most recently used first
+}
+
+type cachedQuery struct {
+	key        uint64
+	term       Term
+	serialized json.RawMessage
+}
+
+// build returns the JSON encoding of t, reusing the encoding of an earlier
+// term which is Equal to t if it is cached. Terms which fail to build, write
+// terms and terms whose encoding is larger than maxCachedQueryBytes are not
+// cached.
+func (c *queryCache) build(t Term) (interface{}, error) {
+	if t.rawQuery || t.lastErr != nil || isWriteTerm(&t) {
+		return t.Build()
+	}
+
+	key := t.Hash()
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok && elem.Value.(*cachedQuery).term.Equal(t) {
+		c.lru.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*cachedQuery).serialized, nil
+	}
+	c.mu.Unlock()
+
+	built, err := t.Build()
+	if err != nil {
+		return nil, err
+	}
+	var serialized json.RawMessage
+	serialized, err = json.Marshal(built)
+	if err != nil {
+		// The error is reported when the query is sent
+		return built, nil
+	}
+	if len(serialized) > maxCachedQueryBytes {
+		return serialized, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[uint64]*list.Element)
+	}
+	if elem, ok := c.entries[key]; ok {
+		// Replace a term with the same hash
+		c.lru.Remove(elem)
+	} else if len(c.entries) >= maxCachedQueries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedQuery).key)
+	}
+	c.entries[key] = c.lru.PushFront(&cachedQuery{key: key, term: t, serialized: serialized})
+
+	return serialized, nil
+}
@@ -3,9 +3,12 @@ package rethinkdb
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"time"
 
 	"reflect"
 
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -157,23 +160,98 @@ func Expr(val interface{}) Term {
 	}
 }
 
+// ToMap converts a struct or map to the map which Expr would send to the
+// database, after the value has been encoded but before it is serialized to
+// JSON. This can be useful when testing or debugging struct tags, see Expr
+// for how values are encoded.
+//
+//	m, err := r.ToMap(user)
+//	// m == map[string]interface{}{"id": "1", "name": "bob"}
+func ToMap(v interface{}) (map[string]interface{}, error) {
+	data, err := encoding.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, RQLDriverError{rqlError(fmt.Sprintf("Cannot convert value of type %T to a map", v))}
+	}
+
+	return m, nil
+}
+
 // JSOpts contains the optional arguments for the JS term
+//
+// Timeout accepts the number of seconds the JavaScript may run for,
+// alternatively TimeoutDuration can be used to set the timeout as a
+// time.Duration. Only one of Timeout and TimeoutDuration may be set.
 type JSOpts struct {
 	Timeout interface{} `rethinkdb:"timeout,omitempty"`
+
+	// TimeoutDuration is how long the JavaScript may run for before the
+	// query fails.
+	TimeoutDuration time.Duration `rethinkdb:"-"`
 }
 
 func (o JSOpts) toMap() map[string]interface{} {
-	return optArgsToMap(o)
+	opts := optArgsToMap(o)
+	if o.TimeoutDuration > 0 {
+		opts["timeout"] = o.TimeoutDuration.Seconds()
+	}
+
+	return opts
+}
+
+func (o JSOpts) validate() error {
+	if o.Timeout != nil && o.TimeoutDuration != 0 {
+		return RQLDriverError{rqlError("JS: only one of Timeout and TimeoutDuration can be set")}
+	}
+	if o.TimeoutDuration < 0 {
+		return RQLDriverError{rqlError(fmt.Sprintf("JS: TimeoutDuration must be positive, got %v", o.TimeoutDuration))}
+	}
+	positive := true
+	switch v := reflect.ValueOf(o.Timeout); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		positive = v.Int() > 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		positive = v.Uint() > 0
+	case reflect.Float32, reflect.Float64:
+		positive = v.Float() > 0
+	}
+	if !positive {
+		return RQLDriverError{rqlError(fmt.Sprintf("JS: Timeout must be positive, got %v", o.Timeout))}
+	}
+
+	return nil
 }
 
 // JS creates a JavaScript expression which is evaluated by the database when
 // running the query.
+//
+// The value returned by the JavaScript is decoded like the result of any
+// other query, so it can be read into a value of the expected type, for
+// example:
+//
+//	var sum int
+//	err := r.JS("1 + 2").ReadOne(&sum, session)
+//
+// If the options are invalid, for example the timeout is not positive, then
+// an error is returned when the query is run.
 func JS(jssrc interface{}, optArgs ...JSOpts) Term {
 	opts := map[string]interface{}{}
+	var err error
 	if len(optArgs) >= 1 {
+		err = optArgs[0].validate()
 		opts = optArgs[0].toMap()
 	}
-	return constructRootTerm("Js", p.Term_JAVASCRIPT, []interface{}{jssrc}, opts)
+
+	t := constructRootTerm("Js", p.Term_JAVASCRIPT, []interface{}{jssrc}, opts)
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
 }
 
 // HTTPOpts contains the optional arguments for the HTTP term
@@ -219,6 +297,22 @@ func JSON(args ...interface{}) Term {
 
 // Error throws a runtime error. If called with no arguments inside the second argument
 // to `default`, re-throw the current error.
+//
+// When the error fails the query it is returned as an RQLUserError, the
+// message passed to Error can be retrieved using its Message method. If the
+// error is raised while writing documents, for example in the function passed
+// to Update, then the query does not fail and RunWrite returns an
+// RQLWriteError containing the message.
+//
+//	_, err := r.Table("orders").Get(id).Update(func(order r.Term) interface{} {
+//		return r.Branch(order.Field("state").Eq("shipped"),
+//			r.Error("order already shipped"),
+//			map[string]interface{}{"state": "cancelled"},
+//		)
+//	}).RunWrite(session)
+//	if writeErr, ok := err.(r.RQLWriteError); ok {
+//		// writeErr.FirstError == "order already shipped"
+//	}
 func Error(args ...interface{}) Term {
 	return constructRootTerm("Error", p.Term_ERROR, args, map[string]interface{}{})
 }
@@ -298,14 +392,54 @@ func Do(args ...interface{}) Term {
 // Branch evaluates one of two control paths based on the value of an expression.
 // branch is effectively an if renamed due to language constraints.
 //
+// Branch also accepts multiple test and value pairs followed by a default
+// value, the value of the first test which is true is returned:
+//
+//	r.Branch(
+//		r.Row.Field("age").Lt(13), "child",
+//		r.Row.Field("age").Lt(18), "teen",
+//		"adult",
+//	)
+//
 // The type of the result is determined by the type of the branch that gets executed.
 func Branch(args ...interface{}) Term {
 	return constructRootTerm("Branch", p.Term_BRANCH, args, map[string]interface{}{})
 }
 
+// Case is a test and the value returned when the test is true, see Switch.
+type Case struct {
+	Test  interface{}
+	Value interface{}
+}
+
+// Switch returns the value of the first case whose test is true, or def if
+// none of the tests are true. It is equivalent to calling Branch with the
+// test and value of each case followed by the default value:
+//
+//	r.Switch([]r.Case{
+//		{Test: r.Row.Field("age").Lt(13), Value: "child"},
+//		{Test: r.Row.Field("age").Lt(18), Value: "teen"},
+//	}, "adult")
+func Switch(cases []Case, def interface{}) Term {
+	if len(cases) == 0 {
+		return Expr(def)
+	}
+
+	args := make([]interface{}, 0, len(cases)*2+1)
+	for _, c := range cases {
+		args = append(args, c.Test, c.Value)
+	}
+	args = append(args, def)
+
+	return Branch(args...)
+}
+
 // Branch evaluates one of two control paths based on the value of an expression.
 // branch is effectively an if renamed due to language constraints.
 //
+// Like the Branch function multiple test and value pairs may be passed, the
+// term is used as the first test.
+//
 // The type of the result is determined by the type of the branch that gets executed.
 func (t Term) Branch(args ...interface{}) Term {
 	return constructMethodTerm(t, "Branch", p.Term_BRANCH, args, map[string]interface{}{})
@@ -319,6 +453,9 @@ func (t Term) Branch(args ...interface{}) Term {
 //     r.Table("table").ForEach(func (row r.Term) interface{} {
 //         return r.Table("new_table").Insert(row)
 //     })
+//
+// The results of the writes are combined into a single write response, when
+// run using RunWrite any failed writes are returned as an RQLWriteError.
 func (t Term) ForEach(args ...interface{}) Term {
 	return constructMethodTerm(t, "Foreach", p.Term_FOR_EACH, funcWrapArgs(args), map[string]interface{}{})
 }
@@ -346,16 +483,57 @@ func (t Term) CoerceTo(args ...interface{}) Term {
 	return constructMethodTerm(t, "CoerceTo", p.Term_COERCE_TO, args, map[string]interface{}{})
 }
 
+// DatumType is the name of a type as returned by TypeOf, the result of TypeOf
+// can be decoded into a DatumType.
+type DatumType string
+
+// Types returned by TypeOf.
+const (
+	TypeArray           DatumType = "ARRAY"
+	TypeBool            DatumType = "BOOL"
+	TypeDB              DatumType = "DB"
+	TypeFunction        DatumType = "FUNCTION"
+	TypeGroupedData     DatumType = "GROUPED_DATA"
+	TypeGroupedStream   DatumType = "GROUPED_STREAM"
+	TypeMaxVal          DatumType = "MAXVAL"
+	TypeMinVal          DatumType = "MINVAL"
+	TypeNull            DatumType = "NULL"
+	TypeNumber          DatumType = "NUMBER"
+	TypeObject          DatumType = "OBJECT"
+	TypeBinary          DatumType = "PTYPE<BINARY>"
+	TypeGeometry        DatumType = "PTYPE<GEOMETRY>"
+	TypeTime            DatumType = "PTYPE<TIME>"
+	TypeSelectionArray  DatumType = "SELECTION<ARRAY>"
+	TypeSelectionObject DatumType = "SELECTION<OBJECT>"
+	TypeSelectionStream DatumType = "SELECTION<STREAM>"
+	TypeStream          DatumType = "STREAM"
+	TypeString          DatumType = "STRING"
+	TypeTable           DatumType = "TABLE"
+	TypeTableSlice      DatumType = "TABLE_SLICE"
+)
+
 // TypeOf gets the type of a value.
 func TypeOf(args ...interface{}) Term {
 	return constructRootTerm("TypeOf", p.Term_TYPE_OF, args, map[string]interface{}{})
 }
 
-// TypeOf gets the type of a value.
+// TypeOf gets the type of a value, the result can be decoded into a
+// DatumType:
+//
+//	var typ r.DatumType
+//	err := r.Expr(value).TypeOf().ReadOne(&typ, session)
 func (t Term) TypeOf(args ...interface{}) Term {
 	return constructMethodTerm(t, "TypeOf", p.Term_TYPE_OF, args, map[string]interface{}{})
 }
 
+// IsType returns true if the type of the value is typ, it is shorthand for
+// t.TypeOf().Eq(typ), for example:
+//
+//	r.Table("users").Filter(r.Row.Field("tags").IsType(r.TypeArray))
+func (t Term) IsType(typ DatumType) Term {
+	return t.TypeOf().Eq(string(typ))
+}
+
 // ToJSON converts a ReQL value or object to a JSON string.
 func (t Term) ToJSON() Term {
 	return constructMethodTerm(t, "ToJSON", p.Term_TO_JSON_STRING, []interface{}{}, map[string]interface{}{})
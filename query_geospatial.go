@@ -1,10 +1,49 @@
 package rethinkdb
 
 import (
+	"fmt"
+
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
+// Unit is a unit of distance used by the geospatial terms.
+type Unit string
+
+// Units of distance accepted by the Unit option of Circle, Distance and
+// GetNearest.
+const (
+	UnitMeter        Unit = "m"
+	UnitKilometer    Unit = "km"
+	UnitMile         Unit = "mi"
+	UnitNauticalMile Unit = "nm"
+	UnitFoot         Unit = "ft"
+)
+
+// validateUnit returns an error if unit is a string which is not a valid unit
+// of distance, other values (such as terms) are left to the server.
+func validateUnit(term string, unit interface{}) error {
+	var u Unit
+	switch v := unit.(type) {
+	case Unit:
+		u = v
+	case string:
+		u = Unit(v)
+	default:
+		return nil
+	}
+
+	switch u {
+	case UnitMeter, UnitKilometer, UnitMile, UnitNauticalMile, UnitFoot:
+		return nil
+	default:
+		return RQLDriverError{rqlError(fmt.Sprintf("%s: invalid unit %q, expected one of m, km, mi, nm or ft", term, string(u)))}
+	}
+}
+
 // CircleOpts contains the optional arguments for the Circle term.
+//
+// Unit accepts one of the Unit constants, invalid units cause an error when
+// the query is run.
 type CircleOpts struct {
 	NumVertices interface{} `rethinkdb:"num_vertices,omitempty"`
 	GeoSystem   interface{} `rethinkdb:"geo_system,omitempty"`
@@ -21,14 +60,24 @@ func (o CircleOpts) toMap() map[string]interface{} {
 // center, consisting of a specified number of vertices (default 32).
 func Circle(point, radius interface{}, optArgs ...CircleOpts) Term {
 	opts := map[string]interface{}{}
+	var err error
 	if len(optArgs) >= 1 {
 		opts = optArgs[0].toMap()
+		err = validateUnit("Circle", optArgs[0].Unit)
 	}
 
-	return constructRootTerm("Circle", p.Term_CIRCLE, []interface{}{point, radius}, opts)
+	t := constructRootTerm("Circle", p.Term_CIRCLE, []interface{}{point, radius}, opts)
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
 }
 
 // DistanceOpts contains the optional arguments for the Distance term.
+//
+// Unit accepts one of the Unit constants, for example UnitKilometer, invalid
+// units cause an error when the query is run.
 type DistanceOpts struct {
 	GeoSystem interface{} `rethinkdb:"geo_system,omitempty"`
 	Unit      interface{} `rethinkdb:"unit,omitempty"`
@@ -42,22 +91,36 @@ func (o DistanceOpts) toMap() map[string]interface{} {
 // of the geometry objects specified must be a point.
 func (t Term) Distance(point interface{}, optArgs ...DistanceOpts) Term {
 	opts := map[string]interface{}{}
+	var err error
 	if len(optArgs) >= 1 {
 		opts = optArgs[0].toMap()
+		err = validateUnit("Distance", optArgs[0].Unit)
+	}
+
+	t = constructMethodTerm(t, "Distance", p.Term_DISTANCE, []interface{}{point}, opts)
+	if err != nil {
+		t.lastErr = err
 	}
 
-	return constructMethodTerm(t, "Distance", p.Term_DISTANCE, []interface{}{point}, opts)
+	return t
 }
 
 // Distance calculates the Haversine distance between two points. At least one
 // of the geometry objects specified must be a point.
 func Distance(point1, point2 interface{}, optArgs ...DistanceOpts) Term {
 	opts := map[string]interface{}{}
+	var err error
 	if len(optArgs) >= 1 {
 		opts = optArgs[0].toMap()
+		err = validateUnit("Distance", optArgs[0].Unit)
+	}
+
+	t := constructRootTerm("Distance", p.Term_DISTANCE, []interface{}{point1, point2}, opts)
+	if err != nil {
+		t.lastErr = err
 	}
 
-	return constructRootTerm("Distance", p.Term_DISTANCE, []interface{}{point1, point2}, opts)
+	return t
 }
 
 // Fill converts a Line object into a Polygon object. If the last point does not
@@ -98,6 +161,10 @@ func (t Term) GetIntersecting(args interface{}, optArgs ...GetIntersectingOpts)
 }
 
 // GetNearestOpts contains the optional arguments for the GetNearest term.
+//
+// Unit accepts one of the Unit constants and is used for both MaxDist and the
+// distances returned by the query, invalid units cause an error when the query
+// is run.
 type GetNearestOpts struct {
 	Index      interface{} `rethinkdb:"index,omitempty"`
 	MaxResults interface{} `rethinkdb:"max_results,omitempty"`
@@ -110,15 +177,37 @@ func (o GetNearestOpts) toMap() map[string]interface{} {
 	return optArgsToMap(o)
 }
 
+// GetNearestResponse is a helper type used when decoding the results of
+// GetNearest, each result contains the distance from the point (in the unit
+// passed to GetNearest) and the document. To decode the documents into a
+// specific type define a similar struct with a typed Doc field:
+//
+//	var results []struct {
+//		Dist float64 `rethinkdb:"dist"`
+//		Doc  Place   `rethinkdb:"doc"`
+//	}
+type GetNearestResponse struct {
+	Dist float64     `rethinkdb:"dist"`
+	Doc  interface{} `rethinkdb:"doc"`
+}
+
 // GetNearest gets all documents where the specified geospatial index is within a
-// certain distance of the specified point (default 100 kilometers).
+// certain distance of the specified point (default 100 kilometers). The results
+// are ordered by distance and can be decoded using GetNearestResponse.
 func (t Term) GetNearest(point interface{}, optArgs ...GetNearestOpts) Term {
 	opts := map[string]interface{}{}
+	var err error
 	if len(optArgs) >= 1 {
 		opts = optArgs[0].toMap()
+		err = validateUnit("GetNearest", optArgs[0].Unit)
+	}
+
+	t = constructMethodTerm(t, "GetNearest", p.Term_GET_NEAREST, []interface{}{point}, opts)
+	if err != nil {
+		t.lastErr = err
 	}
 
-	return constructMethodTerm(t, "GetNearest", p.Term_GET_NEAREST, []interface{}{point}, opts)
+	return t
 }
 
 // Includes tests whether a geometry object is completely contained within another.
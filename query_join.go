@@ -32,6 +32,20 @@ func (o EqJoinOpts) toMap() map[string]interface{} {
 // EqJoin is an efficient join that looks up elements in the right table by primary key.
 //
 // Optional arguments: "index" (string - name of the index to use in right table instead of the primary key)
+//
+// Each result is an object with a "left" and a "right" field containing the
+// matched documents, which can be decoded into a struct such as:
+//
+//	type PostAuthor struct {
+//		Post   Post   `rethinkdb:"left"`
+//		Author Author `rethinkdb:"right"`
+//	}
+//
+// EqJoin is an inner join, left documents whose field is missing or null, or
+// which do not match any document of the right table, are not returned. Use
+// OuterJoin to keep them. The results are not ordered unless the Ordered
+// option is true, in which case they are in the order of the left sequence.
+// See EqJoinZip to merge the matched documents.
 func (t Term) EqJoin(left, right interface{}, optArgs ...EqJoinOpts) Term {
 	opts := map[string]interface{}{}
 	if len(optArgs) >= 1 {
@@ -40,6 +54,15 @@ func (t Term) EqJoin(left, right interface{}, optArgs ...EqJoinOpts) Term {
 	return constructMethodTerm(t, "EqJoin", p.Term_EQ_JOIN, []interface{}{funcWrap(left), right}, opts)
 }
 
+// EqJoinZip joins the sequence with the right table like EqJoin and merges
+// each pair of matched documents using Zip, so the results can be decoded
+// into a single struct containing the fields of both documents. Fields of the
+// right document replace the fields of the left document with the same
+// name, such as the primary key, use Without or Map to rename them first.
+func (t Term) EqJoinZip(left, right interface{}, optArgs ...EqJoinOpts) Term {
+	return t.EqJoin(left, right, optArgs...).Zip()
+}
+
 // Zip is used to 'zip' up the result of a join by merging the 'right' fields into 'left'
 // fields of each member of the sequence.
 func (t Term) Zip(args ...interface{}) Term {
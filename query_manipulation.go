@@ -1,6 +1,10 @@
 package rethinkdb
 
 import (
+	"reflect"
+	"sort"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -44,10 +48,85 @@ func (t Term) Without(args ...interface{}) Term {
 
 // Merge merges two objects together to construct a new object with properties from both.
 // Gives preference to attributes from other when there is a conflict.
+//
+// Nested objects are merged recursively, to replace a nested object instead
+// wrap it with Literal or use MergeShallow. For example if the field "tags"
+// of doc is {"a": 1}:
+//
+//	doc.Merge(map[string]interface{}{"tags": map[string]interface{}{"b": 2}})
+//	// {"tags": {"a": 1, "b": 2}}
+//	doc.MergeShallow(map[string]interface{}{"tags": map[string]interface{}{"b": 2}})
+//	// {"tags": {"b": 2}}
 func (t Term) Merge(args ...interface{}) Term {
 	return constructMethodTerm(t, "Merge", p.Term_MERGE, funcWrapArgs(args), map[string]interface{}{})
 }
 
+// MergeShallow behaves like Merge however the top-level fields of the objects
+// being merged replace the existing fields instead of being merged with them,
+// this is done by wrapping each field which is an object with Literal.
+//
+// Only maps and structs are rewritten, arguments which are terms or functions
+// are evaluated by the database and are merged recursively as with Merge.
+func (t Term) MergeShallow(args ...interface{}) Term {
+	shallowArgs := make([]interface{}, len(args))
+	var err error
+	for i, arg := range args {
+		if shallowArgs[i], err = literalFields(arg); err != nil {
+			break
+		}
+	}
+
+	t = t.Merge(shallowArgs...)
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
+}
+
+// literalFields returns the map or struct v with each field which is an
+// object wrapped with Literal, other values are returned unchanged.
+func literalFields(v interface{}) (interface{}, error) {
+	if _, ok := v.(Term); ok || v == nil {
+		return v, nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Map && rv.Kind() != reflect.Struct {
+		return v, nil
+	}
+
+	fields, ok := v.(map[string]interface{})
+	if !ok {
+		data, err := encoding.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+		if fields, ok = data.(map[string]interface{}); !ok {
+			return v, nil
+		}
+	}
+
+	shallow := make(map[string]interface{}, len(fields))
+	for k, field := range fields {
+		shallow[k] = field
+		if _, ok := field.(Term); ok {
+			continue
+		}
+		data, err := encoding.Encode(field)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := data.(map[string]interface{}); ok {
+			shallow[k] = Literal(field)
+		}
+	}
+
+	return shallow, nil
+}
+
 // Append appends a value to an array.
 func (t Term) Append(args ...interface{}) Term {
 	return constructMethodTerm(t, "Append", p.Term_APPEND, args, map[string]interface{}{})
@@ -116,6 +195,47 @@ func (t Term) Values(args ...interface{}) Term {
 }
 
 // Object creates an object from a list of key-value pairs, where the keys must be strings.
+// See also ObjectFromPairs and ObjectFromMap, which always pass an even number
+// of arguments.
 func Object(args ...interface{}) Term {
 	return constructRootTerm("Object", p.Term_OBJECT, args, map[string]interface{}{})
 }
+
+// KV is a key-value pair used to build an object with ObjectFromPairs. The key
+// may be a term which evaluates to a string.
+type KV struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// ObjectFromPairs creates an object from the key-value pairs, it is useful
+// when the keys are only known when the query is run:
+//
+//	r.ObjectFromPairs([]r.KV{
+//		{Key: r.Row.Field("name"), Value: r.Row.Field("score")},
+//	})
+func ObjectFromPairs(pairs []KV) Term {
+	args := make([]interface{}, 0, len(pairs)*2)
+	for _, kv := range pairs {
+		args = append(args, kv.Key, kv.Value)
+	}
+
+	return Object(args...)
+}
+
+// ObjectFromMap creates an object from the map of keys to terms, the keys are
+// sorted so the query is the same for equal maps.
+func ObjectFromMap(m map[string]Term) Term {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := make([]interface{}, 0, len(m)*2)
+	for _, k := range keys {
+		args = append(args, k, m[k])
+	}
+
+	return Object(args...)
+}
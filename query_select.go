@@ -1,6 +1,10 @@
 package rethinkdb
 
 import (
+	"encoding/json"
+	"errors"
+	"reflect"
+
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -61,6 +65,16 @@ func (t Term) Get(args ...interface{}) Term {
 	return constructMethodTerm(t, "Get", p.Term_GET, args, map[string]interface{}{})
 }
 
+// Exists returns true if the document selected by Get exists, the comparison
+// is done by the database so the document itself is not sent to the client.
+// The result can be decoded into a bool, for example:
+//
+//	var exists bool
+//	err := r.Table("users").Get(id).Exists().ReadOne(&exists, session)
+func (t Term) Exists() Term {
+	return t.Ne(nil)
+}
+
 // GetAllOpts contains the optional arguments for the GetAll term
 type GetAllOpts struct {
 	Index interface{} `rethinkdb:"index,omitempty"`
@@ -74,6 +88,11 @@ func (o GetAllOpts) toMap() map[string]interface{} {
 // index. Multiple values can be passed this function if you want to select multiple
 // documents. If the documents you are fetching have composite keys then each
 // argument should be a slice. For more information see the examples.
+//
+// Keys are not deduplicated, if a key is passed more than once then the
+// documents matching it are returned once for each time it was passed. The
+// order of the returned documents does not follow the order of the keys. Use
+// GetAllDistinct to return each document once.
 func (t Term) GetAll(keys ...interface{}) Term {
 	return constructMethodTerm(t, "GetAll", p.Term_GET_ALL, keys, map[string]interface{}{})
 }
@@ -84,6 +103,106 @@ func (t Term) GetAllByIndex(index interface{}, keys ...interface{}) Term {
 	return constructMethodTerm(t, "GetAll", p.Term_GET_ALL, keys, map[string]interface{}{"index": index})
 }
 
+// GetAllDistinct is like GetAll but removes duplicate keys before the query is
+// sent, so each matching document is returned once. ExpandByKey can be used to
+// arrange the decoded documents to match the original keys.
+func (t Term) GetAllDistinct(keys ...interface{}) Term {
+	return t.GetAll(DistinctKeys(keys...)...)
+}
+
+// GetAllDistinctByIndex is like GetAllByIndex but removes duplicate keys before
+// the query is sent, see GetAllDistinct.
+func (t Term) GetAllDistinctByIndex(index interface{}, keys ...interface{}) Term {
+	return t.GetAllByIndex(index, DistinctKeys(keys...)...)
+}
+
+// DistinctKeys returns the keys with duplicates removed, keeping the first
+// occurrence of each key. Keys are compared using their encoded values, so
+// for example int(1) and float64(1) are equal. Terms are never considered
+// duplicates as their values are only known by the database.
+func DistinctKeys(keys ...interface{}) []interface{} {
+	distinct := make([]interface{}, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		id, ok := keyID(key)
+		if ok && seen[id] {
+			continue
+		}
+		if ok {
+			seen[id] = true
+		}
+		distinct = append(distinct, key)
+	}
+
+	return distinct
+}
+
+// ExpandByKey sets dest, which must be a pointer to a slice with the same type
+// as docs, to the documents in docs arranged to match keys. For each key the
+// documents whose key, as returned by keyOf for the document at index i of
+// docs, equals it are appended in the order they appear in docs. This restores
+// the results GetAll would have returned for duplicate keys, in the order of
+// the keys, for example:
+//
+//	var users []User
+//	err := r.Table("users").GetAllDistinct(ids...).ReadAll(&users, session)
+//	err = r.ExpandByKey(&users, users, ids, func(i int) interface{} {
+//		return users[i].ID
+//	})
+//
+// Keys which do not match any document are skipped.
+func ExpandByKey(dest interface{}, docs interface{}, keys []interface{}, keyOf func(i int) interface{}) error {
+	dv := reflect.ValueOf(dest)
+	sv := reflect.ValueOf(docs)
+	if sv.Kind() != reflect.Slice {
+		return errors.New("rethinkdb: ExpandByKey: docs must be a slice")
+	}
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Type() != sv.Type() {
+		return errors.New("rethinkdb: ExpandByKey: dest must be a pointer to a slice with the same type as docs")
+	}
+
+	byKey := make(map[string][]int, sv.Len())
+	for i := 0; i < sv.Len(); i++ {
+		id, ok := keyID(keyOf(i))
+		if !ok {
+			return errors.New("rethinkdb: ExpandByKey: document keys must be encodable values")
+		}
+		byKey[id] = append(byKey[id], i)
+	}
+
+	expanded := reflect.MakeSlice(sv.Type(), 0, len(keys))
+	for _, key := range keys {
+		id, ok := keyID(key)
+		if !ok {
+			return errors.New("rethinkdb: ExpandByKey: keys must be encodable values")
+		}
+		for _, i := range byKey[id] {
+			expanded = reflect.Append(expanded, sv.Index(i))
+		}
+	}
+	dv.Elem().Set(expanded)
+
+	return nil
+}
+
+// keyID returns a string identifying the encoded value of key, ok is false if
+// the key is a term or cannot be encoded.
+func keyID(key interface{}) (id string, ok bool) {
+	if _, isTerm := key.(Term); isTerm {
+		return "", false
+	}
+	built, err := Expr(key).Build()
+	if err != nil {
+		return "", false
+	}
+	b, err := json.Marshal(built)
+	if err != nil {
+		return "", false
+	}
+
+	return string(b), true
+}
+
 // BetweenOpts contains the optional arguments for the Between term
 type BetweenOpts struct {
 	Index      interface{} `rethinkdb:"index,omitempty"`
@@ -1,6 +1,9 @@
 package rethinkdb
 
 import (
+	"fmt"
+	"time"
+
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -62,6 +65,112 @@ func (t Term) TableList(args ...interface{}) Term {
 	return constructMethodTerm(t, "TableList", p.Term_TABLE_LIST, args, map[string]interface{}{})
 }
 
+// TableSpec describes a table and its secondary indexes, see EnsureTable.
+// PrimaryKey, Shards and Replicas are only used when the table is created.
+type TableSpec struct {
+	PrimaryKey interface{}
+	Shards     interface{}
+	Replicas   interface{}
+	Indexes    []IndexSpec
+}
+
+// IndexSpec describes a secondary index created by EnsureTable. If Func is nil
+// the index is created on the field with the same name as the index, otherwise
+// Func is used as the index function as with IndexCreateFunc. As the index is
+// created within a subquery Func must be a function rather than a term using
+// Row.
+type IndexSpec struct {
+	Name  string
+	Func  interface{}
+	Multi bool
+	Geo   bool
+}
+
+// EnsureTable creates the table in the default database if it does not exist
+// along with any of the indexes which do not exist, then waits for the table
+// and its indexes to be ready. See Term.EnsureTable.
+func EnsureTable(name string, spec TableSpec) Term {
+	return ensureTable(TableList(), TableCreate(name, spec.createOpts()), Table(name), name, spec)
+}
+
+// EnsureTable creates the table in the database if it does not exist along
+// with any of the indexes which do not exist, then waits for the table and its
+// indexes to be ready. All of this happens as a single query so it can be run
+// again to apply a spec to an existing table, for example when an application
+// starts:
+//
+//	res, err := r.DB("app").EnsureTable("users", r.TableSpec{
+//		Indexes: []r.IndexSpec{
+//			{Name: "email"},
+//			{Name: "full_name", Func: func(row r.Term) interface{} {
+//				return []interface{}{row.Field("last_name"), row.Field("first_name")}
+//			}},
+//		},
+//	}).RunWrite(session)
+//
+// The TablesCreated field of the response is 1 if the table was created and
+// Created is the number of indexes created. Existing indexes are left
+// unchanged even if their definition differs from the spec. If creating an
+// index fails the query returns an error, any table or indexes created before
+// then are kept and are skipped when the query is run again.
+func (t Term) EnsureTable(name string, spec TableSpec) Term {
+	return ensureTable(t.TableList(), t.TableCreate(name, spec.createOpts()), t.Table(name), name, spec)
+}
+
+func (s TableSpec) createOpts() TableCreateOpts {
+	return TableCreateOpts{
+		PrimaryKey: s.PrimaryKey,
+		Shards:     s.Shards,
+		Replicas:   s.Replicas,
+	}
+}
+
+func ensureTable(tableList, tableCreate, table Term, name string, spec TableSpec) Term {
+	createTable := Branch(
+		tableList.Contains(name),
+		map[string]interface{}{"tables_created": 0},
+		tableCreate,
+	)
+
+	createIndexes := make([]interface{}, len(spec.Indexes))
+	indexNames := make([]interface{}, len(spec.Indexes))
+	for i, index := range spec.Indexes {
+		opts := IndexCreateOpts{}
+		if index.Multi {
+			opts.Multi = true
+		}
+		if index.Geo {
+			opts.Geo = true
+		}
+
+		create := table.IndexCreate(index.Name, opts)
+		if index.Func != nil {
+			create = table.IndexCreateFunc(index.Name, index.Func, opts)
+		}
+		createIndexes[i] = Branch(
+			table.IndexList().Contains(index.Name),
+			map[string]interface{}{"created": 0},
+			create,
+		)
+		indexNames[i] = index.Name
+	}
+
+	// Each step is run in the body of the function of the previous step so
+	// the table is ready before its indexes are created
+	return Do(createTable, func(tableRes Term) Term {
+		return Do(table.Wait(), func(Term) Term {
+			return Do(Expr(createIndexes), func(indexRes Term) Term {
+				return Do(table.IndexWait(indexNames...), func(Term) Term {
+					return Expr(map[string]interface{}{
+						"tables_created": tableRes.Field("tables_created"),
+						"created":        indexRes.Field("created").Sum(),
+					})
+				})
+			})
+		})
+	})
+}
+
 // IndexCreateOpts contains the optional arguments for the IndexCreate term
 type IndexCreateOpts struct {
 	Multi interface{} `rethinkdb:"multi,omitempty"`
@@ -149,6 +258,10 @@ func (t Term) IndexWait(args ...interface{}) Term {
 }
 
 // ChangesOpts contains the optional arguments for the Changes term
+//
+// Squash accepts either a bool or the number of seconds to squash changes
+// for, alternatively SquashInterval can be used to set the interval as a
+// time.Duration. Only one of Squash and SquashInterval may be set.
 type ChangesOpts struct {
 	Squash              interface{} `rethinkdb:"squash,omitempty"`
 	IncludeInitial      interface{} `rethinkdb:"include_initial,omitempty"`
@@ -156,18 +269,55 @@ type ChangesOpts struct {
 	IncludeOffsets      interface{} `rethinkdb:"include_offsets,omitempty"`
 	IncludeTypes        interface{} `rethinkdb:"include_types,omitempty"`
 	ChangefeedQueueSize interface{} `rethinkdb:"changefeed_queue_size,omitempty"`
+
+	// SquashInterval squashes changes which happen within the interval.
+	SquashInterval time.Duration `rethinkdb:"-"`
 }
 
 // ChangesOpts contains the optional arguments for the Changes term
 func (o ChangesOpts) toMap() map[string]interface{} {
-	return optArgsToMap(o)
+	opts := optArgsToMap(o)
+	if o.SquashInterval > 0 {
+		opts["squash"] = o.SquashInterval.Seconds()
+	}
+
+	return opts
+}
+
+func (o ChangesOpts) validate() error {
+	if o.Squash != nil && o.SquashInterval != 0 {
+		return RQLDriverError{rqlError("Changes: only one of Squash and SquashInterval can be set")}
+	}
+	if o.SquashInterval < 0 {
+		return RQLDriverError{rqlError(fmt.Sprintf("Changes: SquashInterval must be positive, got %v", o.SquashInterval))}
+	}
+
+	return nil
 }
 
 // Changes returns an infinite stream of objects representing changes to a query.
+//
+// Each change emitted by the cursor contains both an old_val and a new_val
+// field. When IncludeInitial is set the initial values are delivered as
+// changes with a nil old_val, so initial values and later changes can be
+// handled by the same code. For feeds over GetAll with multiple keys the
+// initial values and changes of different keys may be interleaved in any
+// order.
+//
+// If the options are invalid, for example both Squash and SquashInterval are
+// set, then an error is returned when the query is run.
 func (t Term) Changes(optArgs ...ChangesOpts) Term {
 	opts := map[string]interface{}{}
+	var err error
 	if len(optArgs) >= 1 {
+		err = optArgs[0].validate()
 		opts = optArgs[0].toMap()
 	}
-	return constructMethodTerm(t, "Changes", p.Term_CHANGES, []interface{}{}, opts)
+
+	t = constructMethodTerm(t, "Changes", p.Term_CHANGES, []interface{}{}, opts)
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
 }
@@ -0,0 +1,979 @@
+package rethinkdb
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	test "gopkg.in/check.v1"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+type QuerySuite struct{}
+
+var _ = test.Suite(&QuerySuite{})
+
+func (s *QuerySuite) TestRunOpts_ArrayLimit(c *test.C) {
+	_, ok := RunOpts{}.toMap()["array_limit"]
+	c.Assert(ok, test.Equals, false)
+
+	limit := 200000
+	c.Assert(RunOpts{ArrayLimit: &limit}.toMap()["array_limit"], test.Equals, int64(200000))
+}
+
+func (s *QuerySuite) TestRun_InvalidArrayLimit(c *test.C) {
+	mock := NewMock()
+	query := mock.On(Table("test")).Return([]interface{}{}, nil)
+
+	limit := 0
+	_, err := Table("test").Run(mock, RunOpts{ArrayLimit: &limit})
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+
+	err = Table("test").Exec(mock, ExecOpts{ArrayLimit: &limit})
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+
+	mock.AssertNotExecuted(c, query)
+}
+
+func (s *QuerySuite) TestWriteResponse_WasNoOp(c *test.C) {
+	c.Assert(WriteResponse{Unchanged: 1}.WasNoOp(), test.Equals, true)
+	c.Assert(WriteResponse{}.WasNoOp(), test.Equals, false)
+	c.Assert(WriteResponse{Unchanged: 1, Replaced: 1}.WasNoOp(), test.Equals, false)
+	c.Assert(WriteResponse{Unchanged: 1, Inserted: 1}.WasNoOp(), test.Equals, false)
+	c.Assert(WriteResponse{Unchanged: 1, Errors: 1}.WasNoOp(), test.Equals, false)
+}
+
+func (s *QuerySuite) TestTerm_WithOpts(c *test.C) {
+	base := Table("test").Insert(map[string]interface{}{"id": 1}, InsertOpts{Durability: "soft"})
+	t := base.WithOpts(InsertOpts{Conflict: "replace"}).WithOpts(map[string]interface{}{
+		"durability": "hard",
+	})
+
+	built, err := t.Build()
+	c.Assert(err, test.IsNil)
+	optArgs := built.([]interface{})[2].(map[string]interface{})
+	c.Assert(optArgs, test.DeepEquals, map[string]interface{}{
+		"conflict":   "replace",
+		"durability": "hard",
+	})
+
+	// The original term is unchanged
+	built, err = base.Build()
+	c.Assert(err, test.IsNil)
+	optArgs = built.([]interface{})[2].(map[string]interface{})
+	c.Assert(optArgs, test.DeepEquals, map[string]interface{}{
+		"durability": "soft",
+	})
+}
+
+func (s *QuerySuite) TestTerm_FirstLast(c *test.C) {
+	c.Assert(Expr([]int{1, 2, 3}).First().String(), test.Equals, Expr([]int{1, 2, 3}).Nth(0).String())
+	c.Assert(Expr([]int{1, 2, 3}).Last().String(), test.Equals, Expr([]int{1, 2, 3}).Nth(-1).String())
+}
+
+func (s *QuerySuite) TestTerm_NthInvalidIndex(c *test.C) {
+	mock := NewMock()
+
+	_, err := Expr([]int{1, 2, 3}).Nth(1.5).Run(mock)
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+
+	_, err = Expr([]int{1, 2, 3}).Nth(2.0).Build()
+	c.Assert(err, test.IsNil)
+}
+
+func (s *QuerySuite) TestNonExistenceError_Is(c *test.C) {
+	err := createRuntimeError(p.Response_NON_EXISTENCE, &Response{Responses: []json.RawMessage{[]byte(`"Index out of bounds: 0"`)}}, nil, "")
+	c.Assert(errors.Is(err, ErrNonExistence), test.Equals, true)
+
+	err = createRuntimeError(p.Response_QUERY_LOGIC, &Response{Responses: []json.RawMessage{[]byte(`"Expected type"`)}}, nil, "")
+	c.Assert(errors.Is(err, ErrNonExistence), test.Equals, false)
+}
+
+func (s *QuerySuite) TestChangesOpts_SquashInterval(c *test.C) {
+	opts := ChangesOpts{SquashInterval: 1500 * time.Millisecond}.toMap()
+	c.Assert(opts["squash"], test.Equals, 1.5)
+
+	c.Assert(Table("test").Changes(ChangesOpts{SquashInterval: 2 * time.Second}).String(), test.Equals,
+		Table("test").Changes(ChangesOpts{Squash: 2}).String())
+}
+
+func (s *QuerySuite) TestChanges_InvalidSquash(c *test.C) {
+	mock := NewMock()
+
+	_, err := Table("test").Changes(ChangesOpts{Squash: true, SquashInterval: time.Second}).Run(mock)
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+
+	_, err = Table("test").Changes(ChangesOpts{SquashInterval: -time.Second}).Run(mock)
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+}
+
+func (s *QuerySuite) TestJSOpts_TimeoutDuration(c *test.C) {
+	opts := JSOpts{TimeoutDuration: 1300 * time.Millisecond}.toMap()
+	c.Assert(opts["timeout"], test.Equals, 1.3)
+
+	mock := NewMock()
+	mock.On(JS("1 + 2", JSOpts{TimeoutDuration: 2 * time.Second})).Return(3, nil)
+
+	var sum int
+	err := JS("1 + 2", JSOpts{TimeoutDuration: 2 * time.Second}).ReadOne(&sum, mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(sum, test.Equals, 3)
+}
+
+func (s *QuerySuite) TestJS_InvalidTimeout(c *test.C) {
+	mock := NewMock()
+
+	for _, opts := range []JSOpts{
+		{Timeout: 1, TimeoutDuration: time.Second},
+		{TimeoutDuration: -time.Second},
+		{Timeout: 0},
+		{Timeout: -1.5},
+	} {
+		_, err := JS("1 + 2", opts).Run(mock)
+		c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+	}
+}
+
+func (s *QuerySuite) TestNewRawQuery(c *test.C) {
+	q, err := newRawQuery([]byte(`[15, [[14, ["test"]], "users"]]`), map[string]json.RawMessage{
+		"read_mode": json.RawMessage(`"outdated"`),
+	})
+	c.Assert(err, test.IsNil)
+
+	b, err := json.Marshal(q.Build())
+	c.Assert(err, test.IsNil)
+	c.Assert(string(b), test.Equals, `[1,[15,[[14,["test"]],"users"]],{"read_mode":"outdated"}]`)
+}
+
+func (s *QuerySuite) TestNewRawQuery_InvalidJSON(c *test.C) {
+	_, err := newRawQuery([]byte(`[15, `), nil)
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+
+	_, err = newRawQuery([]byte(`[15, []]`), map[string]json.RawMessage{"db": json.RawMessage(`{`)})
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+}
+
+func (s *QuerySuite) TestRunOpts_RawPseudotypes(c *test.C) {
+	opts := RunOpts{RawPseudotypes: Bool(true), TimeFormat: "native"}.toMap()
+	c.Assert(opts["time_format"], test.Equals, "raw")
+	c.Assert(opts["group_format"], test.Equals, "raw")
+	c.Assert(opts["binary_format"], test.Equals, "raw")
+	c.Assert(opts["geometry_format"], test.Equals, "raw")
+
+	doc := map[string]interface{}{
+		"time":   map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1.5, "timezone": "+00:00"},
+		"binary": map[string]interface{}{"$reql_type$": "BINARY", "data": "AQID"},
+	}
+	converted, err := recursivelyConvertPseudotype(doc, opts)
+	c.Assert(err, test.IsNil)
+	c.Assert(converted, test.DeepEquals, map[string]interface{}{
+		"time":   map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1.5, "timezone": "+00:00"},
+		"binary": map[string]interface{}{"$reql_type$": "BINARY", "data": "AQID"},
+	})
+}
+
+func (s *QuerySuite) TestRQLUserError_Message(c *test.C) {
+	term := Error("invalid state")
+	err := createRuntimeError(p.Response_USER, &Response{Responses: []json.RawMessage{[]byte(`"invalid state"`)}}, &term, "")
+
+	userErr, ok := err.(RQLUserError)
+	c.Assert(ok, test.Equals, true)
+	c.Assert(userErr.Message(), test.Equals, "invalid state")
+	c.Assert(userErr.Error(), test.Equals, "rethinkdb: invalid state in:\nr.Error(\"invalid state\")")
+}
+
+func (s *QuerySuite) TestRunWrite_WriteError(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test").Get("a").Update(map[string]interface{}{"state": "b"})).Return(map[string]interface{}{
+		"errors":      1,
+		"first_error": "invalid state",
+	}, nil)
+
+	_, err := Table("test").Get("a").Update(map[string]interface{}{"state": "b"}).RunWrite(mock)
+	c.Assert(err, test.Equals, RQLWriteError{FirstError: "invalid state", Errors: 1})
+}
+
+func (s *QuerySuite) TestRunWrite_CollectErrors(c *test.C) {
+	query := Table("users").Insert(Expr([]interface{}{
+		map[string]interface{}{"id": 1},
+		map[string]interface{}{"id": 2},
+	}), InsertOpts{ReturnChanges: "always"})
+	mock := NewMock()
+	mock.On(query).Return(map[string]interface{}{
+		"errors":      2,
+		"inserted":    0,
+		"first_error": "Duplicate primary key `id`:\n{\n\t\"id\":\t1\n}\n{\n\t\"id\":\t1\n}",
+		"changes": []interface{}{
+			map[string]interface{}{"old_val": map[string]interface{}{"id": 1}, "new_val": map[string]interface{}{"id": 1}, "error": "Duplicate primary key `id`"},
+			map[string]interface{}{"old_val": nil, "new_val": nil, "error": "Document too large"},
+		},
+	}, nil)
+
+	_, err := query.RunWrite(mock)
+	c.Assert(err, test.FitsTypeOf, RQLWriteError{})
+	writeErr := err.(RQLWriteError)
+	c.Assert(writeErr.Errors, test.Equals, 2)
+	c.Assert(writeErr.DuplicateKey, test.Equals, "id")
+	c.Assert(writeErr.WriteErrors(), test.IsNil)
+
+	_, err = query.RunWrite(mock, RunOpts{CollectErrors: Bool(true)})
+	writeErr = err.(RQLWriteError)
+	c.Assert(writeErr.WriteErrors(), test.DeepEquals, []WriteError{
+		{Message: "Duplicate primary key `id`", OldValue: map[string]interface{}{"id": 1.0}, NewValue: map[string]interface{}{"id": 1.0}},
+		{Message: "Document too large"},
+	})
+	c.Assert(writeErr.WriteErrors()[1], test.ErrorMatches, "Document too large")
+}
+
+func (s *QuerySuite) TestTerm_EqualAndHash(c *test.C) {
+	build := func() Term {
+		return Table("test").Filter(func(row Term) Term {
+			return row.Field("age").Gt(18)
+		}).Changes(ChangesOpts{IncludeInitial: true, Squash: true})
+	}
+
+	t1, t2 := build(), build()
+	c.Assert(t1.Equal(t2), test.Equals, true)
+	c.Assert(t1.Hash(), test.Equals, t2.Hash())
+	c.Assert(t1.Hash(), test.Equals, t1.Hash())
+
+	other := Table("test").Filter(func(row Term) Term {
+		return row.Field("age").Gt(21)
+	})
+	c.Assert(t1.Equal(other), test.Equals, false)
+	c.Assert(t1.Hash(), test.Not(test.Equals), other.Hash())
+
+	c.Assert(Table("a").Hash(), test.Not(test.Equals), Table("b").Hash())
+	c.Assert(Expr(1).Hash(), test.Not(test.Equals), Expr("1").Hash())
+
+	// Equal and Hash cover the names of the terms
+	renamed := Table("a")
+	renamed.name = "Other"
+	c.Assert(Table("a").Equal(renamed), test.Equals, false)
+	c.Assert(Table("a").Hash(), test.Not(test.Equals), renamed.Hash())
+
+	// MockAnything only matches any term when mocking queries
+	c.Assert(MockAnything().Equal(Table("a")), test.Equals, false)
+	c.Assert(Table("a").Equal(MockAnything()), test.Equals, false)
+	c.Assert(MockAnything().Equal(MockAnything()), test.Equals, true)
+	c.Assert(MockAnything().Hash(), test.Equals, MockAnything().Hash())
+	c.Assert(MockAnything().Hash(), test.Not(test.Equals), Expr(nil).Hash())
+}
+
+func (s *QuerySuite) TestTerm_Depth(c *test.C) {
+	c.Assert(Expr(1).Depth(), test.Equals, 1)
+	c.Assert(Table("test").Depth(), test.Equals, 2)
+	c.Assert(DB("db").Table("test").Depth(), test.Equals, 3)
+	c.Assert(Expr([]interface{}{[]interface{}{1}}).Depth(), test.Equals, 3)
+
+	// optional arguments are included
+	c.Assert(Table("test").Get(1).Delete(DeleteOpts{ReturnChanges: Expr([]interface{}{[]interface{}{[]interface{}{true}}})}).Depth(), test.Equals, 5)
+
+	nested := Expr(0)
+	for i := 0; i < 10; i++ {
+		nested = nested.Add(1)
+	}
+	c.Assert(nested.Depth(), test.Equals, 11)
+	c.Assert(RawQuery([]byte(`[24,[1,2]]`)).Depth(), test.Equals, 1)
+}
+
+func (s *QuerySuite) TestInsert_Ordered(c *test.C) {
+	docs := []interface{}{
+		map[string]interface{}{"id": 1},
+		map[string]interface{}{"id": 2},
+		map[string]interface{}{"id": 3},
+	}
+
+	mock := NewMock()
+	mock.On(Table("test").Insert(docs[0])).Return(map[string]interface{}{"inserted": 1}, nil)
+	mock.On(Table("test").Insert(docs[1])).Return(map[string]interface{}{
+		"errors":      1,
+		"first_error": "Duplicate primary key `id`",
+	}, nil)
+	third := mock.On(Table("test").Insert(docs[2])).Return(map[string]interface{}{"inserted": 1}, nil)
+
+	res, err := Table("test").Insert(docs, InsertOpts{Ordered: true}).RunWrite(mock)
+	c.Assert(err, test.FitsTypeOf, RQLWriteError{})
+	c.Assert(IsConflictErr(err), test.Equals, true)
+	c.Assert(res.Inserted, test.Equals, 1)
+	c.Assert(res.Errors, test.Equals, 1)
+	mock.AssertNotExecuted(c, third)
+
+	_, err = Table("test").Insert(docs, InsertOpts{Ordered: true}).Run(mock)
+	c.Assert(err, test.Equals, errOrderedInsertRun)
+}
+
+func (s *QuerySuite) TestInsert_OrderedErrors(c *test.C) {
+	docs := []interface{}{
+		map[string]interface{}{"id": 1},
+		map[string]interface{}{"id": 2},
+	}
+
+	mock := NewMock()
+
+	// The error of the insert term is returned before running any queries
+	_, err := Table("test").Insert(docs, InsertOpts{Ordered: true, Conflict: "ignore"}).RunWrite(mock)
+	c.Assert(err, test.ErrorMatches, "rethinkdb: Insert: invalid Conflict .*")
+
+	_, err = Table("test").Insert(docs, InsertOpts{Ordered: true}).Pluck("inserted").RunWrite(mock)
+	c.Assert(err, test.Equals, errOrderedInsertChained)
+	_, err = Table("test").Insert(docs, InsertOpts{Ordered: true}).Pluck("inserted").Run(mock)
+	c.Assert(err, test.Equals, errOrderedInsertRun)
+	c.Assert(mock.Queries, test.HasLen, 0)
+}
+
+func (s *QuerySuite) TestInsert_OrderedTransform(c *test.C) {
+	docs := []interface{}{
+		map[string]interface{}{"id": 1},
+		map[string]interface{}{"id": 2},
+	}
+
+	mock := NewMock()
+	mock.On(Table("other").Insert(docs[0])).Return(map[string]interface{}{"inserted": 1}, nil)
+	mock.On(Table("other").Insert(docs[1])).Return(map[string]interface{}{"inserted": 1}, nil)
+
+	// Ordered inserts are kept when the term is rewritten
+	query := Table("test").Insert(docs, InsertOpts{Ordered: true}).Transform(func(t Term) Term {
+		if t.Type() == p.Term_TABLE {
+			return Table("other")
+		}
+		return t
+	})
+	res, err := query.RunWrite(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Inserted, test.Equals, 2)
+	mock.AssertExpectations(c)
+}
+
+func (s *QuerySuite) TestInsert_OrderedSingleDocument(c *test.C) {
+	doc := map[string]interface{}{"id": 1}
+
+	mock := NewMock()
+	mock.On(Table("test").Insert(doc)).Return(map[string]interface{}{"inserted": 1}, nil)
+
+	res, err := Table("test").Insert(doc, InsertOpts{Ordered: true}).RunWrite(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Inserted, test.Equals, 1)
+}
+
+func (s *QuerySuite) TestGeospatial_Units(c *test.C) {
+	c.Assert(Distance(Point(0, 0), Point(1, 1), DistanceOpts{Unit: UnitKilometer}).String(), test.Equals,
+		Distance(Point(0, 0), Point(1, 1), DistanceOpts{Unit: "km"}).String())
+
+	mock := NewMock()
+	_, err := Distance(Point(0, 0), Point(1, 1), DistanceOpts{Unit: "kilometers"}).Run(mock)
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+
+	_, err = Table("places").GetNearest(Point(0, 0), GetNearestOpts{Index: "location", Unit: Unit("yd")}).Run(mock)
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+
+	_, err = Circle(Point(0, 0), 10, CircleOpts{Unit: "miles"}).Run(mock)
+	c.Assert(err, test.FitsTypeOf, RQLDriverError{})
+}
+
+func (s *QuerySuite) TestGetNearest_DecodeResponse(c *test.C) {
+	mock := NewMock()
+	query := Table("places").GetNearest(Point(0, 0), GetNearestOpts{Index: "location", Unit: UnitMeter})
+	mock.On(query).Return([]interface{}{
+		map[string]interface{}{"dist": 12.5, "doc": map[string]interface{}{"id": "a"}},
+		map[string]interface{}{"dist": 30, "doc": map[string]interface{}{"id": "b"}},
+	}, nil)
+
+	res, err := query.Run(mock)
+	c.Assert(err, test.IsNil)
+
+	var results []struct {
+		Dist float64 `rethinkdb:"dist"`
+		Doc  struct {
+			ID string `rethinkdb:"id"`
+		} `rethinkdb:"doc"`
+	}
+	c.Assert(res.All(&results), test.IsNil)
+	c.Assert(results, test.HasLen, 2)
+	c.Assert(results[0].Dist, test.Equals, 12.5)
+	c.Assert(results[1].Doc.ID, test.Equals, "b")
+
+	var generic []GetNearestResponse
+	res, err = query.Run(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(res.All(&generic), test.IsNil)
+	c.Assert(generic[1].Dist, test.Equals, 30.0)
+}
+
+func (s *QuerySuite) TestTerm_UpdateNonZero(c *test.C) {
+	type user struct {
+		Name string `rethinkdb:"name"`
+		Age  int    `rethinkdb:"age"`
+	}
+
+	c.Assert(Table("users").Get("a").UpdateNonZero(user{Name: "bob"}).String(), test.Equals,
+		Table("users").Get("a").Update(map[string]interface{}{"name": "bob"}).String())
+}
+
+func (s *QuerySuite) TestTerm_Increment(c *test.C) {
+	query := Table("posts").Get("1").Increment("views", 1)
+	c.Assert(query.String(), test.Matches,
+		`r\.Table\("posts"\)\.Get\("1"\)\.Update\(func\((var_\d+) r\.Term\) r\.Term \{ return \{views=var_\d+\.Field\("views"\)\.Default\(0\)\.Add\(1\)\} \}\)`)
+	c.Assert(Table("posts").Get("1").Decrement("stock", 3).String(), test.Matches,
+		`.*\{stock=var_\d+\.Field\("stock"\)\.Default\(0\)\.Sub\(3\)\}.*`)
+
+	mock := NewMock()
+	mock.On(query).Return(map[string]interface{}{"replaced": 1}, nil).Once()
+	res, err := query.RunWrite(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Replaced, test.Equals, 1)
+	mock.AssertExpectations(c)
+}
+
+func (s *QuerySuite) TestTerm_UpdateIfVersion(c *test.C) {
+	query := Table("posts").Get("1").UpdateIfVersion(2, map[string]interface{}{"title": "a"})
+	c.Assert(query.String(), test.Matches,
+		`r\.Table\("posts"\)\.Get\("1"\)\.Update\(func\((var_\d+) r\.Term\) r\.Term \{ return r\.Branch\(var_\d+\.Field\("version"\)\.Eq\(2\), \{title="a"\}\.Merge\(\{version=var_\d+\.Field\("version"\)\.Add\(1\)\}\), var_\d+\) \}\)`)
+
+	mock := NewMock()
+	mock.On(query).Return(map[string]interface{}{"replaced": 1}, nil).Once()
+	mock.On(query).Return(map[string]interface{}{"unchanged": 1}, nil).Once()
+	mock.On(query).Return(map[string]interface{}{"skipped": 1}, nil).Once()
+
+	res, err := query.RunWrite(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Applied(), test.Equals, true)
+
+	res, err = query.RunWrite(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Applied(), test.Equals, false)
+	c.Assert(res.Unchanged, test.Equals, 1)
+
+	res, err = query.RunWrite(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Applied(), test.Equals, false)
+	c.Assert(res.Skipped, test.Equals, 1)
+}
+
+func (s *QuerySuite) TestTerm_Exists(c *test.C) {
+	query := Table("users").Get("a").Exists()
+	c.Assert(query.String(), test.Equals, Table("users").Get("a").Ne(nil).String())
+
+	mock := NewMock()
+	mock.On(query).Return(true, nil)
+
+	var exists bool
+	err := query.ReadOne(&exists, mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(exists, test.Equals, true)
+}
+
+func (s *QuerySuite) TestTerm_MergeShallow(c *test.C) {
+	type post struct {
+		Title string                 `rethinkdb:"title"`
+		Tags  map[string]interface{} `rethinkdb:"tags"`
+	}
+	doc := Table("posts").Get("1")
+	assertBuildsTo := func(obtained, expected Term) {
+		obtainedBuilt, err := obtained.Build()
+		c.Assert(err, test.IsNil)
+		expectedBuilt, err := expected.Build()
+		c.Assert(err, test.IsNil)
+		obtainedJSON, _ := json.Marshal(obtainedBuilt)
+		expectedJSON, _ := json.Marshal(expectedBuilt)
+		c.Assert(string(obtainedJSON), test.Equals, string(expectedJSON))
+	}
+
+	assertBuildsTo(doc.MergeShallow(map[string]interface{}{
+		"title": "a",
+		"tags":  map[string]interface{}{"b": 2},
+		"count": doc.Field("count").Add(1),
+	}), doc.Merge(map[string]interface{}{
+		"title": "a",
+		"tags":  Literal(map[string]interface{}{"b": 2}),
+		"count": doc.Field("count").Add(1),
+	}))
+
+	assertBuildsTo(doc.MergeShallow(post{Title: "a", Tags: map[string]interface{}{"b": 2}}),
+		doc.Merge(map[string]interface{}{
+			"title": "a",
+			"tags":  Literal(map[string]interface{}{"b": 2}),
+		}))
+
+	// Terms are passed through unchanged
+	assertBuildsTo(doc.MergeShallow(doc.Field("other")), doc.Merge(doc.Field("other")))
+}
+
+func (s *QuerySuite) TestTerm_WriteDryRun(c *test.C) {
+	update := Table("posts").Get("1").Update(map[string]interface{}{"title": "b"}, UpdateOpts{DryRun: true})
+	insert := Table("posts").Insert([]interface{}{map[string]interface{}{"title": "a"}}, InsertOpts{DryRun: true})
+	c.Assert(isWriteTerm(&update), test.Equals, false)
+	c.Assert(isWriteTerm(&insert), test.Equals, false)
+	c.Assert(update.String(), test.Matches, `.*Get\("1"\).*Merge\(\{title="b"\}\).*`)
+
+	mock := NewMock()
+	mock.On(Table("posts").Get("1").Update(map[string]interface{}{"title": "b"}, UpdateOpts{DryRun: true})).Return(map[string]interface{}{
+		"replaced":  1,
+		"unchanged": 0,
+		"skipped":   0,
+		"changes": []interface{}{map[string]interface{}{
+			"old_val": map[string]interface{}{"title": "a"},
+			"new_val": map[string]interface{}{"title": "b"},
+		}},
+	}, nil)
+
+	res, err := update.RunWrite(mock)
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Replaced, test.Equals, 1)
+	c.Assert(res.Changes, test.HasLen, 1)
+	c.Assert(res.Changes[0].NewValue, test.DeepEquals, map[string]interface{}{"title": "b"})
+	mock.AssertExpectations(c)
+}
+
+func (s *QuerySuite) TestTerm_MinMaxBy(c *test.C) {
+	c.Assert(Table("t").MinByField("age").String(), test.Equals, Table("t").Min("age").String())
+	c.Assert(Table("t").MaxByField("age").String(), test.Equals, Table("t").Max("age").String())
+	c.Assert(Table("t").MinByIndex("age").String(), test.Equals, Table("t").MinIndex("age").String())
+	c.Assert(Table("t").MaxByIndex("age").String(), test.Equals, Table("t").MaxIndex("age").String())
+	c.Assert(Table("t").MinByFunc(func(row Term) Term { return row.Field("age") }).String(), test.Matches,
+		`r\.Table\("t"\)\.Min\(func\(var_\d+ r\.Term\) r\.Term \{ return var_\d+\.Field\("age"\) \}\)`)
+	c.Assert(Table("t").MaxByFunc(func(row Term) Term { return row.Field("age") }).String(), test.Matches,
+		`r\.Table\("t"\)\.Max\(func\(var_\d+ r\.Term\) r\.Term \{ return var_\d+\.Field\("age"\) \}\)`)
+}
+
+func (s *QuerySuite) TestQueryError_Database(c *test.C) {
+	term := Table("users")
+	db, _ := DB("test").Build()
+	q := Query{Term: &term, Opts: map[string]interface{}{"db": db}}
+	response := &Response{
+		Type:      p.Response_RUNTIME_ERROR,
+		ErrorType: p.Response_OP_FAILED,
+		Responses: []json.RawMessage{[]byte("\"Table `test.users` does not exist.\"")},
+	}
+
+	_, _, err := newConnection(nil, "addr", &ConnectOpts{}).processResponse(nil, q, response, nil)
+	opErr, ok := err.(RQLOpFailedError)
+	c.Assert(ok, test.Equals, true)
+	c.Assert(opErr.Database(), test.Equals, "test")
+	c.Assert(opErr.Error(), test.Equals, "rethinkdb: Table `test.users` does not exist. (default database: test) in:\nr.Table(\"users\")")
+}
+
+func (s *QuerySuite) TestToMap(c *test.C) {
+	type user struct {
+		ID      string    `rethinkdb:"id,omitempty"`
+		Name    string    `rethinkdb:"name"`
+		Created time.Time `rethinkdb:"created"`
+	}
+
+	m, err := ToMap(user{Name: "bob", Created: time.Unix(10, 0)})
+	c.Assert(err, test.IsNil)
+	c.Assert(m, test.DeepEquals, map[string]interface{}{
+		"name":    "bob",
+		"created": map[string]interface{}{"$reql_type$": "TIME", "epoch_time": float64(10), "timezone": "+00:00"},
+	})
+
+	_, err = ToMap("bob")
+	c.Assert(err, test.NotNil)
+}
+
+func (s *QuerySuite) TestUnionOrderedBy(c *test.C) {
+	c.Assert(Table("a").UnionOrderedBy("created", Table("b")).String(), test.Equals,
+		Table("a").UnionWithOpts(UnionOpts{Interleave: "created"}, Table("b")).String())
+
+	_, err := UnionWithOpts(UnionOpts{Interleave: func(row Term) Term { return row.Field("created") }}, Table("a"), Table("b")).Build()
+	c.Assert(err, test.IsNil)
+	_, err = UnionWithOpts(UnionOpts{Interleave: false}, Table("a"), Table("b")).Build()
+	c.Assert(err, test.IsNil)
+	_, err = Table("a").UnionWithOpts(UnionOpts{Interleave: 1}, Table("b")).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: Union: Interleave must be a bool, field name, Term or function, got int")
+}
+
+func (s *QuerySuite) TestTerm_RunFirst(c *test.C) {
+	mock := NewMock()
+	mock.On(Table("test")).Return([]interface{}{"a", "b", "c"}, nil)
+	mock.On(Table("empty")).Return([]interface{}{}, nil)
+	mock.On(Table("fail")).Return(nil, errors.New("failed"))
+
+	var first string
+	cursor, ok, err := Table("test").RunFirst(mock, &first)
+	c.Assert(err, test.IsNil)
+	c.Assert(ok, test.Equals, true)
+	c.Assert(first, test.Equals, "a")
+	var rest []string
+	c.Assert(cursor.All(&rest), test.IsNil)
+	c.Assert(rest, test.DeepEquals, []string{"b", "c"})
+
+	cursor, ok, err = Table("empty").RunFirst(mock, &first)
+	c.Assert(err, test.IsNil)
+	c.Assert(ok, test.Equals, false)
+	c.Assert(cursor, test.NotNil)
+
+	cursor, ok, err = Table("fail").RunFirst(mock, &first)
+	c.Assert(err, test.ErrorMatches, "failed")
+	c.Assert(ok, test.Equals, false)
+	c.Assert(cursor, test.IsNil)
+
+	// The cursor is closed when the first row cannot be read
+	mock.On(Table("numbers")).Return([]interface{}{1, 2}, nil)
+	executor := &cursorExecutor{Mock: mock}
+	var doc struct{ ID string }
+	cursor, ok, err = Table("numbers").RunFirst(executor, &doc)
+	c.Assert(err, test.NotNil)
+	c.Assert(ok, test.Equals, false)
+	c.Assert(cursor, test.IsNil)
+	c.Assert(executor.cursor.closed, test.Equals, true)
+}
+
+// cursorExecutor records the cursor of the last query.
+type cursorExecutor struct {
+	*Mock
+	cursor *Cursor
+}
+
+func (e *cursorExecutor) Query(ctx context.Context, q Query) (*Cursor, error) {
+	cursor, err := e.Mock.Query(ctx, q)
+	e.cursor = cursor
+	return cursor, err
+}
+
+func (s *QuerySuite) TestTerm_Transform(c *test.C) {
+	term := Table("a").Union(Table("b")).Filter(map[string]interface{}{"x": 1})
+	renamed := term.Transform(func(t Term) Term {
+		if t.Type() == p.Term_TABLE {
+			return Table("prefix_" + t.args[0].data.(string))
+		}
+		return t
+	})
+
+	c.Assert(renamed.String(), test.Equals, Table("prefix_a").Union(Table("prefix_b")).Filter(map[string]interface{}{"x": 1}).String())
+	c.Assert(term.String(), test.Equals, Table("a").Union(Table("b")).Filter(map[string]interface{}{"x": 1}).String())
+}
+
+func (s *QuerySuite) TestTerm_DuringBounds(c *test.C) {
+	start, end := Time(2020, 1, 1, "Z"), Time(2021, 1, 1, "Z")
+
+	typed, err := Row.Field("created").During(start, end, DuringOpts{LeftBound: BoundOpen, RightBound: BoundClosed}).Build()
+	c.Assert(err, test.IsNil)
+	untyped, err := Row.Field("created").During(start, end, DuringOpts{LeftBound: "open", RightBound: "closed"}).Build()
+	c.Assert(err, test.IsNil)
+	c.Assert(typed, test.DeepEquals, untyped)
+
+	_, err = Row.Field("created").During(start, end, DuringOpts{RightBound: "inclusive"}).Build()
+	c.Assert(err, test.ErrorMatches, `rethinkdb: During: invalid RightBound "inclusive", expected open or closed`)
+}
+
+func (s *QuerySuite) TestSwitch(c *test.C) {
+	age := Expr(15)
+	term := Switch([]Case{
+		{Test: age.Lt(13), Value: "child"},
+		{Test: age.Lt(18), Value: "teen"},
+	}, "adult")
+	c.Assert(term.String(), test.Equals, Branch(age.Lt(13), "child", age.Lt(18), "teen", "adult").String())
+
+	c.Assert(Switch(nil, "adult").String(), test.Equals, Expr("adult").String())
+}
+
+func (s *QuerySuite) TestTerm_EnsureTable(c *test.C) {
+	term := DB("app").EnsureTable("users", TableSpec{
+		PrimaryKey: "uid",
+		Indexes: []IndexSpec{
+			{Name: "email"},
+			{Name: "tags", Multi: true, Func: func(row Term) interface{} {
+				return row.Field("tags")
+			}},
+		},
+	})
+	c.Assert(term.String(), test.Matches, `r\.Do\(func\(var_\d+ r\.Term\) r\.Term \{ return r\.Do\(.*\}, `+
+		`r\.Branch\(r\.DB\("app"\)\.TableList\(\)\.Contains\("users"\), \{tables_created=0\}, r\.DB\("app"\)\.TableCreate\("users", primary_key="uid"\)\)\)`)
+	c.Assert(term.String(), test.Matches, `.*r\.DB\("app"\)\.Table\("users"\)\.Wait\(\).*`)
+	c.Assert(term.String(), test.Matches, `.*\[r\.Branch\(r\.DB\("app"\)\.Table\("users"\)\.IndexList\(\)\.Contains\("email"\), \{created=0\}, r\.DB\("app"\)\.Table\("users"\)\.IndexCreate\("email"\)\), `+
+		`r\.Branch\(r\.DB\("app"\)\.Table\("users"\)\.IndexList\(\)\.Contains\("tags"\), \{created=0\}, r\.DB\("app"\)\.Table\("users"\)\.IndexCreate\("tags", func\(var_\d+ r\.Term\) r\.Term \{ return var_\d+\.Field\("tags"\) \}, multi=true\)\)\].*`)
+	c.Assert(term.String(), test.Matches, `.*r\.DB\("app"\)\.Table\("users"\)\.IndexWait\("email", "tags"\).*`)
+
+	_, err := term.Build()
+	c.Assert(err, test.IsNil)
+
+	c.Assert(EnsureTable("users", TableSpec{}).String(), test.Matches, `.*r\.Branch\(r\.TableList\(\)\.Contains\("users"\), \{tables_created=0\}, r\.TableCreate\("users"\)\)\)`)
+}
+
+func (s *QuerySuite) TestObject(c *test.C) {
+	pairs := ObjectFromPairs([]KV{{Key: "a", Value: 1}, {Key: Row.Field("k"), Value: 2}})
+	c.Assert(pairs.String(), test.Equals, Object("a", 1, Row.Field("k"), 2).String())
+
+	m := ObjectFromMap(map[string]Term{"b": Expr(2), "a": Expr(1)})
+	c.Assert(m.String(), test.Equals, Object("a", 1, "b", 2).String())
+	_, err := m.Build()
+	c.Assert(err, test.IsNil)
+
+	c.Assert(ObjectFromPairs(nil).String(), test.Equals, Object().String())
+}
+
+func (s *QuerySuite) TestTerm_GetAllDistinct(c *test.C) {
+	term := Table("users").GetAllDistinct("a", "b", "a", 1, 1.0, []interface{}{"x", 2}, []interface{}{"x", 2})
+	c.Assert(term.String(), test.Equals, Table("users").GetAll("a", "b", 1, []interface{}{"x", 2}).String())
+
+	term = Table("users").GetAllDistinctByIndex("email", "a", "a", Expr("a"))
+	c.Assert(term.String(), test.Equals, Table("users").GetAllByIndex("email", "a", Expr("a")).String())
+
+	type user struct {
+		ID   string
+		Name string
+	}
+	users := []user{{ID: "b", Name: "Bob"}, {ID: "a", Name: "Alice"}}
+	var expanded []user
+	err := ExpandByKey(&expanded, users, []interface{}{"a", "c", "b", "a"}, func(i int) interface{} {
+		return users[i].ID
+	})
+	c.Assert(err, test.IsNil)
+	c.Assert(expanded, test.DeepEquals, []user{{ID: "a", Name: "Alice"}, {ID: "b", Name: "Bob"}, {ID: "a", Name: "Alice"}})
+
+	err = ExpandByKey(expanded, users, nil, func(i int) interface{} { return users[i].ID })
+	c.Assert(err, test.NotNil)
+}
+
+func (s *QuerySuite) TestTerm_ContainsValueFunc(c *test.C) {
+	tags := Expr([]interface{}{"go", "db"})
+
+	term := tags.ContainsValue("go", Row.Field("tag"))
+	c.Assert(term.String(), test.Equals, `["go", "db"].Contains("go", r.Row.Field("tag"))`)
+	_, err := term.Build()
+	c.Assert(err, test.IsNil)
+
+	_, err = tags.ContainsValue(func(tag Term) Term { return tag.Eq("go") }).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: ContainsValue: argument 0 is a function, use ContainsFunc instead")
+
+	term = tags.ContainsFunc(func(tag Term) Term { return tag.Eq("go") }, Row.Eq("db"))
+	c.Assert(term.String(), test.Matches, `\["go", "db"\]\.Contains\(func\(var_\d+ r\.Term\) r\.Term \{ return var_\d+\.Eq\("go"\) \}, func\(var_\d+ r\.Term\) r\.Term \{ return r\.Row\.Eq\("db"\) \}\)`)
+	_, err = term.Build()
+	c.Assert(err, test.IsNil)
+
+	_, err = tags.ContainsFunc("go").Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: ContainsFunc: argument 0 must be a function, got string")
+	_, err = tags.ContainsFunc(func(a, b Term) Term { return a.Eq(b) }).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: ContainsFunc: argument 0 must accept a single argument, got 2")
+}
+
+func (s *QuerySuite) TestTerm_SliceBounds(c *test.C) {
+	arr := Expr([]int{0, 1, 2, 3})
+
+	typed, err := arr.Slice(1, 3, SliceOpts{LeftBound: BoundOpen, RightBound: BoundClosed}).Build()
+	c.Assert(err, test.IsNil)
+	untyped, err := arr.Slice(1, 3, SliceOpts{LeftBound: "open", RightBound: "closed"}).Build()
+	c.Assert(err, test.IsNil)
+	c.Assert(typed, test.DeepEquals, untyped)
+
+	_, err = arr.Slice(1, 3, SliceOpts{LeftBound: "exclusive"}).Build()
+	c.Assert(err, test.ErrorMatches, `rethinkdb: Slice: invalid LeftBound "exclusive", expected open or closed`)
+
+	c.Assert(arr.SliceFrom(1).String(), test.Equals, arr.Slice(1).String())
+	c.Assert(arr.SliceFrom(1, SliceOpts{LeftBound: BoundOpen}).String(), test.Equals, arr.Slice(1, SliceOpts{LeftBound: "open"}).String())
+}
+
+func (s *QuerySuite) TestTerm_ReduceWithBase(c *test.C) {
+	add := func(left, right Term) interface{} { return left.Add(right) }
+	term := Table("orders").Field("total").ReduceWithBase(0, add)
+	c.Assert(term.Equal(Table("orders").Field("total").Reduce(add).Default(0)), test.Equals, true)
+	_, err := term.Build()
+	c.Assert(err, test.IsNil)
+}
+
+func (s *QuerySuite) TestTerm_InsertConflictFunc(c *test.C) {
+	doc := map[string]interface{}{"id": 1, "count": 2}
+	term := Table("counters").Insert(doc, InsertOpts{
+		Conflict: func(id, oldDoc, newDoc Term) interface{} {
+			return newDoc.Merge(map[string]interface{}{
+				"count": Max([]interface{}{oldDoc.Field("count"), newDoc.Field("count")}),
+			})
+		},
+	})
+	built, err := term.Build()
+	c.Assert(err, test.IsNil)
+	conflict := built.([]interface{})[2].(map[string]interface{})["conflict"].([]interface{})
+	c.Assert(conflict[0], test.Equals, int(p.Term_FUNC))
+	params := conflict[1].([]interface{})[0].([]interface{})[1]
+	c.Assert(params, test.HasLen, 3)
+
+	_, err = Table("counters").Insert(doc, InsertOpts{Conflict: "update"}).Build()
+	c.Assert(err, test.IsNil)
+	_, err = Table("counters").Insert(doc, InsertOpts{Conflict: "merge"}).Build()
+	c.Assert(err, test.ErrorMatches, `rethinkdb: Insert: invalid Conflict "merge", expected error, replace, update or a function`)
+	_, err = Table("counters").Insert(doc, InsertOpts{Conflict: func(oldDoc, newDoc Term) Term { return newDoc }}).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: Insert: the Conflict function must accept 3 arguments, got 2")
+}
+
+func (s *QuerySuite) TestTerm_OrderByFields(c *test.C) {
+	posts := Table("posts")
+	c.Assert(posts.OrderByFields([]OrderKey{{Field: "date", Desc: true}, {Field: "title"}}).String(), test.Equals,
+		posts.OrderBy(Desc("date"), Asc("title")).String())
+	c.Assert(posts.OrderByFields([]OrderKey{{Field: "title"}}, OrderByOpts{Index: "date"}).String(), test.Equals,
+		posts.OrderBy(Asc("title"), OrderByOpts{Index: "date"}).String())
+
+	_, err := posts.OrderByFields([]OrderKey{{Field: "date"}, {Desc: true}}).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: OrderByFields: key 1 has an empty field")
+}
+
+func (s *QuerySuite) TestTerm_OrderByIndexValidation(c *test.C) {
+	// An index can be combined with keys, the index takes precedence
+	_, err := Table("posts").OrderBy("title", OrderByOpts{Index: "date"}).Build()
+	c.Assert(err, test.IsNil)
+	_, err = Table("posts").Between(1, 10, BetweenOpts{Index: "date"}).OrderBy(OrderByOpts{Index: "date"}).Build()
+	c.Assert(err, test.IsNil)
+	_, err = Branch(true, Table("a"), Table("b")).OrderBy(OrderByOpts{Index: "id"}).Build()
+	c.Assert(err, test.IsNil)
+
+	_, err = Table("posts").Filter(map[string]interface{}{"draft": false}).OrderBy(OrderByOpts{Index: "date"}).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: OrderBy: an index can only be used to order a table or the result of Between, got Filter")
+	_, err = Expr([]int{2, 1}).OrderBy(OrderByOpts{Index: "id"}).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: OrderBy: an index can only be used to order a table or the result of Between, got Expr")
+	_, err = Table("posts").Filter(map[string]interface{}{"draft": false}).OrderBy("date").Build()
+	c.Assert(err, test.IsNil)
+}
+
+func (s *QuerySuite) TestInsertStream(c *test.C) {
+	input := "{\"id\": 1}\n\n{\"id\": 2}\n{\"id\": 3}"
+	users := Table("users")
+
+	mock := NewMock()
+	mock.On(users.Insert([]interface{}{JSON(`{"id": 1}`), JSON(`{"id": 2}`)})).Return(map[string]interface{}{"inserted": 2}, nil).Once()
+	mock.On(users.Insert([]interface{}{JSON(`{"id": 3}`)})).Return(map[string]interface{}{"inserted": 1}, nil).Once()
+
+	res, err := InsertStream(mock, users, strings.NewReader(input), InsertStreamOpts{BatchSize: 2})
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Inserted, test.Equals, 3)
+	mock.AssertExpectations(c)
+
+	_, err = InsertStream(NewMock(), users, strings.NewReader("{\"id\": 1}\n[1, 2]\n"))
+	c.Assert(err, test.ErrorMatches, "rethinkdb: InsertStream: line 2 is not a valid JSON object")
+
+	res, err = InsertStream(NewMock(), users, strings.NewReader(""))
+	c.Assert(err, test.IsNil)
+	c.Assert(res.Inserted, test.Equals, 0)
+}
+
+func (s *QuerySuite) TestTerm_EqJoinZip(c *test.C) {
+	posts := Table("posts")
+	query := posts.EqJoinZip("author_id", Table("authors"), EqJoinOpts{Ordered: true})
+	c.Assert(query.String(), test.Equals, posts.EqJoin("author_id", Table("authors"), EqJoinOpts{Ordered: true}).Zip().String())
+
+	type postAuthor struct {
+		Title    string `rethinkdb:"title"`
+		AuthorID string `rethinkdb:"author_id"`
+		Name     string `rethinkdb:"name"`
+	}
+
+	mock := NewMock()
+	mock.On(query).Return([]interface{}{
+		map[string]interface{}{"id": "a", "title": "Hello", "author_id": "a", "name": "Ann"},
+	}, nil)
+
+	var rows []postAuthor
+	c.Assert(query.ReadAll(&rows, mock), test.IsNil)
+	c.Assert(rows, test.DeepEquals, []postAuthor{{Title: "Hello", AuthorID: "a", Name: "Ann"}})
+	mock.AssertExpectations(c)
+}
+
+type textKey struct{ uuid string }
+
+func (k *textKey) UnmarshalText(b []byte) error {
+	if len(b) != 36 {
+		return errors.New("invalid UUID")
+	}
+	k.uuid = string(b)
+	return nil
+}
+
+type scanKey struct{ uuid string }
+
+func (k *scanKey) Scan(src interface{}) error {
+	k.uuid = src.(string)
+	return nil
+}
+
+func (s *QuerySuite) TestWriteResponse_GeneratedKeysAs(c *test.C) {
+	uuids := []string{"dd782b64-70a7-43e4-b65e-dd14ae61d947", "1b6cd6c5-5ddc-4e7a-9a2b-1a9dcd7c8a1b"}
+	res := WriteResponse{Inserted: 2, GeneratedKeys: uuids}
+
+	var text []textKey
+	c.Assert(res.GeneratedKeysAs(&text), test.IsNil)
+	c.Assert(text, test.DeepEquals, []textKey{{uuids[0]}, {uuids[1]}})
+
+	var scanned []scanKey
+	c.Assert(res.GeneratedKeysAs(&scanned), test.IsNil)
+	c.Assert(scanned, test.DeepEquals, []scanKey{{uuids[0]}, {uuids[1]}})
+
+	type plainKey string
+	var plain []plainKey
+	c.Assert(res.GeneratedKeysAs(&plain), test.IsNil)
+	c.Assert(plain, test.DeepEquals, []plainKey{plainKey(uuids[0]), plainKey(uuids[1])})
+
+	c.Assert(WriteResponse{GeneratedKeys: []string{"a"}}.GeneratedKeysAs(&text), test.ErrorMatches,
+		"rethinkdb: GeneratedKeysAs: key 0: invalid UUID")
+	c.Assert(res.GeneratedKeysAs(text), test.ErrorMatches,
+		`rethinkdb: GeneratedKeysAs: dest must be a pointer to a slice, got \[\]rethinkdb.textKey`)
+}
+
+func (s *QuerySuite) TestTerm_IsType(c *test.C) {
+	c.Assert(Row.Field("tags").IsType(TypeArray).String(), test.Equals,
+		Row.Field("tags").TypeOf().Eq("ARRAY").String())
+
+	mock := NewMock()
+	mock.On(Expr(1).TypeOf()).Return("NUMBER", nil).Once()
+	mock.On(Table("users").TypeOf()).Return("TABLE", nil).Once()
+
+	var typ DatumType
+	c.Assert(Expr(1).TypeOf().ReadOne(&typ, mock), test.IsNil)
+	c.Assert(typ, test.Equals, TypeNumber)
+	c.Assert(Table("users").TypeOf().ReadOne(&typ, mock), test.IsNil)
+	c.Assert(typ, test.Equals, TypeTable)
+	mock.AssertExpectations(c)
+}
+
+func (s *QuerySuite) TestTerm_DistinctIndexValidation(c *test.C) {
+	_, err := Table("users").Distinct(DistinctOpts{Index: "email"}).Build()
+	c.Assert(err, test.IsNil)
+	_, err = Table("users").Distinct(DistinctOpts{Index: Expr("email")}).Build()
+	c.Assert(err, test.IsNil)
+	_, err = Distinct(Table("users"), DistinctOpts{Index: "email"}).Build()
+	c.Assert(err, test.IsNil)
+	_, err = Table("users").Field("email").Distinct().Build()
+	c.Assert(err, test.IsNil)
+
+	_, err = Table("users").Distinct(DistinctOpts{Index: ""}).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: Distinct: the Index must not be empty")
+	_, err = Table("users").Distinct(DistinctOpts{Index: 1}).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: Distinct: invalid Index int, expected the name of an index")
+	_, err = Table("users").Filter(map[string]interface{}{"active": true}).Distinct(DistinctOpts{Index: "email"}).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: Distinct: an index can only be used on a table, got Filter")
+	_, err = Distinct([]interface{}{1, 2}, DistinctOpts{Index: "email"}).Build()
+	c.Assert(err, test.ErrorMatches, "rethinkdb: Distinct: an index can only be used on a table, got Expr")
+}
+
+func (s *QuerySuite) TestRQLRuntimeError_Backtrace(c *test.C) {
+	var response Response
+	err := json.Unmarshal([]byte(`{"t":18,"e":3100000,"r":["No attribute age"],"b":[1,0]}`), &response)
+	c.Assert(err, test.IsNil)
+
+	term := Expr(1).Add(Table("users").Get("a").Field("age"))
+	err = createRuntimeError(response.ErrorType, &response, &term, "")
+	existenceErr, ok := err.(RQLNonExistenceError)
+	c.Assert(ok, test.Equals, true)
+	c.Assert(existenceErr.Backtrace(), test.DeepEquals, []BacktraceFrame{{Pos: 1}, {Pos: 0}})
+	c.Assert(existenceErr.FailedTerm().String(), test.Equals, Table("users").Get("a").String())
+
+	term = Table("users").Insert(map[string]interface{}{"id": 1}, InsertOpts{Conflict: "update"})
+	err = createRuntimeError(p.Response_QUERY_LOGIC, &Response{
+		Responses: []json.RawMessage{[]byte(`"Invalid conflict"`)},
+		Backtrace: []interface{}{"conflict"},
+	}, &term, "")
+	runtimeErr := err.(RQLQueryLogicError)
+	c.Assert(runtimeErr.Backtrace(), test.DeepEquals, []BacktraceFrame{{Opt: "conflict"}})
+	c.Assert(runtimeErr.FailedTerm().String(), test.Equals, Expr("update").String())
+
+	// Backtraces which do not match the query
+	err = createRuntimeError(p.Response_QUERY_LOGIC, &Response{
+		Responses: []json.RawMessage{[]byte(`"boom"`)},
+		Backtrace: []interface{}{float64(5)},
+	}, &term, "")
+	c.Assert(err.(RQLQueryLogicError).FailedTerm(), test.IsNil)
+
+	err = createRuntimeError(p.Response_QUERY_LOGIC, &Response{Responses: []json.RawMessage{[]byte(`"boom"`)}}, &term, "")
+	c.Assert(err.(RQLQueryLogicError).Backtrace(), test.IsNil)
+	c.Assert(err.(RQLQueryLogicError).FailedTerm(), test.IsNil)
+}
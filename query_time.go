@@ -1,6 +1,8 @@
 package rethinkdb
 
 import (
+	"fmt"
+
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
@@ -51,7 +53,41 @@ func (t Term) Timezone(args ...interface{}) Term {
 	return constructMethodTerm(t, "Timezone", p.Term_TIMEZONE, args, map[string]interface{}{})
 }
 
-// DuringOpts contains the optional arguments for the During term
+// Bound sets whether the end of a range is included in the range.
+type Bound string
+
+// Bounds accepted by the LeftBound and RightBound options of During, Between
+// and Slice.
+const (
+	BoundOpen   Bound = "open"
+	BoundClosed Bound = "closed"
+)
+
+// validateBound returns an error if bound is a string which is not a valid
+// bound, other values (such as terms) are left to the server.
+func validateBound(term, name string, bound interface{}) error {
+	var b Bound
+	switch v := bound.(type) {
+	case Bound:
+		b = v
+	case string:
+		b = Bound(v)
+	default:
+		return nil
+	}
+
+	switch b {
+	case BoundOpen, BoundClosed:
+		return nil
+	default:
+		return RQLDriverError{rqlError(fmt.Sprintf("%s: invalid %s %q, expected open or closed", term, name, string(b)))}
+	}
+}
+
+// DuringOpts contains the optional arguments for the During term.
+//
+// LeftBound and RightBound accept BoundOpen or BoundClosed, invalid bounds
+// cause an error when the query is run.
 type DuringOpts struct {
 	LeftBound  interface{} `rethinkdb:"left_bound,omitempty"`
 	RightBound interface{} `rethinkdb:"right_bound,omitempty"`
@@ -61,14 +97,35 @@ func (o DuringOpts) toMap() map[string]interface{} {
 	return optArgsToMap(o)
 }
 
+func (o DuringOpts) validate() error {
+	if err := validateBound("During", "LeftBound", o.LeftBound); err != nil {
+		return err
+	}
+
+	return validateBound("During", "RightBound", o.RightBound)
+}
+
 // During returns true if a time is between two other times
 // (by default, inclusive for the start, exclusive for the end).
+//
+//	r.Table("events").Filter(r.Row.Field("created").During(
+//		r.Time(2020, 1, 1, "Z"), r.Time(2021, 1, 1, "Z"),
+//		r.DuringOpts{RightBound: r.BoundClosed},
+//	))
 func (t Term) During(startTime, endTime interface{}, optArgs ...DuringOpts) Term {
 	opts := map[string]interface{}{}
+	var err error
 	if len(optArgs) >= 1 {
+		err = optArgs[0].validate()
 		opts = optArgs[0].toMap()
 	}
-	return constructMethodTerm(t, "During", p.Term_DURING, []interface{}{startTime, endTime}, opts)
+
+	t = constructMethodTerm(t, "During", p.Term_DURING, []interface{}{startTime, endTime}, opts)
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
 }
 
 // Date returns a new time object only based on the day, month and year
@@ -1,6 +1,12 @@
 package rethinkdb
 
-import p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
 
 // Map transform each element of the sequence by applying the given mapping
 // function. It takes two arguments, a sequence and a function of type
@@ -67,14 +73,20 @@ func (o OrderByOpts) toMap() map[string]interface{} {
 // Sorting without an index requires the server to hold the sequence in memory,
 // and is limited to 100,000 documents (or the setting of the ArrayLimit option
 // for run). Sorting with an index can be done on arbitrarily large tables, or
-// after a between command using the same index.
+// after a between command using the same index. When an index is combined
+// with keys the index ordering takes precedence and the keys order documents
+// with equal index values. Using an index on the result of a term which does
+// not return a table, such as Filter or Map, causes an error when the query is
+// run.
 func (t Term) OrderBy(args ...interface{}) Term {
 	var opts = map[string]interface{}{}
+	var index interface{}
 
 	// Look for options map
 	if len(args) > 0 {
 		if possibleOpts, ok := args[len(args)-1].(OrderByOpts); ok {
 			opts = possibleOpts.toMap()
+			index = possibleOpts.Index
 			args = args[:len(args)-1]
 		}
 	}
@@ -85,7 +97,67 @@ func (t Term) OrderBy(args ...interface{}) Term {
 		}
 	}
 
-	return constructMethodTerm(t, "OrderBy", p.Term_ORDER_BY, args, opts)
+	term := constructMethodTerm(t, "OrderBy", p.Term_ORDER_BY, args, opts)
+	if index != nil && !indexOrderable(t) {
+		name := t.name
+		if t.termType == p.Term_DATUM || t.termType == p.Term_MAKE_ARRAY || t.termType == p.Term_MAKE_OBJ {
+			name = "Expr"
+		}
+		term.lastErr = RQLDriverError{rqlError(fmt.Sprintf("OrderBy: an index can only be used to order a table or the result of Between, got %s", name))}
+	}
+
+	return term
+}
+
+// indexOrderable returns false if t is known not to return a table or table
+// slice, and therefore cannot be ordered using an index. Terms whose result
+// is only known by the server, such as Branch or a function variable, are
+// assumed to be orderable.
+func indexOrderable(t Term) bool {
+	switch t.termType {
+	case p.Term_DATUM, p.Term_MAKE_ARRAY, p.Term_MAKE_OBJ, p.Term_GET_ALL,
+		p.Term_FILTER, p.Term_MAP, p.Term_CONCAT_MAP, p.Term_ORDER_BY,
+		p.Term_SKIP, p.Term_LIMIT, p.Term_SLICE, p.Term_PLUCK, p.Term_WITHOUT,
+		p.Term_WITH_FIELDS, p.Term_MERGE, p.Term_UNION, p.Term_DISTINCT:
+		return false
+	default:
+		return true
+	}
+}
+
+// OrderKey is a key used by OrderByFields, Desc sorts the field in descending
+// order.
+type OrderKey struct {
+	Field string
+	Desc  bool
+}
+
+// OrderByFields sorts the sequence by the given fields, in order of
+// precedence. It is equivalent to calling OrderBy with each field wrapped by
+// Asc or Desc, for example:
+//
+//	r.Table("posts").OrderByFields([]r.OrderKey{{Field: "date", Desc: true}, {Field: "title"}})
+func (t Term) OrderByFields(keys []OrderKey, optArgs ...OrderByOpts) Term {
+	args := make([]interface{}, 0, len(keys)+1)
+	for _, key := range keys {
+		if key.Desc {
+			args = append(args, Desc(key.Field))
+		} else {
+			args = append(args, Asc(key.Field))
+		}
+	}
+	if len(optArgs) >= 1 {
+		args = append(args, optArgs[0])
+	}
+
+	term := t.OrderBy(args...)
+	for i, key := range keys {
+		if key.Field == "" && term.lastErr == nil {
+			term.lastErr = RQLDriverError{rqlError(fmt.Sprintf("OrderByFields: key %d has an empty field", i))}
+		}
+	}
+
+	return term
 }
 
 // Desc is used by the OrderBy term to specify the ordering to be descending.
@@ -110,6 +182,9 @@ func (t Term) Limit(args ...interface{}) Term {
 }
 
 // SliceOpts contains the optional arguments for the Slice term
+//
+// LeftBound and RightBound accept BoundOpen or BoundClosed, invalid bounds
+// cause an error when the query is run.
 type SliceOpts struct {
 	LeftBound  interface{} `rethinkdb:"left_bound,omitempty"`
 	RightBound interface{} `rethinkdb:"right_bound,omitempty"`
@@ -119,19 +194,46 @@ func (o SliceOpts) toMap() map[string]interface{} {
 	return optArgsToMap(o)
 }
 
+func (o SliceOpts) validate() error {
+	if err := validateBound("Slice", "LeftBound", o.LeftBound); err != nil {
+		return err
+	}
+
+	return validateBound("Slice", "RightBound", o.RightBound)
+}
+
 // Slice trims the sequence to within the bounds provided.
 func (t Term) Slice(args ...interface{}) Term {
 	var opts = map[string]interface{}{}
+	var err error
 
 	// Look for options map
 	if len(args) > 0 {
 		if possibleOpts, ok := args[len(args)-1].(SliceOpts); ok {
+			err = possibleOpts.validate()
 			opts = possibleOpts.toMap()
 			args = args[:len(args)-1]
 		}
 	}
 
-	return constructMethodTerm(t, "Slice", p.Term_SLICE, args, opts)
+	t = constructMethodTerm(t, "Slice", p.Term_SLICE, args, opts)
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
+}
+
+// SliceFrom trims the sequence to the elements from start to the end of the
+// sequence, it is equivalent to Slice with only a start index. Only the
+// LeftBound option applies as the slice has no end.
+//
+//	r.Expr([]int{0, 1, 2, 3}).SliceFrom(1, r.SliceOpts{LeftBound: r.BoundOpen}) // [2, 3]
+func (t Term) SliceFrom(start interface{}, optArgs ...SliceOpts) Term {
+	if len(optArgs) >= 1 {
+		return t.Slice(start, optArgs[0])
+	}
+	return t.Slice(start)
 }
 
 // AtIndex gets a single field from an object or the nth element from a sequence.
@@ -139,9 +241,40 @@ func (t Term) AtIndex(args ...interface{}) Term {
 	return constructMethodTerm(t, "AtIndex", p.Term_BRACKET, args, map[string]interface{}{})
 }
 
-// Nth gets the nth element from a sequence.
+// Nth gets the nth element from a sequence. Negative indexes count from the
+// end of the sequence so Nth(-1) returns the last element. If the index is out
+// of bounds, for example when the sequence is empty, the query returns an
+// RQLNonExistenceError which can be matched using errors.Is(err, ErrNonExistence).
 func (t Term) Nth(args ...interface{}) Term {
-	return constructMethodTerm(t, "Nth", p.Term_NTH, args, map[string]interface{}{})
+	term := constructMethodTerm(t, "Nth", p.Term_NTH, args, map[string]interface{}{})
+	if len(args) == 1 && !isIntegralIndex(args[0]) {
+		term.lastErr = RQLDriverError{rqlError(fmt.Sprintf("Nth index must be an integer, got %v", args[0]))}
+	}
+
+	return term
+}
+
+// First gets the first element from a sequence, it is equivalent to Nth(0).
+func (t Term) First() Term {
+	return t.Nth(0)
+}
+
+// Last gets the last element from a sequence, it is equivalent to Nth(-1).
+func (t Term) Last() Term {
+	return t.Nth(-1)
+}
+
+// isIntegralIndex returns false if v is a floating point literal with a
+// fractional part, other values (including terms) are left to the server.
+func isIntegralIndex(v interface{}) bool {
+	switch f := v.(type) {
+	case float32:
+		return float32(math.Trunc(float64(f))) == f
+	case float64:
+		return math.Trunc(f) == f
+	default:
+		return true
+	}
 }
 
 // OffsetsOf gets the indexes of an element in a sequence. If the argument is a
@@ -155,7 +288,16 @@ func (t Term) IsEmpty(args ...interface{}) Term {
 	return constructMethodTerm(t, "IsEmpty", p.Term_IS_EMPTY, args, map[string]interface{}{})
 }
 
-// UnionOpts contains the optional arguments for the Slice term
+// UnionOpts contains the optional arguments for the Union term.
+//
+// Interleave controls how the sequences are merged, it can be one of:
+//   - true (the server default), elements are interleaved in arbitrary order.
+//   - false, the sequences are concatenated in the order they were passed.
+//   - a field name, or a Term such as r.Desc("field") or a function, the
+//     sequences (which must already be ordered by it) are merged in order.
+//
+// Any other value results in an error when the query is run. UnionOrderedBy
+// can be used as a shorthand for merging by a field.
 type UnionOpts struct {
 	Interleave interface{} `rethinkdb:"interleave,omitempty"`
 }
@@ -164,6 +306,18 @@ func (o UnionOpts) toMap() map[string]interface{} {
 	return optArgsToMap(o)
 }
 
+func (o UnionOpts) validate() error {
+	switch o.Interleave.(type) {
+	case nil, bool, string, Term:
+		return nil
+	}
+	if reflect.TypeOf(o.Interleave).Kind() == reflect.Func {
+		return nil
+	}
+
+	return RQLDriverError{rqlError(fmt.Sprintf("Union: Interleave must be a bool, field name, Term or function, got %T", o.Interleave))}
+}
+
 // Union concatenates two sequences.
 func Union(args ...interface{}) Term {
 	return constructRootTerm("Union", p.Term_UNION, args, map[string]interface{}{})
@@ -177,17 +331,50 @@ func (t Term) Union(args ...interface{}) Term {
 // UnionWithOpts like Union concatenates two sequences however allows for optional
 // arguments to be passed.
 func UnionWithOpts(optArgs UnionOpts, args ...interface{}) Term {
-	return constructRootTerm("Union", p.Term_UNION, args, optArgs.toMap())
+	t := constructRootTerm("Union", p.Term_UNION, args, optArgs.toMap())
+	if err := optArgs.validate(); err != nil {
+		t.lastErr = err
+	}
+
+	return t
 }
 
 // UnionWithOpts like Union concatenates two sequences however allows for optional
 // arguments to be passed.
 func (t Term) UnionWithOpts(optArgs UnionOpts, args ...interface{}) Term {
-	return constructMethodTerm(t, "Union", p.Term_UNION, args, optArgs.toMap())
+	t = constructMethodTerm(t, "Union", p.Term_UNION, args, optArgs.toMap())
+	if err := optArgs.validate(); err != nil {
+		t.lastErr = err
+	}
+
+	return t
+}
+
+// UnionOrderedBy merges sequences which are each ordered by field into a
+// single sequence ordered by field. It is equivalent to calling UnionWithOpts
+// with Interleave set to field.
+//
+//	r.UnionOrderedBy("created", r.Table("a").OrderBy("created"), r.Table("b").OrderBy("created"))
+func UnionOrderedBy(field string, args ...interface{}) Term {
+	return UnionWithOpts(UnionOpts{Interleave: field}, args...)
+}
+
+// UnionOrderedBy merges sequences which are each ordered by field into a
+// single sequence ordered by field. It is equivalent to calling UnionWithOpts
+// with Interleave set to field.
+func (t Term) UnionOrderedBy(field string, args ...interface{}) Term {
+	return t.UnionWithOpts(UnionOpts{Interleave: field}, args...)
 }
 
 // Sample selects a given number of elements from a sequence with uniform random
 // distribution. Selection is done without replacement.
+//
+// The server does not support seeding the selection, so the elements returned
+// and their order can differ each time the query is run, even when the data
+// has not changed. Tests which depend on the sampled elements should stub the
+// query using Mock instead, for example:
+//
+//	mock.On(r.Table("users").Sample(2)).Return([]interface{}{user1, user2}, nil)
 func (t Term) Sample(args ...interface{}) Term {
 	return constructMethodTerm(t, "Sample", p.Term_SAMPLE, args, map[string]interface{}{})
 }
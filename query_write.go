@@ -1,29 +1,225 @@
 package rethinkdb
 
 import (
+	"database/sql"
+	stdencoding "encoding"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/encoding"
 	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
 )
 
 // InsertOpts contains the optional arguments for the Insert term
+//
+// When inserting an array of documents the server attempts to insert every
+// document, even if some of them fail, and reports the number of errors and
+// the first error. Setting ReturnChanges to "always" includes an entry for
+// every document in the Changes of the WriteResponse, the Error field of each
+// entry contains the error for that document.
+//
+// Setting Ordered instead causes the documents to be inserted one at a time,
+// in order, stopping at the first document which fails. As this is emulated
+// by the driver ordered inserts are not atomic, are slower than inserting the
+// whole array at once and must be run using RunWrite. Other terms cannot be
+// chained after an ordered insert.
+//
+// Setting DryRun reports the documents which would be inserted without
+// writing them, see DryRun for its limitations.
+//
+// Conflict accepts "error", "replace", "update" or a function which resolves
+// the conflict, it is passed the primary key, the existing document and the
+// new document and returns the document to store, for example to keep the
+// largest value of a counter:
+//
+//	r.Table("counters").Insert(doc, r.InsertOpts{
+//		Conflict: func(id, oldDoc, newDoc r.Term) interface{} {
+//			return newDoc.Merge(map[string]interface{}{
+//				"count": r.Max([]interface{}{oldDoc.Field("count"), newDoc.Field("count")}),
+//			})
+//		},
+//	})
+//
+// Invalid values cause an error when the query is run.
 type InsertOpts struct {
 	Durability      interface{} `gorethink:"durability,omitempty"`
 	ReturnChanges   interface{} `gorethink:"return_changes,omitempty"`
 	Conflict        interface{} `gorethink:"conflict,omitempty"`
 	IgnoreWriteHook interface{} `gorethink:"ignore_write_hook,omitempty"`
+
+	Ordered bool `gorethink:"-"`
+	// DryRun replaces the insert with a read-only query which responds like
+	// an insert with ReturnChanges set, every document is reported as
+	// inserted. As the table is not written to the response cannot include
+	// conflicts with existing documents, generated keys or errors caused by
+	// write hooks or invalid documents.
+	DryRun bool `gorethink:"-"`
 }
 
 func (o InsertOpts) toMap() map[string]interface{} {
 	return optArgsToMap(o)
 }
 
+func (o InsertOpts) validate() error {
+	switch c := o.Conflict.(type) {
+	case nil, Term:
+		return nil
+	case string:
+		switch c {
+		case "error", "replace", "update":
+			return nil
+		}
+		return RQLDriverError{rqlError(fmt.Sprintf("Insert: invalid Conflict %q, expected error, replace, update or a function", c))}
+	}
+
+	if t := reflect.TypeOf(o.Conflict); t.Kind() == reflect.Func && t.NumIn() != 3 {
+		return RQLDriverError{rqlError(fmt.Sprintf("Insert: the Conflict function must accept 3 arguments, got %d", t.NumIn()))}
+	}
+	return nil
+}
+
+var (
+	errOrderedInsertRun     = RQLDriverError{rqlError("Insert: ordered inserts must be run using RunWrite")}
+	errOrderedInsertChained = RQLDriverError{rqlError("Insert: terms cannot be chained after an ordered insert")}
+)
+
 // Insert documents into a table. Accepts a single document or an array
 // of documents.
 func (t Term) Insert(arg interface{}, optArgs ...InsertOpts) Term {
 	opts := map[string]interface{}{}
+	ordered := false
+	var err error
 	if len(optArgs) >= 1 {
+		if optArgs[0].DryRun {
+			return dryRunInsert(arg)
+		}
+		err = optArgs[0].validate()
 		opts = optArgs[0].toMap()
+		ordered = optArgs[0].Ordered
+	}
+
+	t = constructMethodTerm(t, "Insert", p.Term_INSERT, []interface{}{Expr(arg)}, opts)
+	if err != nil {
+		t.lastErr = err
+	}
+	// Ordering only matters when inserting an array of documents
+	if ordered && t.args[1].termType == p.Term_MAKE_ARRAY {
+		t.orderedInsert = true
+	}
+
+	return t
+}
+
+// runOrderedInsert inserts each document of an ordered insert separately,
+// stopping at the first error. The responses of each insert are combined.
+func (t Term) runOrderedInsert(s QueryExecutor, optArgs ...RunOpts) (WriteResponse, error) {
+	var response WriteResponse
+
+	if t.lastErr != nil {
+		return response, t.lastErr
+	}
+	if t.termType != p.Term_INSERT {
+		return response, errOrderedInsertChained
+	}
+	if t.args[1].termType != p.Term_MAKE_ARRAY {
+		// The documents were rewritten, for example by ConnectOpts.QueryRewriter,
+		// so they can no longer be inserted separately
+		t.orderedInsert = false
+		return t.RunWrite(s, optArgs...)
+	}
+
+	table, docs := t.args[0], t.args[1].args
+	for _, doc := range docs {
+		insert := t
+		insert.args = []Term{table, doc}
+		insert.orderedInsert = false
+
+		res, err := insert.RunWrite(s, optArgs...)
+		addInsertResponse(&response, res)
+		if err != nil {
+			return response, err
+		}
+	}
+
+	return response, nil
+}
+
+// addInsertResponse adds the counts, keys and changes of the response res of
+// an insert to response.
+func addInsertResponse(response *WriteResponse, res WriteResponse) {
+	response.Inserted += res.Inserted
+	response.Replaced += res.Replaced
+	response.Unchanged += res.Unchanged
+	response.Skipped += res.Skipped
+	response.Errors += res.Errors
+	response.GeneratedKeys = append(response.GeneratedKeys, res.GeneratedKeys...)
+	response.Changes = append(response.Changes, res.Changes...)
+	if res.Errors > 0 {
+		response.FirstError = res.FirstError
 	}
-	return constructMethodTerm(t, "Insert", p.Term_INSERT, []interface{}{Expr(arg)}, opts)
+}
+
+var (
+	rqlUnmarshalerType  = reflect.TypeOf((*encoding.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*stdencoding.TextUnmarshaler)(nil)).Elem()
+	sqlScannerType      = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// GeneratedKeysAs decodes the keys generated by an insert into dest, which
+// must be a pointer to a slice, for example to convert the generated UUIDs to
+// a custom key type:
+//
+//	var ids []UserID
+//	err := res.GeneratedKeysAs(&ids)
+//
+// Each key is decoded using the key type's UnmarshalRQL method if it has one,
+// otherwise using its UnmarshalText or Scan method (with the key as a
+// string), otherwise it is decoded like any other value.
+func (r WriteResponse) GeneratedKeysAs(dest interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Slice {
+		return RQLDriverError{rqlError(fmt.Sprintf("GeneratedKeysAs: dest must be a pointer to a slice, got %T", dest))}
+	}
+
+	sv := dv.Elem()
+	keys := reflect.MakeSlice(sv.Type(), len(r.GeneratedKeys), len(r.GeneratedKeys))
+	elemType := sv.Type().Elem()
+	ptrType := reflect.PtrTo(elemType)
+	for i, key := range r.GeneratedKeys {
+		elem := keys.Index(i).Addr()
+		var err error
+		switch {
+		case ptrType.Implements(rqlUnmarshalerType):
+			err = encoding.Decode(elem.Interface(), key)
+		case ptrType.Implements(textUnmarshalerType):
+			err = elem.Interface().(stdencoding.TextUnmarshaler).UnmarshalText([]byte(key))
+		case ptrType.Implements(sqlScannerType):
+			err = elem.Interface().(sql.Scanner).Scan(key)
+		default:
+			err = encoding.Decode(elem.Interface(), key)
+		}
+		if err != nil {
+			return RQLDriverError{rqlError(fmt.Sprintf("GeneratedKeysAs: key %d: %v", i, err))}
+		}
+	}
+	sv.Set(keys)
+
+	return nil
+}
+
+// dryRunInsert returns a query which reports the documents of arg as inserted
+// without writing them.
+func dryRunInsert(arg interface{}) Term {
+	return Expr(arg).Do(func(docs Term) Term {
+		return Branch(docs.TypeOf().Eq("ARRAY"), docs, []interface{}{docs})
+	}).Map(func(doc Term) Term {
+		return Expr(map[string]interface{}{"old_val": nil, "new_val": doc})
+	}).Do(func(changes Term) Term {
+		return Expr(map[string]interface{}{
+			"inserted": changes.Count(),
+			"changes":  changes,
+		})
+	})
 }
 
 // UpdateOpts contains the optional arguments for the Update term
@@ -33,6 +229,15 @@ type UpdateOpts struct {
 	NonAtomic       interface{} `gorethink:"non_atomic,omitempty"`
 	Conflict        interface{} `gorethink:"conflict,omitempty"`
 	IgnoreWriteHook interface{} `gorethink:"ignore_write_hook,omitempty"`
+
+	// DryRun replaces the update with a read-only query which responds like
+	// an update with ReturnChanges set, reporting the documents which would
+	// be replaced or left unchanged. The new values are calculated by merging
+	// the update into each document so the response cannot include errors
+	// caused by write hooks or by the documents changing before the update is
+	// really run. As the update is evaluated inside a function arg must not
+	// use Row, use a function instead.
+	DryRun bool `gorethink:"-"`
 }
 
 func (o UpdateOpts) toMap() map[string]interface{} {
@@ -42,14 +247,116 @@ func (o UpdateOpts) toMap() map[string]interface{} {
 // Update JSON documents in a table. Accepts a JSON document, a ReQL expression,
 // or a combination of the two. You can pass options like returnChanges that will
 // return the old and new values of the row you have modified.
+//
+// When updating using a struct every field is sent, including fields with
+// their zero value, and will overwrite the existing values of the document
+// (unlike Insert which writes the whole document). Fields tagged with the
+// "omitempty" option are not sent when empty and therefore keep their
+// existing value, to skip every zero field use UpdateNonZero.
 func (t Term) Update(arg interface{}, optArgs ...UpdateOpts) Term {
 	opts := map[string]interface{}{}
 	if len(optArgs) >= 1 {
+		if optArgs[0].DryRun {
+			return dryRunUpdate(t, arg)
+		}
 		opts = optArgs[0].toMap()
 	}
 	return constructMethodTerm(t, "Update", p.Term_UPDATE, []interface{}{funcWrap(arg)}, opts)
 }
 
+// dryRunUpdate returns a query which reports the changes that updating the
+// documents selected by t with arg would make without writing them.
+func dryRunUpdate(t Term, arg interface{}) Term {
+	single := t.termType == p.Term_GET
+	docs := t
+	if single {
+		// A missing document is skipped by the update
+		docs = t.Do(func(doc Term) Term {
+			return Branch(doc.Eq(nil), []interface{}{}, []interface{}{doc})
+		})
+	}
+
+	return docs.Map(func(doc Term) Term {
+		return Expr(map[string]interface{}{"old_val": doc, "new_val": doc.Merge(arg)})
+	}).CoerceTo("array").Do(func(changes Term) Term {
+		changed := changes.Filter(func(change Term) Term {
+			return change.Field("old_val").Ne(change.Field("new_val"))
+		})
+		response := map[string]interface{}{
+			"replaced":  changed.Count(),
+			"unchanged": changes.Count().Sub(changed.Count()),
+			"changes":   changed,
+		}
+		if single {
+			response["skipped"] = Expr(1).Sub(changes.Count())
+		}
+
+		return Expr(response)
+	})
+}
+
+// UpdateNonZero updates documents using the non-zero fields of the struct
+// arg, fields with their zero value (including those of nested structs) are
+// not sent and keep their existing values. Note that this means a field
+// cannot be set to its zero value using UpdateNonZero.
+func (t Term) UpdateNonZero(arg interface{}, optArgs ...UpdateOpts) Term {
+	doc, err := encoding.EncodeNonZero(arg)
+	t = t.Update(doc, optArgs...)
+	if err != nil {
+		t.lastErr = err
+	}
+
+	return t
+}
+
+// UpdateIfVersion updates the document selected by t with patch only if the
+// document's "version" field equals version, incrementing the field when the
+// update is applied. This allows documents to be updated using optimistic
+// concurrency control, for example:
+//
+//	res, err := r.Table("posts").Get(id).UpdateIfVersion(2, patch).RunWrite(session)
+//
+// The WriteResponse returned by RunWrite reports the outcome, Applied returns
+// true if the document was updated, if the version did not match then
+// Unchanged is 1 and if the document does not exist then Skipped is 1.
+func (t Term) UpdateIfVersion(version, patch interface{}, optArgs ...UpdateOpts) Term {
+	return t.Update(func(doc Term) Term {
+		return Branch(
+			doc.Field("version").Eq(version),
+			Expr(patch).Merge(map[string]interface{}{
+				"version": doc.Field("version").Add(1),
+			}),
+			doc,
+		)
+	}, optArgs...)
+}
+
+// Increment adds delta to the numeric field of the documents selected by t,
+// fields which are missing or null are treated as 0. The update is atomic,
+// for example:
+//
+//	res, err := r.Table("posts").Get(id).Increment("views", 1).RunWrite(session)
+//
+// Documents which do not exist are skipped, use Insert with a Conflict
+// function to create them.
+func (t Term) Increment(field string, delta interface{}, optArgs ...UpdateOpts) Term {
+	return t.Update(func(doc Term) Term {
+		return Expr(map[string]interface{}{
+			field: doc.Field(field).Default(0).Add(delta),
+		})
+	}, optArgs...)
+}
+
+// Decrement subtracts delta from the numeric field of the documents selected
+// by t, see Increment.
+func (t Term) Decrement(field string, delta interface{}, optArgs ...UpdateOpts) Term {
+	return t.Update(func(doc Term) Term {
+		return Expr(map[string]interface{}{
+			field: doc.Field(field).Default(0).Sub(delta),
+		})
+	}, optArgs...)
+}
+
 // ReplaceOpts contains the optional arguments for the Replace term
 type ReplaceOpts struct {
 	Durability      interface{} `gorethink:"durability,omitempty"`
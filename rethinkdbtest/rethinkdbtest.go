@@ -0,0 +1,133 @@
+// Package rethinkdbtest provides a helper for writing integration tests
+// against a real RethinkDB server.
+//
+// Start runs a disposable RethinkDB server in a Docker container and returns
+// a session connected to it, the container is removed when the server is
+// closed. The docker command must be available and able to run containers.
+//
+//	func TestMyQuery(t *testing.T) {
+//		srv, err := rethinkdbtest.Start()
+//		if err != nil {
+//			t.Skipf("could not start rethinkdb: %v", err)
+//		}
+//		defer srv.Close()
+//
+//		_, err = r.TableCreate("test").RunWrite(srv.Session)
+//		...
+//	}
+package rethinkdbtest
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+const (
+	// DefaultImage is the Docker image used when Opts.Image is not set.
+	DefaultImage = "rethinkdb:2.4"
+	// DefaultStartupTimeout is the time allowed for the server to accept
+	// connections when Opts.StartupTimeout is not set.
+	DefaultStartupTimeout = 30 * time.Second
+)
+
+// Opts contains the optional arguments for the Start function.
+type Opts struct {
+	// Image is the Docker image to run, defaults to DefaultImage.
+	Image string
+	// StartupTimeout is the time to wait for the server to accept connections,
+	// defaults to DefaultStartupTimeout.
+	StartupTimeout time.Duration
+	// ConnectOpts are the options used to connect the session, the Address is
+	// always set to the address of the container.
+	ConnectOpts r.ConnectOpts
+}
+
+// Server is a RethinkDB server running in a Docker container.
+type Server struct {
+	// Session is connected to the server.
+	Session *r.Session
+	// Address is the host and port of the server's client driver port.
+	Address string
+
+	containerID string
+}
+
+// Start runs a new RethinkDB container and connects a session to it.
+func Start(optArgs ...Opts) (*Server, error) {
+	var opts Opts
+	if len(optArgs) >= 1 {
+		opts = optArgs[0]
+	}
+	if opts.Image == "" {
+		opts.Image = DefaultImage
+	}
+	if opts.StartupTimeout <= 0 {
+		opts.StartupTimeout = DefaultStartupTimeout
+	}
+
+	id, err := docker("run", "-d", "--rm", "-p", "127.0.0.1::28015", opts.Image)
+	if err != nil {
+		return nil, fmt.Errorf("rethinkdbtest: starting container: %v", err)
+	}
+	srv := &Server{containerID: id}
+
+	port, err := docker("port", id, "28015/tcp")
+	if err != nil {
+		srv.Close()
+		return nil, fmt.Errorf("rethinkdbtest: finding container port: %v", err)
+	}
+	// The output may contain a line per address family, use the first
+	srv.Address = strings.SplitN(port, "\n", 2)[0]
+
+	connectOpts := opts.ConnectOpts
+	connectOpts.Address = srv.Address
+	connectOpts.Addresses = nil
+
+	deadline := time.Now().Add(opts.StartupTimeout)
+	for {
+		srv.Session, err = r.Connect(connectOpts)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			srv.Close()
+			return nil, fmt.Errorf("rethinkdbtest: connecting to %s: %v", srv.Address, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return srv, nil
+}
+
+// Close closes the session and removes the container.
+func (s *Server) Close() error {
+	var err error
+	if s.Session != nil {
+		err = s.Session.Close()
+	}
+	if _, rmErr := docker("rm", "-f", s.containerID); rmErr != nil {
+		return fmt.Errorf("rethinkdbtest: removing container: %v", rmErr)
+	}
+
+	return err
+}
+
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
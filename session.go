@@ -2,6 +2,7 @@ package rethinkdb
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"sync"
 	"time"
 
@@ -15,9 +16,14 @@ type Session struct {
 	hosts []Host
 	opts  *ConnectOpts
 
-	mu      sync.RWMutex
-	cluster *Cluster
-	closed  bool
+	mu             sync.RWMutex
+	reconnectMu    sync.Mutex // serializes reconnects so only one cluster is created at a time
+	cluster        *Cluster
+	closed         bool
+	events         chan Event
+	defaultRunOpts *RunOpts
+	queries        queryCache
+	inFlight       *inFlightQueries
 }
 
 // ConnectOpts is used to specify optional arguments when connecting to a cluster.
@@ -65,13 +71,28 @@ type ConnectOpts struct {
 	HandshakeVersion HandshakeVersion `rethinkdb:"handshake_version,omitempty" json:"handshake_version,omitempty"`
 	// UseJSONNumber indicates whether the cursors running in this session should
 	// use json.Number instead of float64 while unmarshaling documents with
-	// interface{}. The default is `false`.
+	// interface{}. The default is `false`. Note that the server stores numbers
+	// as 64-bit floats, to store integers larger than 2^53 exactly use the
+	// "string" struct tag option.
 	UseJSONNumber bool `json:"use_json_number,omitempty"`
 	// NumRetries is the number of times a query is retried if a connection
 	// error is detected, queries are not retried if RethinkDB returns a
 	// runtime error.
 	// Default is 3.
 	NumRetries int `json:"num_retries,omitempty"`
+	// ShouldRetry, if set, is called when a query fails with an error which
+	// the driver would not retry, such as a runtime error returned while the
+	// primary replica of a table is unavailable. The query is retried if it
+	// returns true, attempt is the number of times the query has been run.
+	// Queries are never run more than NumRetries times.
+	ShouldRetry func(err error, attempt int) bool `rethinkdb:"-" json:"-"`
+
+	// CircuitBreaker enables a circuit breaker for queries run using this
+	// session when set. After a number of consecutive connection failures
+	// queries fail immediately with ErrCircuitOpen until the cooldown has
+	// passed, after which a single query is sent to check whether the database
+	// has recovered.
+	CircuitBreaker *CircuitBreakerOpts `json:"circuit_breaker,omitempty"`
 
 	// InitialCap is used by the internal connection pool and is used to
 	// configure how many connections are created for each host when the
@@ -82,6 +103,95 @@ type ConnectOpts struct {
 	// the maximum number of connections held in the pool. By default the
 	// maximum number of connections is 1
 	MaxOpen int `rethinkdb:"max_open,omitempty" json:"max_open,omitempty"`
+	// HostPools overrides InitialCap and MaxOpen for individual hosts, keyed
+	// by the address of the host in the form "host:port". Each host has its
+	// own connection pool so a slow host cannot use up the connections of
+	// other hosts, this allows the size of each pool to be set separately.
+	HostPools map[string]HostPoolOpts `json:"host_pools,omitempty"`
+	// MaxConcurrentPerConn limits the number of queries which can be waiting
+	// for a response on a single connection. Once a connection has this many
+	// queries in-flight new queries are sent using another connection of the
+	// pool, opening a new connection if fewer than MaxOpen are open. If every
+	// connection is busy the query is queued on one of them as usual. If zero
+	// then there is no limit.
+	MaxConcurrentPerConn int `rethinkdb:"max_concurrent_per_conn,omitempty" json:"max_concurrent_per_conn,omitempty"`
+	// MaxInFlightQueries limits the number of queries run using the session
+	// which can be waiting for a response at the same time, across all
+	// connections and hosts. Once the limit is reached Run and Exec block
+	// until another query receives its response, returning ErrQueryTimeout if
+	// the context of the query is done first. Fetching further batches of an
+	// open cursor is not limited and an open cursor does not count as
+	// in-flight, so long running changefeeds do not use up the limit. If zero
+	// then there is no limit, the current number of in-flight queries is
+	// reported by Session.Stats.
+	MaxInFlightQueries int `json:"max_in_flight_queries,omitempty"`
+	// MaxInFlightWait is the maximum amount of time a query waits for another
+	// query to finish once MaxInFlightQueries is reached, after which it
+	// fails with ErrTooManyInFlightQueries. If zero then queries wait until
+	// their context is done, queries run without a context use ReadTimeout
+	// and WriteTimeout like when they are sent, or wait for at most a minute
+	// if these are not set either.
+	MaxInFlightWait time.Duration `json:"max_in_flight_wait,omitempty"`
+	// ConnMaxIdleTime is the maximum amount of time a connection of the pool
+	// may be unused before it is closed, a new connection is opened when it is
+	// next needed. Connections with open cursors are not closed. If zero then
+	// connections are not closed due to being idle.
+	ConnMaxIdleTime time.Duration `rethinkdb:"conn_max_idle_time,omitempty" json:"conn_max_idle_time,omitempty"`
+	// ConnMaxLifetime is the maximum amount of time a connection of the pool
+	// may be reused, once expired the connection is closed when it is next
+	// idle and replaced when needed. If zero then connections are reused
+	// forever.
+	ConnMaxLifetime time.Duration `rethinkdb:"conn_max_lifetime,omitempty" json:"conn_max_lifetime,omitempty"`
+	// RTTProbeInterval is how often the round-trip time of each connection of
+	// the pool is measured, see Session.Stats. If zero then round-trip times
+	// are only measured when Session.Server is called. Probes do not count as
+	// using a connection, so they do not stop idle connections from being
+	// closed after ConnMaxIdleTime.
+	RTTProbeInterval time.Duration `rethinkdb:"rtt_probe_interval,omitempty" json:"rtt_probe_interval,omitempty"`
+	// OnConnOpen, if set, is called with the address of the host whenever the
+	// connection pool opens a new connection.
+	OnConnOpen func(address string) `rethinkdb:"-" json:"-"`
+	// OnConnClose, if set, is called with the address of the host whenever the
+	// connection pool closes a connection, including when a broken connection
+	// is replaced and when the pool is closed.
+	OnConnClose func(address string) `rethinkdb:"-" json:"-"`
+	// QueryRewriter, if set, is called with the term of every query run using
+	// the session just before it is built, and the returned term is sent
+	// instead. The options passed to Run or Exec are sent unchanged. Queries
+	// sent by SendRaw and the queries used internally by the driver, such as
+	// for host discovery, are not rewritten.
+	//
+	// This is an advanced option, the returned term must still be a valid
+	// query and errors returned by the server refer to the rewritten term.
+	// Term.Type and Term.Transform can be used to find and replace sub-terms.
+	QueryRewriter func(Term) Term `rethinkdb:"-" json:"-"`
+	// QueryIDGenerator, if set, is called for every query run using the
+	// session to generate an ID which identifies the query on the client, for
+	// example to correlate log entries. The ID is not sent to the server but
+	// is available from Term.QueryID in QueryRewriter, Cursor.QueryID and the
+	// QueryID method of errors returned by the server, and is included in
+	// the error message.
+	QueryIDGenerator func() string `rethinkdb:"-" json:"-"`
+	// CacheSerializedQueries enables caching the JSON encoding of the terms of
+	// queries run using the session, when a term Equal to a recently run term
+	// is run again its cached encoding is sent instead of building the term.
+	// This reduces the CPU usage of applications which repeatedly run the same
+	// queries, terms are still hashed and compared so the cost of building a
+	// query is only partly saved. Write queries and terms whose encoding is
+	// larger than 4 KiB are not cached. Up to 1024 terms are cached, evicting
+	// the least recently used, so the cache holds at most 4 MiB of encoded
+	// terms plus the terms themselves.
+	CacheSerializedQueries bool `json:"cache_serialized_queries,omitempty"`
+	// ApplicationName identifies the application using the session, for
+	// example a service and instance name. The RethinkDB protocol has no way
+	// for a client to name its connections so the name is not sent to the
+	// server, the jobs system table only lists the client address and port
+	// of each query. Instead the driver uses the name on the client:
+	// Session.RunningJobs sets Job.Application for the query jobs sent using
+	// the connections of the session, matched by their client address and
+	// port, and the name is added to tracing spans as the
+	// "rethinkdb.application_name" tag.
+	ApplicationName string `json:"application_name,omitempty"`
 
 	// Below options are for cluster discovery, please note there is a high
 	// probability of these changing as the API is still being worked on.
@@ -90,6 +200,14 @@ type ConnectOpts struct {
 	// will attempt to discover any new nodes added to the cluster and then
 	// start sending queries to these new nodes.
 	DiscoverHosts bool `rethinkdb:"discover_hosts,omitempty" json:"discover_hosts,omitempty"`
+	// ReadNodeTag and WriteNodeTag split queries between nodes based on the
+	// server tags set in the server_config system table. Queries which
+	// insert, update, replace or delete documents are sent to a node tagged
+	// with WriteNodeTag and all other queries to a node tagged with
+	// ReadNodeTag. If no connected node has the tag any node is used. Tags are
+	// read when the driver connects to a node.
+	ReadNodeTag  string `rethinkdb:"read_node_tag,omitempty" json:"read_node_tag,omitempty"`
+	WriteNodeTag string `rethinkdb:"write_node_tag,omitempty" json:"write_node_tag,omitempty"`
 	// HostDecayDuration is used by the go-hostpool package to calculate a weighted
 	// score when selecting a host. By default a value of 5 minutes is used.
 	HostDecayDuration time.Duration `json:"host_decay_duration,omitempty"`
@@ -149,8 +267,10 @@ func Connect(opts ConnectOpts) (*Session, error) {
 
 	// Connect
 	s := &Session{
-		hosts: hosts,
-		opts:  &opts,
+		hosts:    hosts,
+		opts:     &opts,
+		events:   newEventChan(),
+		inFlight: newInFlightQueries(&opts),
 	}
 
 	err := s.Reconnect()
@@ -158,8 +278,10 @@ func Connect(opts ConnectOpts) (*Session, error) {
 		// note: s.Reconnect() will initialize cluster information which
 		// will cause the .IsConnected() method to be caught in a loop
 		return &Session{
-			hosts: hosts,
-			opts:  &opts,
+			hosts:    hosts,
+			opts:     &opts,
+			events:   s.events,
+			inFlight: s.inFlight,
 		}, err
 	}
 
@@ -186,23 +308,127 @@ func (s *Session) IsConnected() bool {
 	return s.cluster.IsConnected()
 }
 
+// healthCheckInterval is the interval at which WaitForHealthy checks the
+// state of the cluster.
+const healthCheckInterval = 100 * time.Millisecond
+
+// WaitForHealthy blocks until the session has working connections to at least
+// minNodes distinct hosts, polling the state of the cluster until the
+// condition holds. If ctx is done first the context's error is returned.
+//
+// When DiscoverHosts is disabled only the hosts passed to Connect are
+// counted.
+func (s *Session) WaitForHealthy(ctx context.Context, minNodes int) error {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.RLock()
+		if s.closed || s.cluster == nil {
+			s.mu.RUnlock()
+			return ErrConnectionClosed
+		}
+		healthy := s.cluster.healthyHosts()
+		s.mu.RUnlock()
+
+		if healthy >= minNodes {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ping checks that the session can run queries by running a trivial query
+// using one of its connections. An error is returned if no connection can run
+// the query or ctx is done first, which makes Ping suitable for health
+// checks. A nil context uses the session's timeouts.
+func (s *Session) Ping(ctx context.Context) error {
+	return ping(ctx, s)
+}
+
+func ping(ctx context.Context, s QueryExecutor) error {
+	var v int
+	if err := Expr(1).ReadOne(&v, s, RunOpts{Context: ctx}); err != nil {
+		return err
+	}
+	if v != 1 {
+		return RQLDriverError{rqlError("Ping: unexpected response")}
+	}
+
+	return nil
+}
+
+// Events returns a channel which receives events about the connections of the
+// session, such as connections being lost, hosts being discovered and queries
+// being retried. The channel is buffered and events are dropped when it is
+// full so the driver is never blocked by a slow reader, the channel is never
+// closed.
+func (s *Session) Events() <-chan Event {
+	return s.events
+}
+
 // Reconnect closes and re-opens a session.
 func (s *Session) Reconnect(optArgs ...CloseOpts) error {
-	var err error
+	return s.ReconnectWithContext(context.Background(), optArgs...)
+}
+
+// ReconnectWithContext closes and re-opens a session like Reconnect, but
+// returns the context's error if ctx is done before the connections are
+// re-established, for example when the servers are unreachable. The session
+// is then left without connections and queries fail until it is
+// reconnected, connections opened after ctx is done are closed. Concurrent
+// calls reconnect one after the other. A nil ctx never expires.
+func (s *Session) ReconnectWithContext(ctx context.Context, optArgs ...CloseOpts) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
-	if err = s.Close(optArgs...); err != nil {
+	s.reconnectMu.Lock()
+	defer s.reconnectMu.Unlock()
+
+	if err := s.Close(optArgs...); err != nil {
 		return err
 	}
 
-	s.mu.Lock()
-	s.cluster, err = NewCluster(s.hosts, s.opts)
-	if err != nil {
+	type clusterAndError struct {
+		cluster *Cluster
+		err     error
+	}
+	result := make(chan clusterAndError, 1)
+	go func() {
+		cluster, err := newCluster(s.hosts, s.opts, s.events)
+		result <- clusterAndError{cluster, err}
+	}()
+
+	var res clusterAndError
+	select {
+	case res = <-result:
+	case <-ctx.Done():
+		s.mu.Lock()
+		s.cluster = nil
 		s.mu.Unlock()
-		return err
+
+		go func() {
+			if res := <-result; res.err == nil {
+				_ = res.cluster.Close()
+			}
+		}()
+		return ctx.Err()
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cluster = res.cluster
+	if res.err != nil {
+		return res.err
+	}
 	s.closed = false
-	s.mu.Unlock()
 
 	return nil
 }
@@ -285,6 +511,30 @@ func (s *Session) Use(database string) {
 	s.opts.Database = database
 }
 
+// SetDefaultRunOpts sets the options used by every query run using the
+// session, such as the read mode or time format. The options passed to Run,
+// RunWrite and similar functions are merged over the defaults, any field
+// which is set to a non-nil or non-zero value overrides the default. Options
+// such as Prefetch, Node and MaxResponseBytes are pointers, set using Bool,
+// String and Int, so that they can be overridden for a single query with any
+// value, including false or zero.
+func (s *Session) SetDefaultRunOpts(opts RunOpts) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.defaultRunOpts = &opts
+}
+
+func (s *Session) runOptsDefaults() (RunOpts, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.defaultRunOpts == nil {
+		return RunOpts{}, false
+	}
+	return *s.defaultRunOpts, true
+}
+
 // Database returns the selected database set by Use
 func (s *Session) Database() string {
 	s.mu.RLock()
@@ -295,6 +545,11 @@ func (s *Session) Database() string {
 
 // Query executes a ReQL query using the session to connect to the database
 func (s *Session) Query(ctx context.Context, q Query) (*Cursor, error) {
+	if err := s.inFlight.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.inFlight.release()
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -307,6 +562,11 @@ func (s *Session) Query(ctx context.Context, q Query) (*Cursor, error) {
 
 // Exec executes a ReQL query using the session to connect to the database
 func (s *Session) Exec(ctx context.Context, q Query) error {
+	if err := s.inFlight.acquire(ctx); err != nil {
+		return err
+	}
+	defer s.inFlight.release()
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -317,6 +577,30 @@ func (s *Session) Exec(ctx context.Context, q Query) error {
 	return s.cluster.Exec(ctx, q)
 }
 
+// SendRaw sends a query built from a raw JSON term tree and global optional
+// arguments, bypassing the query builder. It is intended for advanced use,
+// such as building tooling or experimenting with ReQL features which are not
+// yet supported by the driver, and the query is not validated by the driver.
+//
+// The term must be the JSON serialization of a ReQL term, as described in
+// https://rethinkdb.com/docs/writing-drivers/, and the optional arguments
+// must already be serialized as ReQL. For example:
+//
+//	cursor, err := session.SendRaw(ctx, []byte(`[15, [[14, ["test"]], "users"]]`), map[string]json.RawMessage{
+//		"read_mode": json.RawMessage(`"outdated"`),
+//	})
+//
+// If the session has a default database it is used as the db optional
+// argument. A nil context uses the session's timeouts.
+func (s *Session) SendRaw(ctx context.Context, term []byte, optArgs map[string]json.RawMessage) (*Cursor, error) {
+	q, err := newRawQuery(term, optArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Query(ctx, q)
+}
+
 // Server returns the server name and server UUID being used by a connection.
 func (s *Session) Server() (ServerResponse, error) {
 	return s.cluster.Server()
@@ -331,5 +615,18 @@ func (s *Session) SetHosts(hosts []Host) {
 }
 
 func (s *Session) newQuery(t Term, opts map[string]interface{}) (Query, error) {
+	var id string
+	if s.opts.QueryIDGenerator != nil {
+		id = s.opts.QueryIDGenerator()
+		t.queryID = id
+	}
+	if s.opts.QueryRewriter != nil {
+		t = s.opts.QueryRewriter(t)
+	}
+	t.queryID = id
+
+	if s.opts.CacheSerializedQueries {
+		return buildQuery(t, opts, s.opts, s.queries.build)
+	}
 	return newQuery(t, opts, s.opts)
 }
@@ -0,0 +1,309 @@
+package rethinkdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	test "gopkg.in/check.v1"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+type SessionSuite struct{}
+
+var _ = test.Suite(&SessionSuite{})
+
+func newHealthTestNode(id string, host Host) *Node {
+	conn, _ := net.Pipe()
+	opts := &ConnectOpts{}
+	pool := &Pool{
+		host:    host,
+		opts:    opts,
+		conns:   []*Connection{newConnection(conn, host.String(), opts)},
+		pointer: -1,
+	}
+
+	return newNode(id, []Host{host}, pool)
+}
+
+func (s *SessionSuite) TestSession_WaitForHealthy(c *test.C) {
+	node1 := newHealthTestNode("node1", Host{Name: "host1", Port: 28015})
+	node2 := newHealthTestNode("node2", Host{Name: "host2", Port: 28015})
+
+	opts := &ConnectOpts{}
+	cluster := &Cluster{hp: newHostPool(opts), opts: opts}
+	cluster.replaceNodes([]*Node{node1, node2})
+	session := &Session{opts: opts, cluster: cluster}
+
+	err := session.WaitForHealthy(context.Background(), 2)
+	c.Assert(err, test.IsNil)
+
+	// A node with only broken connections is not healthy
+	node2.pool.conns[0].setBad()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = session.WaitForHealthy(ctx, 2)
+	c.Assert(err, test.Equals, context.DeadlineExceeded)
+
+	err = session.WaitForHealthy(context.Background(), 1)
+	c.Assert(err, test.IsNil)
+
+	session.closed = true
+	err = session.WaitForHealthy(context.Background(), 1)
+	c.Assert(err, test.Equals, ErrConnectionClosed)
+}
+
+func (s *SessionSuite) TestSession_QueryRewriter(c *test.C) {
+	users := Table("users")
+	session := &Session{opts: &ConnectOpts{
+		QueryRewriter: func(t Term) Term {
+			return t.Transform(func(t Term) Term {
+				if t.Equal(users) {
+					return DB("tenant_a").Table("users")
+				}
+				return t
+			})
+		},
+	}}
+
+	q, err := session.newQuery(Table("users").Get("1").Field("name"), map[string]interface{}{"read_mode": "outdated"})
+	c.Assert(err, test.IsNil)
+	c.Assert(q.Term.String(), test.Equals, DB("tenant_a").Table("users").Get("1").Field("name").String())
+	c.Assert(q.Opts, test.HasLen, 1)
+}
+
+func (s *SessionSuite) TestSession_QueryIDGenerator(c *test.C) {
+	var rewritten string
+	n := 0
+	session := &Session{opts: &ConnectOpts{
+		QueryIDGenerator: func() string {
+			n++
+			return fmt.Sprintf("q%d", n)
+		},
+		QueryRewriter: func(t Term) Term {
+			rewritten = t.QueryID()
+			return t.Limit(1)
+		},
+	}}
+
+	q, err := session.newQuery(Table("users"), nil)
+	c.Assert(err, test.IsNil)
+	c.Assert(rewritten, test.Equals, "q1")
+	c.Assert(q.Term.QueryID(), test.Equals, "q1")
+	c.Assert(newCursor(nil, nil, "", 1, q.Term, q.Opts).QueryID(), test.Equals, "q1")
+
+	response := &Response{Responses: []json.RawMessage{json.RawMessage(`"boom"`)}}
+	err = createRuntimeError(p.Response_QUERY_LOGIC, response, q.Term, "")
+	c.Assert(err, test.FitsTypeOf, RQLQueryLogicError{})
+	c.Assert(err.(RQLQueryLogicError).QueryID(), test.Equals, "q1")
+	c.Assert(err, test.ErrorMatches, `(?s)rethinkdb: boom \(query id: q1\) in:.*`)
+
+	q, err = session.newQuery(Table("users"), nil)
+	c.Assert(err, test.IsNil)
+	c.Assert(q.Term.QueryID(), test.Equals, "q2")
+
+	q, err = (&Session{opts: &ConnectOpts{}}).newQuery(Table("users"), nil)
+	c.Assert(err, test.IsNil)
+	c.Assert(q.Term.QueryID(), test.Equals, "")
+}
+
+func (s *SessionSuite) TestSession_Ping(c *test.C) {
+	mock := NewMock()
+	mock.On(Expr(1)).Return(1, nil).Once()
+	c.Assert(ping(context.Background(), mock), test.IsNil)
+
+	mock.On(Expr(1)).Return(nil, ErrNoConnections).Once()
+	c.Assert(ping(context.Background(), mock), test.Equals, ErrNoConnections)
+	mock.AssertExpectations(c)
+
+	session := &Session{opts: &ConnectOpts{}, closed: true}
+	c.Assert(session.Ping(context.Background()), test.Equals, ErrConnectionClosed)
+}
+
+func (s *SessionSuite) TestSession_SetDefaultRunOpts(c *test.C) {
+	session := &Session{opts: &ConnectOpts{}}
+	c.Assert(withDefaultRunOpts(session, nil), test.HasLen, 0)
+
+	limit := 10
+	session.SetDefaultRunOpts(RunOpts{ReadMode: "outdated", TimeFormat: "raw", ArrayLimit: &limit})
+	c.Assert(withDefaultRunOpts(session, nil), test.DeepEquals, []RunOpts{{ReadMode: "outdated", TimeFormat: "raw", ArrayLimit: &limit}})
+
+	merged := withDefaultRunOpts(session, []RunOpts{{ReadMode: "majority", Profile: true}})
+	c.Assert(merged, test.DeepEquals, []RunOpts{{ReadMode: "majority", TimeFormat: "raw", ArrayLimit: &limit, Profile: true}})
+
+	// The defaults are not changed by merging
+	c.Assert(withDefaultRunOpts(session, nil)[0].ReadMode, test.Equals, "outdated")
+
+	// Boolean options enabled by default can be disabled for a query
+	session.SetDefaultRunOpts(RunOpts{Profile: true, Prefetch: Bool(true), CollectErrors: Bool(true)})
+	merged = withDefaultRunOpts(session, []RunOpts{{Profile: false, Prefetch: Bool(false)}})
+	c.Assert(merged, test.DeepEquals, []RunOpts{{Profile: false, Prefetch: Bool(false), CollectErrors: Bool(true)}})
+
+	// So can options which are disabled by zero values
+	session.SetDefaultRunOpts(RunOpts{Node: String("node1"), MaxResponseBytes: Int(1024), TimeoutRTTMultiplier: Float64(10)})
+	merged = withDefaultRunOpts(session, []RunOpts{{Node: String(""), MaxResponseBytes: Int(0)}})
+	c.Assert(merged, test.DeepEquals, []RunOpts{{Node: String(""), MaxResponseBytes: Int(0), TimeoutRTTMultiplier: Float64(10)}})
+
+	// Executors without defaults use the options unchanged
+	c.Assert(withDefaultRunOpts(NewMock(), []RunOpts{{Profile: true}}), test.DeepEquals, []RunOpts{{Profile: true}})
+}
+
+func (s *SessionSuite) TestSession_ReconnectWithContext(c *test.C) {
+	// The server accepts connections but never answers the handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, test.IsNil)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	session := &Session{hosts: []Host{NewHost(addr.IP.String(), addr.Port)}, opts: &ConnectOpts{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	c.Assert(session.ReconnectWithContext(ctx), test.Equals, context.DeadlineExceeded)
+	c.Assert(time.Since(start) < time.Second, test.Equals, true)
+	c.Assert(session.IsConnected(), test.Equals, false)
+	_, err = Expr(1).Run(session)
+	c.Assert(err, test.NotNil)
+
+	// Closing the connection ends the abandoned attempt
+	(<-accepted).Close()
+
+	// A nil context does not expire, so the error of the attempt is returned
+	listener.Close()
+	c.Assert(session.ReconnectWithContext(nil), test.NotNil)
+	c.Assert(session.IsConnected(), test.Equals, false)
+}
+
+func cacheTestTerm() Term {
+	return Table("users").Filter(func(row Term) Term {
+		return row.Field("age").Gt(18)
+	}).OrderBy(Desc("created")).Limit(10)
+}
+
+func (s *SessionSuite) TestSession_CacheSerializedQueries(c *test.C) {
+	uncached := &Session{opts: &ConnectOpts{}}
+	cached := &Session{opts: &ConnectOpts{CacheSerializedQueries: true}}
+
+	// Variables are numbered differently each time a term is built
+	vars := regexp.MustCompile(`\[(2|10),\[\d+\]\]`)
+	encode := func(session *Session) string {
+		q, err := session.newQuery(cacheTestTerm(), map[string]interface{}{"read_mode": "outdated"})
+		c.Assert(err, test.IsNil)
+		b, err := json.Marshal(q.Build())
+		c.Assert(err, test.IsNil)
+		return string(b)
+	}
+
+	want := encode(uncached)
+	first := encode(cached)
+	c.Assert(vars.ReplaceAllString(first, "VAR"), test.Equals, vars.ReplaceAllString(want, "VAR"))
+	// The second term is Equal to the first and reuses its encoding
+	c.Assert(encode(cached), test.Equals, first)
+	c.Assert(cached.queries.entries, test.HasLen, 1)
+
+	// Terms which fail to build are not cached
+	_, err := cached.newQuery(Table("posts").Slice(1, 2, SliceOpts{LeftBound: "inclusive"}), nil)
+	c.Assert(err, test.NotNil)
+	c.Assert(cached.queries.entries, test.HasLen, 1)
+
+	// Write terms and large terms are not cached
+	_, err = cached.newQuery(Table("posts").Insert(map[string]interface{}{"title": "a"}), nil)
+	c.Assert(err, test.IsNil)
+	_, err = cached.newQuery(Expr(strings.Repeat("a", maxCachedQueryBytes)), nil)
+	c.Assert(err, test.IsNil)
+	c.Assert(cached.queries.entries, test.HasLen, 1)
+
+	// The least recently used terms are evicted
+	for i := 0; i < maxCachedQueries+10; i++ {
+		_, err := cached.newQuery(Table("users").Get(i), nil)
+		c.Assert(err, test.IsNil)
+		encode(cached)
+	}
+	c.Assert(cached.queries.entries, test.HasLen, maxCachedQueries)
+	c.Assert(cached.queries.lru.Len(), test.Equals, maxCachedQueries)
+	c.Assert(cached.queries.entries[cacheTestTerm().Hash()], test.NotNil)
+	c.Assert(cached.queries.entries[Table("users").Get(0).Hash()], test.IsNil)
+	c.Assert(cached.queries.entries[Table("users").Get(maxCachedQueries+9).Hash()], test.NotNil)
+}
+
+func (s *SessionSuite) TestSession_MaxInFlightQueries(c *test.C) {
+	opts := &ConnectOpts{MaxInFlightQueries: 1}
+	session := &Session{opts: opts, inFlight: newInFlightQueries(opts), closed: true}
+
+	// Simulate a query waiting for its response
+	c.Assert(session.inFlight.acquire(nil), test.IsNil)
+	c.Assert(session.Stats().InFlightQueries, test.Equals, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := session.Query(ctx, Query{})
+	c.Assert(err, test.Equals, ErrQueryTimeout)
+
+	session.inFlight.wait = 20 * time.Millisecond
+	c.Assert(session.Exec(nil, Query{}), test.Equals, ErrTooManyInFlightQueries)
+
+	// A blocked query is sent once the in-flight query is done
+	errc := make(chan error, 1)
+	go func() {
+		errc <- session.Exec(context.Background(), Query{})
+	}()
+	session.inFlight.release()
+	c.Assert(<-errc, test.Equals, ErrConnectionClosed)
+	c.Assert(session.Stats().InFlightQueries, test.Equals, 0)
+
+	// Queries run without a context use the connection timeouts, or a default
+	// wait if there are none
+	c.Assert(session.inFlight.acquire(nil), test.IsNil)
+	session.inFlight.wait = 0
+	session.inFlight.timeout = 20 * time.Millisecond
+	c.Assert(session.Exec(nil, Query{}), test.Equals, ErrQueryTimeout)
+	session.inFlight.timeout = 0
+	session.inFlight.unboundedWait = 20 * time.Millisecond
+	_, err = session.Query(nil, Query{})
+	c.Assert(err, test.Equals, ErrTooManyInFlightQueries)
+	session.inFlight.release()
+
+	timeouts := &ConnectOpts{MaxInFlightQueries: 1, ReadTimeout: time.Second, WriteTimeout: 2 * time.Second}
+	c.Assert(newInFlightQueries(timeouts).timeout, test.Equals, time.Second)
+	c.Assert(newInFlightQueries(opts).unboundedWait, test.Equals, defaultInFlightWait)
+
+	// Sessions which were not created using Connect are not limited
+	unlimited := &Session{opts: &ConnectOpts{}, closed: true}
+	c.Assert(unlimited.Exec(nil, Query{}), test.Equals, ErrConnectionClosed)
+	c.Assert(unlimited.Stats().InFlightQueries, test.Equals, 0)
+}
+
+func benchmarkNewQuery(b *testing.B, opts *ConnectOpts) {
+	session := &Session{opts: opts}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		q, err := session.newQuery(cacheTestTerm(), map[string]interface{}{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := json.Marshal(q.Build()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSession_newQuery(b *testing.B) {
+	benchmarkNewQuery(b, &ConnectOpts{})
+}
+
+func BenchmarkSession_newQuery_CacheSerializedQueries(b *testing.B) {
+	benchmarkNewQuery(b, &ConnectOpts{CacheSerializedQueries: true})
+}
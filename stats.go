@@ -0,0 +1,171 @@
+package rethinkdb
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// rttSmoothing is the weight given to each new round-trip time measurement in
+// the moving average of a connection.
+const rttSmoothing = 0.2
+
+// Stats contains statistics about the connections of a session, see
+// Session.Stats.
+type Stats struct {
+	// RTT is the average round-trip time of the connections to all hosts,
+	// zero if no round-trip times have been measured.
+	RTT time.Duration
+	// Hosts contains the statistics of each host the session is connected
+	// to, sorted by address.
+	Hosts []HostStats
+	// InFlightQueries is the number of queries run using the session which
+	// are waiting for a response, see ConnectOpts.MaxInFlightQueries.
+	InFlightQueries int
+}
+
+// HostStats contains statistics about the connections to a single host.
+type HostStats struct {
+	// Host is the address of the host.
+	Host string
+	// Connections is the number of open connections to the host.
+	Connections int
+	// RTT is the average round-trip time of the connections to the host,
+	// zero if no round-trip times have been measured.
+	RTT time.Duration
+}
+
+// Stats returns statistics about the connections of the session.
+//
+// Round-trip times are measured using SERVER_INFO queries, which are answered
+// by the server without running a query. These are sent every
+// ConnectOpts.RTTProbeInterval as well as when Server is called.
+func (s *Session) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := Stats{InFlightQueries: s.inFlight.inFlight()}
+	if s.closed || s.cluster == nil {
+		return stats
+	}
+
+	var total time.Duration
+	var measured int
+	for _, node := range s.cluster.GetNodes() {
+		host := node.pool.stats()
+		stats.Hosts = append(stats.Hosts, host)
+		if host.RTT > 0 {
+			total += host.RTT
+			measured++
+		}
+	}
+	if measured > 0 {
+		stats.RTT = total / time.Duration(measured)
+	}
+	sort.Slice(stats.Hosts, func(i, j int) bool {
+		return stats.Hosts[i].Host < stats.Hosts[j].Host
+	})
+
+	return stats
+}
+
+// averageRTT returns the average round-trip time of the connections of the
+// session, used by RunOpts.TimeoutRTTMultiplier.
+func (s *Session) averageRTT() time.Duration {
+	return s.Stats().RTT
+}
+
+// rttEstimator is implemented by query executors which measure the round-trip
+// time of their connections.
+type rttEstimator interface {
+	averageRTT() time.Duration
+}
+
+// stats returns the statistics of the open connections of the pool.
+func (p *Pool) stats() HostStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := HostStats{Host: p.host.String()}
+	var total time.Duration
+	var measured int
+	for _, c := range p.conns {
+		if c == nil || c.isBad() || c.isClosed() {
+			continue
+		}
+		stats.Connections++
+		if rtt := c.rtt(); rtt > 0 {
+			total += rtt
+			measured++
+		}
+	}
+	if measured > 0 {
+		stats.RTT = total / time.Duration(measured)
+	}
+
+	return stats
+}
+
+// probeRTT periodically measures the round-trip time of the open connections
+// of the pool until the pool is closed.
+func (p *Pool) probeRTT(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			conns := make([]*Connection, 0, len(p.conns))
+			for _, c := range p.conns {
+				if c != nil && !c.isBad() && !c.isClosed() {
+					conns = append(conns, c)
+				}
+			}
+			p.mu.Unlock()
+
+			for _, c := range conns {
+				_, _ = c.server(true)
+			}
+		}
+	}
+}
+
+// recordRTT adds a round-trip time measurement to the moving average of the
+// connection.
+func (c *Connection) recordRTT(d time.Duration) {
+	for {
+		old := atomic.LoadInt64(&c.rttNanos)
+		next := int64(d)
+		if old > 0 {
+			next = old + int64(rttSmoothing*float64(int64(d)-old))
+		}
+		if atomic.CompareAndSwapInt64(&c.rttNanos, old, next) {
+			return
+		}
+	}
+}
+
+// rtt returns the moving average of the round-trip time of the connection,
+// zero if it has not been measured.
+func (c *Connection) rtt() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.rttNanos))
+}
+
+// rttTimeout returns the average round-trip time of s multiplied by
+// multiplier, used as the timeout of each batch of a query run with
+// RunOpts.TimeoutRTTMultiplier. Zero is returned if s has not measured any
+// round-trip times.
+func rttTimeout(s QueryExecutor, multiplier float64) time.Duration {
+	e, ok := s.(rttEstimator)
+	if !ok {
+		return 0
+	}
+	rtt := e.averageRTT()
+	if rtt <= 0 {
+		return 0
+	}
+	return time.Duration(multiplier * float64(rtt))
+}
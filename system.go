@@ -0,0 +1,172 @@
+package rethinkdb
+
+import "time"
+
+// SystemTables provides typed access to the system tables of the rethinkdb
+// database, see System.
+type SystemTables struct {
+	s QueryExecutor
+}
+
+// System returns typed accessors for the well-known system tables, the
+// queries are run using s. The user s is connected as must have read
+// permissions on the system tables, for example:
+//
+//	status, err := r.System(session).TableStatus("app", "users")
+//	if err == nil && !status.Status.AllReplicasReady {
+//		// the table is not fully available
+//	}
+func System(s QueryExecutor) SystemTables {
+	return SystemTables{s: s}
+}
+
+// TableStatus describes the availability of a table as listed in the
+// table_status system table.
+type TableStatus struct {
+	ID         string             `rethinkdb:"id"`
+	Name       string             `rethinkdb:"name"`
+	DB         string             `rethinkdb:"db"`
+	RaftLeader string             `rethinkdb:"raft_leader"`
+	Status     TableReadiness     `rethinkdb:"status"`
+	Shards     []TableShardStatus `rethinkdb:"shards"`
+}
+
+// TableReadiness describes which operations a table is ready for.
+type TableReadiness struct {
+	AllReplicasReady      bool `rethinkdb:"all_replicas_ready"`
+	ReadyForOutdatedReads bool `rethinkdb:"ready_for_outdated_reads"`
+	ReadyForReads         bool `rethinkdb:"ready_for_reads"`
+	ReadyForWrites        bool `rethinkdb:"ready_for_writes"`
+}
+
+// TableShardStatus describes the state of the replicas of a single shard of
+// a table.
+type TableShardStatus struct {
+	PrimaryReplicas []string        `rethinkdb:"primary_replicas"`
+	Replicas        []ReplicaStatus `rethinkdb:"replicas"`
+}
+
+// ReplicaStatus describes the state of a replica of a shard, for example
+// "ready", "transitioning" or "disconnected".
+type ReplicaStatus struct {
+	Server string `rethinkdb:"server"`
+	State  string `rethinkdb:"state"`
+}
+
+// TableConfig is the configuration of a table as listed in the table_config
+// system table.
+type TableConfig struct {
+	ID         string             `rethinkdb:"id"`
+	Name       string             `rethinkdb:"name"`
+	DB         string             `rethinkdb:"db"`
+	PrimaryKey string             `rethinkdb:"primary_key"`
+	Shards     []TableShardConfig `rethinkdb:"shards"`
+	Indexes    []string           `rethinkdb:"indexes"`
+	// WriteAcks is either "majority", "single" or a list of objects
+	// describing the acknowledgements required by each set of replicas.
+	WriteAcks  interface{} `rethinkdb:"write_acks"`
+	Durability string      `rethinkdb:"durability"`
+}
+
+// TableShardConfig is the configuration of a single shard of a table.
+type TableShardConfig struct {
+	PrimaryReplica    string   `rethinkdb:"primary_replica"`
+	Replicas          []string `rethinkdb:"replicas"`
+	NonvotingReplicas []string `rethinkdb:"nonvoting_replicas"`
+}
+
+// ServerStatus describes a server of the cluster as listed in the
+// server_status system table.
+type ServerStatus struct {
+	ID      string              `rethinkdb:"id"`
+	Name    string              `rethinkdb:"name"`
+	Network ServerNetworkStatus `rethinkdb:"network"`
+	Process ServerProcessStatus `rethinkdb:"process"`
+}
+
+// ServerNetworkStatus contains the network information of a server.
+type ServerNetworkStatus struct {
+	Hostname    string `rethinkdb:"hostname"`
+	ClusterPort int    `rethinkdb:"cluster_port"`
+	ReqlPort    int    `rethinkdb:"reql_port"`
+	// HTTPAdminPort is the port of the web UI, or the string
+	// "<no http admin>" if the web UI is disabled.
+	HTTPAdminPort      interface{}     `rethinkdb:"http_admin_port"`
+	CanonicalAddresses []ServerAddress `rethinkdb:"canonical_addresses"`
+	ConnectedTo        map[string]bool `rethinkdb:"connected_to"`
+	TimeConnected      time.Time       `rethinkdb:"time_connected"`
+}
+
+// ServerAddress is an address a server can be reached at by other servers.
+type ServerAddress struct {
+	Host string `rethinkdb:"host"`
+	Port int    `rethinkdb:"port"`
+}
+
+// ServerProcessStatus contains information about the process of a server.
+type ServerProcessStatus struct {
+	Argv        []string  `rethinkdb:"argv"`
+	CacheSizeMB float64   `rethinkdb:"cache_size_mb"`
+	PID         int       `rethinkdb:"pid"`
+	TimeStarted time.Time `rethinkdb:"time_started"`
+	Version     string    `rethinkdb:"version"`
+}
+
+// SystemStats is a row of the stats system table. The first element of ID is
+// the kind of object the statistics are for, one of "cluster", "server",
+// "table" or "table_server", followed by the IDs of the server and table.
+//
+// Server, DB and Table are only set for rows about servers and tables, and
+// StorageEngine is only set for "table_server" rows.
+type SystemStats struct {
+	ID            []string               `rethinkdb:"id"`
+	Server        string                 `rethinkdb:"server"`
+	DB            string                 `rethinkdb:"db"`
+	Table         string                 `rethinkdb:"table"`
+	QueryEngine   QueryEngineStats       `rethinkdb:"query_engine"`
+	StorageEngine map[string]interface{} `rethinkdb:"storage_engine"`
+}
+
+// QueryEngineStats contains the query statistics of a stats row, the totals
+// are only reported for rows about servers and tables on a server, and the
+// client counts for rows about the cluster and servers.
+type QueryEngineStats struct {
+	QueriesPerSec     float64 `rethinkdb:"queries_per_sec"`
+	ReadDocsPerSec    float64 `rethinkdb:"read_docs_per_sec"`
+	WrittenDocsPerSec float64 `rethinkdb:"written_docs_per_sec"`
+	QueriesTotal      int64   `rethinkdb:"queries_total"`
+	ReadDocsTotal     int64   `rethinkdb:"read_docs_total"`
+	WrittenDocsTotal  int64   `rethinkdb:"written_docs_total"`
+	ClientConnections int     `rethinkdb:"client_connections"`
+	ClientsActive     int     `rethinkdb:"clients_active"`
+}
+
+// TableStatus returns the status of the table, if the table does not exist
+// the server returns an error.
+func (st SystemTables) TableStatus(db, table string) (TableStatus, error) {
+	var status TableStatus
+	err := DB(db).Table(table).Status().ReadOne(&status, st.s)
+	return status, err
+}
+
+// TableConfig returns the configuration of the table, if the table does not
+// exist the server returns an error.
+func (st SystemTables) TableConfig(db, table string) (TableConfig, error) {
+	var config TableConfig
+	err := DB(db).Table(table).Config().ReadOne(&config, st.s)
+	return config, err
+}
+
+// ServerStatus returns the status of every server of the cluster.
+func (st SystemTables) ServerStatus() ([]ServerStatus, error) {
+	var servers []ServerStatus
+	err := DB(SystemDatabase).Table(ServerStatusSystemTable).ReadAll(&servers, st.s)
+	return servers, err
+}
+
+// Stats returns the statistics of the cluster, its servers and tables.
+func (st SystemTables) Stats() ([]SystemStats, error) {
+	var stats []SystemStats
+	err := DB(SystemDatabase).Table(StatsSystemTable).ReadAll(&stats, st.s)
+	return stats, err
+}
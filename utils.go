@@ -1,6 +1,8 @@
 package rethinkdb
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
@@ -35,12 +37,20 @@ func constructMethodTerm(prevVal Term, name string, termType p.Term_TermType, ar
 		termType: termType,
 		args:     convertTermList(args),
 		optArgs:  convertTermObj(optArgs),
+		// Keep ordered inserts from silently running as a single insert
+		orderedInsert: prevVal.orderedInsert,
 	}
 }
 
 // Helper functions for creating internal RQL types
 
 func newQuery(t Term, qopts map[string]interface{}, copts *ConnectOpts) (q Query, err error) {
+	return buildQuery(t, qopts, copts, Term.Build)
+}
+
+// buildQuery constructs a START query using build to build the term, see
+// queryCache.
+func buildQuery(t Term, qopts map[string]interface{}, copts *ConnectOpts, build func(Term) (interface{}, error)) (q Query, err error) {
 	queryOpts := map[string]interface{}{}
 	for k, v := range qopts {
 		queryOpts[k], err = Expr(v).Build()
@@ -55,7 +65,7 @@ func newQuery(t Term, qopts map[string]interface{}, copts *ConnectOpts) (q Query
 		}
 	}
 
-	builtTerm, err := t.Build()
+	builtTerm, err := build(t)
 	if err != nil {
 		return q, err
 	}
@@ -69,6 +79,35 @@ func newQuery(t Term, qopts map[string]interface{}, copts *ConnectOpts) (q Query
 	}, nil
 }
 
+// newRawQuery constructs a START query from a JSON encoded term tree and
+// global optional arguments which are sent unmodified.
+func newRawQuery(term []byte, optArgs map[string]json.RawMessage) (Query, error) {
+	if !json.Valid(term) {
+		return Query{}, RQLDriverError{rqlError("Error building query: raw term is not valid JSON")}
+	}
+
+	queryOpts := make(map[string]interface{}, len(optArgs))
+	for k, v := range optArgs {
+		if !json.Valid(v) {
+			return Query{}, RQLDriverError{rqlError(fmt.Sprintf("Error building query: raw optional argument %q is not valid JSON", k))}
+		}
+		queryOpts[k] = v
+	}
+
+	t := RawQuery(term)
+	builtTerm, err := t.Build()
+	if err != nil {
+		return Query{}, err
+	}
+
+	return Query{
+		Type:      p.Query_START,
+		Term:      &t,
+		Opts:      queryOpts,
+		builtTerm: builtTerm,
+	}, nil
+}
+
 // makeArray takes a slice of terms and produces a single MAKE_ARRAY term
 func makeArray(args termsList) Term {
 	return Term{
@@ -281,3 +320,46 @@ func shouldRetryQuery(q Query, err error) bool {
 
 	return err == ErrConnectionClosed
 }
+
+// isWriteTerm returns true if the term, or any of its arguments, writes
+// documents.
+func isWriteTerm(t *Term) bool {
+	switch t.termType {
+	case p.Term_INSERT, p.Term_UPDATE, p.Term_REPLACE, p.Term_DELETE:
+		return true
+	}
+
+	for i := range t.args {
+		if isWriteTerm(&t.args[i]) {
+			return true
+		}
+	}
+	for _, arg := range t.optArgs {
+		if isWriteTerm(&arg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// queryDatabase returns the name of the default database set in the global
+// optional arguments of a query, or an empty string if no database was set.
+func queryDatabase(opts map[string]interface{}) string {
+	switch db := opts["db"].(type) {
+	case string:
+		return db
+	case []interface{}:
+		// Built DB term, [DB, [name]]
+		if len(db) < 2 {
+			return ""
+		}
+		if args, ok := db[1].([]interface{}); ok && len(args) == 1 {
+			if name, ok := args[0].(string); ok {
+				return name
+			}
+		}
+	}
+
+	return ""
+}